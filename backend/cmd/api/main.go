@@ -1,18 +1,24 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
 
 	"backend/internal/config"
 	apphttp "backend/internal/http"
+	"backend/internal/jobs"
+	"backend/internal/platform/mailer"
 	"backend/internal/repo/sqlite"
 	"backend/pkg/migrate"
 )
 
 func main() {
 	cfg := config.Load()
+	if err := mailer.CheckDriver(cfg); err != nil {
+		log.Fatalf("mailer: %v", err)
+	}
 
 	db, err := sqlite.Open(cfg.DBPath)
 	if err != nil {
@@ -24,6 +30,11 @@ func main() {
 		log.Fatalf("migrate: %v", err)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	jobs.ReportEmailConflicts(ctx, db)
+	jobs.Start(ctx, cfg, db)
+
 	handler := apphttp.NewRouter(cfg, db)
 
 	srv := &http.Server{
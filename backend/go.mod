@@ -17,4 +17,5 @@ require (
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
 	go.uber.org/atomic v1.7.0 // indirect
 	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/sys v0.25.0 // indirect
 )
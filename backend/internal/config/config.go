@@ -1,35 +1,151 @@
 package config
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Config struct {
-	Port               string
-	DBPath             string
-	MediaDir           string
-	CookieName         string
-	CookieSecure       bool
-	CORSOrigin         string
-	FrontendURL        string
-	GoogleClientID     string
-	GoogleClientSecret string
-	GoogleRedirectURL  string
-	Env                string
+	Port                  string
+	DBPath                string
+	MediaDir              string
+	CookieName            string
+	CookieSecure          bool
+	CORSOrigin            string
+	AllowedOrigins        []string
+	FrontendURL           string
+	OAuthProviders        []OAuthProvider
+	Env                   string
+	SessionIdleTTL        time.Duration
+	SessionAbsoluteTTL    time.Duration
+	SessionRotateInterval time.Duration
+	SessionSweepInterval  time.Duration
+	MailDriver            string
+	MailFrom              string
+	MailDir               string
+	SMTPHost              string
+	SMTPPort              string
+	SMTPUsername          string
+	SMTPPassword          string
+	PasswordResetTTL      time.Duration
+	EmailVerificationTTL  time.Duration
+	RequireVerifiedEmail  []string
+	LoginMaxFailuresEmail int
+	LoginMaxFailuresIP    int
+	LoginFailureWindow    time.Duration
+	LoginLockoutBase      time.Duration
+	LoginLockoutMax       time.Duration
+	AccountDeletionGrace  time.Duration
+	PasswordHash          string
+	BcryptCost            int
+	Argon2MemoryKiB       int
+	Argon2Time            int
+	Argon2Threads         int
+	PasswordMinLength     int
+	PasswordMaxLength     int
+	PasswordMinClasses    int
+	HandleChangeCooldown  time.Duration
+	HandleHoldPeriod      time.Duration
+	MinimumAge            int
+	SuggestionsInterval   time.Duration
+	SuggestionsPerUser    int
+	ReactionNotifyWindow  time.Duration
+}
+
+// OAuthProvider configures one sign-in provider. Empty endpoint, scope and
+// claim fields fall back to the built-in defaults for well-known providers
+// (google, discord, twitch) or to standard OpenID Connect claims otherwise.
+type OAuthProvider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	Scopes       []string
+	Claims       map[string]string
 }
 
 func Load() Config {
 	return Config{
-		Port:               getenv("PORT", "8080"),
-		DBPath:             getenv("DB_PATH", "storage/app.db"),
-		MediaDir:           getenv("MEDIA_DIR", "storage/media"),
-		CookieName:         getenv("COOKIE_NAME", "sid"),
-		CookieSecure:       getenv("COOKIE_SECURE", "false") == "true",
-		CORSOrigin:         getenv("CORS_ORIGIN", "http://localhost:3000"),
-		FrontendURL:        getenv("FRONTEND_URL", ""),
-		GoogleClientID:     getenv("GOOGLE_CLIENT_ID", ""),
-		GoogleClientSecret: getenv("GOOGLE_CLIENT_SECRET", ""),
-		GoogleRedirectURL:  getenv("GOOGLE_REDIRECT_URL", ""),
-		Env:                getenv("APP_ENV", "dev"),
+		Port:                  getenv("PORT", "8080"),
+		DBPath:                getenv("DB_PATH", "storage/app.db"),
+		MediaDir:              getenv("MEDIA_DIR", "storage/media"),
+		CookieName:            getenv("COOKIE_NAME", "sid"),
+		CookieSecure:          getenv("COOKIE_SECURE", "false") == "true",
+		CORSOrigin:            getenv("CORS_ORIGIN", "http://localhost:3000"),
+		AllowedOrigins:        getenvList("ALLOWED_ORIGINS"),
+		FrontendURL:           getenv("FRONTEND_URL", ""),
+		OAuthProviders:        loadOAuthProviders(),
+		Env:                   getenv("APP_ENV", "dev"),
+		SessionIdleTTL:        getenvDuration("SESSION_IDLE_TTL", 7*24*time.Hour),
+		SessionAbsoluteTTL:    getenvDuration("SESSION_ABSOLUTE_TTL", 30*24*time.Hour),
+		SessionRotateInterval: getenvDuration("SESSION_ROTATE_INTERVAL", 24*time.Hour),
+		SessionSweepInterval:  getenvDuration("SESSION_SWEEP_INTERVAL", time.Hour),
+		MailDriver:            getenv("MAIL_DRIVER", "log"),
+		MailFrom:              getenv("MAIL_FROM", "no-reply@localhost"),
+		MailDir:               getenv("MAIL_DIR", "storage/mail"),
+		SMTPHost:              getenv("SMTP_HOST", "localhost"),
+		SMTPPort:              getenv("SMTP_PORT", "587"),
+		SMTPUsername:          getenv("SMTP_USERNAME", ""),
+		SMTPPassword:          getenv("SMTP_PASSWORD", ""),
+		PasswordResetTTL:      getenvDuration("PASSWORD_RESET_TTL", time.Hour),
+		EmailVerificationTTL:  getenvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		RequireVerifiedEmail:  getenvList("REQUIRE_VERIFIED_EMAIL"),
+		LoginMaxFailuresEmail: getenvInt("LOGIN_MAX_FAILURES_PER_EMAIL", 5),
+		LoginMaxFailuresIP:    getenvInt("LOGIN_MAX_FAILURES_PER_IP", 20),
+		LoginFailureWindow:    getenvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockoutBase:      getenvDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		LoginLockoutMax:       getenvDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+		AccountDeletionGrace:  getenvDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour),
+		PasswordHash:          getenv("PASSWORD_HASH", "bcrypt"),
+		BcryptCost:            getenvInt("BCRYPT_COST", 12),
+		Argon2MemoryKiB:       getenvInt("ARGON2_MEMORY_KIB", 64*1024),
+		Argon2Time:            getenvInt("ARGON2_TIME", 3),
+		Argon2Threads:         getenvInt("ARGON2_THREADS", 2),
+		PasswordMinLength:     getenvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:     getenvInt("PASSWORD_MAX_LENGTH", 72),
+		PasswordMinClasses:    getenvInt("PASSWORD_MIN_CLASSES", 1),
+		HandleChangeCooldown:  getenvDuration("HANDLE_CHANGE_COOLDOWN", 30*24*time.Hour),
+		HandleHoldPeriod:      getenvDuration("HANDLE_HOLD_PERIOD", 90*24*time.Hour),
+		MinimumAge:            getenvInt("MINIMUM_AGE", 13),
+		SuggestionsInterval:   getenvDuration("SUGGESTIONS_INTERVAL", 6*time.Hour),
+		SuggestionsPerUser:    getenvInt("SUGGESTIONS_PER_USER", 50),
+		ReactionNotifyWindow:  getenvDuration("REACTION_NOTIFY_WINDOW", time.Hour),
+	}
+}
+
+// MediaFilePath maps a stored "media/<name>" path onto MediaDir. Only the
+// base name is used so a bad row can never point outside the directory.
+func (c Config) MediaFilePath(path string) string {
+	return filepath.Join(c.MediaDir, filepath.Base(filepath.FromSlash(path)))
+}
+
+// TrustedOrigins lists the browser origins allowed to make credentialed
+// requests: CORSOrigin plus any ALLOWED_ORIGINS.
+func (c Config) TrustedOrigins() []string {
+	origins := []string{c.CORSOrigin}
+	for _, origin := range c.AllowedOrigins {
+		if !containsString(origins, origin) {
+			origins = append(origins, origin)
+		}
 	}
+	return origins
+}
+
+// RequiresVerifiedEmail reports whether action ("post", "comment", "dm",
+// "group") is reserved for accounts with a verified email.
+func (c Config) RequiresVerifiedEmail(action string) bool {
+	for _, item := range c.RequireVerifiedEmail {
+		if item == action || item == "all" {
+			return true
+		}
+	}
+	return false
 }
 
 func getenv(key, fallback string) string {
@@ -38,3 +154,80 @@ func getenv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getenvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func getenvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+func getenvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// oauthClaimFields are the identity fields whose source claim can be
+// overridden with OAUTH_<NAME>_CLAIM_<FIELD>.
+var oauthClaimFields = []string{"subject", "email", "email_verified", "name", "given_name", "family_name", "picture"}
+
+// loadOAuthProviders reads the providers listed in OAUTH_PROVIDERS from
+// OAUTH_<NAME>_* variables. The older GOOGLE_* variables still enable Google
+// when it is not configured the new way.
+func loadOAuthProviders() []OAuthProvider {
+	names := getenvList("OAUTH_PROVIDERS")
+	if getenv("GOOGLE_CLIENT_ID", "") != "" && !containsString(names, "google") {
+		names = append(names, "google")
+	}
+
+	var providers []OAuthProvider
+	for _, name := range names {
+		prefix := "OAUTH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OAuthProvider{
+			Name:         name,
+			ClientID:     getenv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getenv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getenv(prefix+"REDIRECT_URL", ""),
+			AuthURL:      getenv(prefix+"AUTH_URL", ""),
+			TokenURL:     getenv(prefix+"TOKEN_URL", ""),
+			UserInfoURL:  getenv(prefix+"USERINFO_URL", ""),
+			Scopes:       strings.Fields(strings.ReplaceAll(getenv(prefix+"SCOPES", ""), ",", " ")),
+			Claims:       map[string]string{},
+		}
+		for _, field := range oauthClaimFields {
+			if claim := getenv(prefix+"CLAIM_"+strings.ToUpper(field), ""); claim != "" {
+				provider.Claims[field] = claim
+			}
+		}
+		if name == "google" && provider.ClientID == "" {
+			provider.ClientID = getenv("GOOGLE_CLIENT_ID", "")
+			provider.ClientSecret = getenv("GOOGLE_CLIENT_SECRET", "")
+			provider.RedirectURL = getenv("GOOGLE_REDIRECT_URL", "")
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
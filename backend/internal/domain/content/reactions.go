@@ -0,0 +1,13 @@
+package content
+
+// Reactions are the emoji a post or comment can be reacted with, by name.
+var Reactions = []string{"like", "love", "laugh", "wow", "sad", "angry", "gg", "fire"}
+
+func IsReaction(emoji string) bool {
+	for _, reaction := range Reactions {
+		if reaction == emoji {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package content
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	TagMaxLength = 50
+	MaxTags      = 10
+	MaxMentions  = 20
+)
+
+// A tag or mention only starts at the beginning of the text or after a
+// character that cannot be part of one, so "a#b" and "me@example.com" are
+// neither.
+var (
+	tagPattern     = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#&])#([\p{L}\p{N}_]+)`)
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([A-Za-z0-9_]+)`)
+)
+
+// NormalizeTag is applied to every tag before it is stored or looked up, so
+// #LeagueOfLegends and #leagueoflegends are the same tag.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// IsTag checks a normalized tag. A tag needs a letter, so "#1" in "we're #1"
+// is not one.
+func IsTag(tag string) bool {
+	if tag == "" || len([]rune(tag)) > TagMaxLength {
+		return false
+	}
+	hasLetter := false
+	for _, r := range tag {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r), r == '_':
+		default:
+			return false
+		}
+	}
+	return hasLetter
+}
+
+// ParseTags returns the distinct normalized #tags in text, in order, up to
+// MaxTags.
+func ParseTags(text string) []string {
+	return parseDistinct(tagPattern, text, MaxTags, func(match string) (string, bool) {
+		tag := NormalizeTag(match)
+		return tag, IsTag(tag)
+	})
+}
+
+// ParseMentions returns the distinct lowercased @handles in text, in order,
+// up to MaxMentions. They are not checked further: a handle nobody holds
+// simply mentions nobody.
+func ParseMentions(text string) []string {
+	return parseDistinct(mentionPattern, text, MaxMentions, func(match string) (string, bool) {
+		return strings.ToLower(match), true
+	})
+}
+
+func parseDistinct(pattern *regexp.Regexp, text string, max int, normalize func(string) (string, bool)) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+		value, ok := normalize(match[1])
+		if !ok || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+		if len(result) == max {
+			break
+		}
+	}
+	return result
+}
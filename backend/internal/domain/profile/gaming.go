@@ -0,0 +1,101 @@
+package profile
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Platforms are the gaming networks a user can list an account for.
+var Platforms = []string{"steam", "psn", "xbox", "nintendo", "riot", "battlenet"}
+
+// Visibilities control who sees a platform account: everyone, followers
+// only, or the owner alone.
+var Visibilities = []string{"public", "followers", "private"}
+
+const (
+	MaxFavoriteGames    = 10
+	MaxRolesPerGame     = 5
+	PlatformIDMaxLength = 64
+	GameNameMaxLength   = 60
+	RankMaxLength       = 40
+	RoleMaxLength       = 30
+)
+
+func IsPlatform(platform string) bool {
+	return contains(Platforms, platform)
+}
+
+func IsVisibility(visibility string) bool {
+	return contains(Visibilities, visibility)
+}
+
+// GameSlug turns a game name into the key games are stored and filtered
+// by, so "League of Legends" and "league-of-legends" are the same game.
+func GameSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// ValidatePlatformAccount checks one platform entry after its fields have
+// been trimmed and lowercased where that applies.
+func ValidatePlatformAccount(platform, accountID, visibility string) error {
+	if !IsPlatform(platform) {
+		return errors.New("platform must be one of " + strings.Join(Platforms, ", "))
+	}
+	if accountID == "" {
+		return errors.New("account_id is required")
+	}
+	if utf8.RuneCountInString(accountID) > PlatformIDMaxLength {
+		return errors.New("account_id must be at most " + strconv.Itoa(PlatformIDMaxLength) + " characters")
+	}
+	if !IsVisibility(visibility) {
+		return errors.New("visibility must be one of " + strings.Join(Visibilities, ", "))
+	}
+	return nil
+}
+
+// ValidateFavoriteGame checks one favorite game entry; rank may be empty.
+func ValidateFavoriteGame(name, rank string, roles []string) error {
+	if GameSlug(name) == "" {
+		return errors.New("game name is required")
+	}
+	if utf8.RuneCountInString(name) > GameNameMaxLength {
+		return errors.New("game name must be at most " + strconv.Itoa(GameNameMaxLength) + " characters")
+	}
+	if utf8.RuneCountInString(rank) > RankMaxLength {
+		return errors.New("rank must be at most " + strconv.Itoa(RankMaxLength) + " characters")
+	}
+	if len(roles) > MaxRolesPerGame {
+		return errors.New("at most " + strconv.Itoa(MaxRolesPerGame) + " roles per game")
+	}
+	for _, role := range roles {
+		if role == "" || utf8.RuneCountInString(role) > RoleMaxLength {
+			return errors.New("roles must be 1-" + strconv.Itoa(RoleMaxLength) + " characters")
+		}
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package profile
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	HandleMinLength = 3
+	HandleMaxLength = 30
+)
+
+var (
+	ErrHandleInvalid  = errors.New("handle must be 3-30 characters of a-z, 0-9 or _ and contain a letter")
+	ErrHandleReserved = errors.New("handle is reserved")
+)
+
+// reservedHandles could be mistaken for the site itself or collide with
+// routes and mention keywords.
+var reservedHandles = map[string]bool{
+	"admin": true, "administrator": true, "all": true, "api": true, "auth": true,
+	"everyone": true, "gaming": true, "gamingnetwork": true, "help": true, "here": true,
+	"login": true, "logout": true, "me": true, "mod": true, "moderator": true,
+	"network": true, "null": true, "official": true, "register": true, "root": true,
+	"search": true, "security": true, "settings": true, "staff": true, "support": true,
+	"system": true, "undefined": true,
+}
+
+// NormalizeHandle is applied to every handle before it is validated, stored
+// or looked up, which is what makes handles case-insensitive.
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+}
+
+// ValidateHandle checks a normalized handle.
+func ValidateHandle(handle string) error {
+	if len(handle) < HandleMinLength || len(handle) > HandleMaxLength {
+		return ErrHandleInvalid
+	}
+	hasLetter := false
+	for _, r := range handle {
+		switch {
+		case r >= 'a' && r <= 'z':
+			hasLetter = true
+		case r >= '0' && r <= '9', r == '_':
+		default:
+			return ErrHandleInvalid
+		}
+	}
+	if !hasLetter {
+		return ErrHandleInvalid
+	}
+	if reservedHandles[handle] || isGeneratedHandle(handle) {
+		return ErrHandleReserved
+	}
+	return nil
+}
+
+// SuggestHandle derives a valid base handle from the first candidate that
+// yields one, such as a nickname or a name. It leaves room for the numeric
+// suffix callers add when the base is taken.
+func SuggestHandle(candidates ...string) string {
+	for _, candidate := range candidates {
+		var b strings.Builder
+		for _, r := range strings.ToLower(candidate) {
+			switch {
+			case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+				b.WriteRune(r)
+			case r == ' ', r == '-', r == '.':
+				b.WriteByte('_')
+			}
+		}
+		handle := strings.Trim(b.String(), "_")
+		if len(handle) > HandleMaxLength-4 {
+			handle = handle[:HandleMaxLength-4]
+		}
+		for len(handle) > 0 && len(handle) < HandleMinLength {
+			handle += "_"
+		}
+		if ValidateHandle(handle) == nil {
+			return handle
+		}
+	}
+	return "player"
+}
+
+// isGeneratedHandle matches the user<id> handles given to accounts that had
+// nothing better to derive one from; nobody else may take that shape.
+func isGeneratedHandle(handle string) bool {
+	digits := strings.TrimPrefix(handle, "user")
+	if digits == handle || digits == "" {
+		return false
+	}
+	return strings.Trim(digits, "0123456789") == ""
+}
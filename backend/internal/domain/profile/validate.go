@@ -0,0 +1,111 @@
+package profile
+
+import (
+	"errors"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	EmailMaxLength    = 254
+	NameMaxLength     = 50
+	NicknameMaxLength = 32
+	AboutMaxLength    = 500
+
+	// oldestAge rejects birth dates that are almost certainly typos.
+	oldestAge = 130
+)
+
+// FieldErrors maps a request field to a message fit to show next to it.
+type FieldErrors map[string]string
+
+// Check records err against field, keeping the first error for a field.
+func (f FieldErrors) Check(field string, err error) {
+	if err != nil {
+		if _, exists := f[field]; !exists {
+			f[field] = err.Error()
+		}
+	}
+}
+
+// NormalizeEmail is applied to every email before it is stored or looked
+// up, so addresses differing only by case belong to the same account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail checks a normalized email. Display names and comments that
+// net/mail would otherwise accept are refused.
+func ValidateEmail(email string) error {
+	if email == "" {
+		return errors.New("email is required")
+	}
+	if len(email) > EmailMaxLength {
+		return errors.New("email must be at most " + strconv.Itoa(EmailMaxLength) + " characters")
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || !strings.Contains(email[strings.LastIndex(email, "@"):], ".") {
+		return errors.New("email is not a valid address")
+	}
+	return nil
+}
+
+// ParseDOB parses an ISO date of birth and checks the user is at least
+// minAge years old on now. It returns the date in canonical form.
+func ParseDOB(value string, minAge int, now time.Time) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("date of birth is required")
+	}
+	dob, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return "", errors.New("date of birth must be a date like 2000-12-31")
+	}
+	now = now.UTC()
+	if dob.After(now) {
+		return "", errors.New("date of birth is in the future")
+	}
+	if dob.AddDate(minAge, 0, 0).After(now) {
+		return "", errors.New("you must be at least " + strconv.Itoa(minAge) + " years old")
+	}
+	if dob.AddDate(oldestAge, 0, 0).Before(now) {
+		return "", errors.New("date of birth is not plausible")
+	}
+	return dob.Format("2006-01-02"), nil
+}
+
+// ValidateName checks a required first or last name.
+func ValidateName(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return errors.New("this field is required")
+	}
+	return maxLength(value, NameMaxLength)
+}
+
+// ValidateNickname checks an optional nickname; nil means unchanged and an
+// empty value clears it.
+func ValidateNickname(value *string) error {
+	if value == nil {
+		return nil
+	}
+	return maxLength(strings.TrimSpace(*value), NicknameMaxLength)
+}
+
+// ValidateAbout checks an optional bio the same way as ValidateNickname.
+func ValidateAbout(value *string) error {
+	if value == nil {
+		return nil
+	}
+	return maxLength(strings.TrimSpace(*value), AboutMaxLength)
+}
+
+func maxLength(value string, max int) error {
+	if utf8.RuneCountInString(value) > max {
+		return errors.New("must be at most " + strconv.Itoa(max) + " characters")
+	}
+	return nil
+}
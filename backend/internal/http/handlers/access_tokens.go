@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/http/middleware"
+	"backend/internal/repo"
+)
+
+const (
+	defaultAccessTokenDays = 30
+	maxAccessTokenDays     = 365
+	maxAccessTokenName     = 100
+)
+
+func ListAccessTokens(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		tokens, err := repo.ListAccessTokens(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "list failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"tokens": tokens})
+	}
+}
+
+// CreateAccessToken issues a personal access token. The plain token is only
+// part of this response; afterwards just its prefix is shown.
+func CreateAccessToken(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		var req struct {
+			Name          string   `json:"name"`
+			Scopes        []string `json:"scopes"`
+			ExpiresInDays int      `json:"expires_in_days"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" || len(name) > maxAccessTokenName {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid name"})
+			return
+		}
+		scopes, ok := normalizeScopes(req.Scopes)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid scopes"})
+			return
+		}
+		days := req.ExpiresInDays
+		if days == 0 {
+			days = defaultAccessTokenDays
+		}
+		if days < 0 || days > maxAccessTokenDays {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid expires_in_days"})
+			return
+		}
+
+		token, secret, err := repo.CreateAccessToken(r.Context(), db, current.ID, name, scopes, time.Duration(days)*24*time.Hour)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "create failed"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"token": secret, "access_token": token})
+	}
+}
+
+func RevokeAccessToken(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		tokenID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		deleted, err := repo.DeleteAccessToken(r.Context(), db, current.ID, tokenID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "revoke failed"})
+			return
+		}
+		if !deleted {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "token not found"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// normalizeScopes checks requested scopes against middleware.TokenScopes and
+// drops duplicates. At least one scope is required.
+func normalizeScopes(requested []string) ([]string, bool) {
+	var scopes []string
+	for _, scope := range requested {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		known := false
+		for _, allowed := range middleware.TokenScopes {
+			if scope == allowed {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, false
+		}
+		duplicate := false
+		for _, existing := range scopes {
+			if existing == scope {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, len(scopes) > 0
+}
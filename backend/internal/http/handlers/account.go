@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/http/middleware"
+	"backend/internal/platform/mailer"
+	"backend/internal/platform/password"
+	"backend/internal/repo"
+)
+
+// recentSignInWindow is how fresh a session must be to stand in for a
+// password on accounts that only sign in through OAuth.
+const recentSignInWindow = 10 * time.Minute
+
+// ExportAccount streams a zip with the user's data as data.json plus every
+// file they uploaded under media/.
+func ExportAccount(cfg config.Config, db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		export, err := repo.ExportAccount(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "export failed"})
+			return
+		}
+
+		filename := "gaming-network-export-" + strconv.FormatInt(current.ID, 10) + ".zip"
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.WriteHeader(http.StatusOK)
+
+		archive := zip.NewWriter(w)
+		defer archive.Close()
+
+		data, err := archive.Create("data.json")
+		if err != nil {
+			return
+		}
+		encoder := json.NewEncoder(data)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(map[string]any{
+			"exported_at": time.Now().UTC().Format(time.RFC3339),
+			"account":     export,
+		}); err != nil {
+			log.Printf("export %d: %v", current.ID, err)
+			return
+		}
+		for _, media := range export.Media {
+			if err := addMediaFile(archive, cfg, media.Path); err != nil {
+				log.Printf("export %d: %s: %v", current.ID, media.Path, err)
+			}
+		}
+	}
+}
+
+func addMediaFile(archive *zip.Writer, cfg config.Config, path string) error {
+	file, err := os.Open(cfg.MediaFilePath(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	entry, err := archive.Create("media/" + filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
+
+// DeleteAccount schedules the account for deletion after the configured
+// grace period. The caller has to re-authenticate: with their password, or
+// with a fresh sign-in for accounts without one, plus a second factor when
+// 2FA is on. All sessions and tokens are revoked; signing in again before
+// the deadline cancels the deletion.
+func DeleteAccount(cfg config.Config, db *sql.DB, mail mailer.Mailer, passwords password.Hasher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		var req struct {
+			Password string `json:"password"`
+			Code     string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+
+		status, message, err := reauthenticate(r, db, passwords, current.ID, current.Email, req.Password, req.Code)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "delete failed"})
+			return
+		}
+		if status != 0 {
+			writeJSON(w, status, errorResponse{Error: message})
+			return
+		}
+
+		deleteAt := time.Now().UTC().Add(cfg.AccountDeletionGrace).Truncate(time.Second)
+		if err := repo.ScheduleAccountDeletion(r.Context(), db, current.ID, deleteAt); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "delete failed"})
+			return
+		}
+		middleware.ClearSessionCookie(cfg, w)
+
+		msg := mailer.Message{
+			To:      current.Email,
+			Subject: "Your account will be deleted",
+			Body: "Your account is scheduled for deletion on " + deleteAt.Format("2006-01-02 15:04 MST") + ".\n\n" +
+				"Sign in again before then if you want to keep it. After that date your profile, posts, messages and uploads are removed for good.\n",
+		}
+		go func() {
+			if err := mail.Send(context.Background(), msg); err != nil {
+				log.Printf("account deletion mail: %v", err)
+			}
+		}()
+		writeJSON(w, http.StatusAccepted, map[string]string{
+			"status":                "scheduled",
+			"deletion_scheduled_at": deleteAt.Format(time.RFC3339),
+		})
+	}
+}
+
+// reauthenticate checks the credentials required for destructive account
+// changes. A non-zero status is the rejection to send back.
+func reauthenticate(r *http.Request, db *sql.DB, passwords password.Hasher, userID int64, email, plain, code string) (int, string, error) {
+	hasPassword, err := repo.HasPassword(r.Context(), db, userID)
+	if err != nil {
+		return 0, "", err
+	}
+	if hasPassword {
+		if strings.TrimSpace(plain) == "" {
+			return http.StatusBadRequest, "password required", nil
+		}
+		_, hash, err := repo.GetUserByEmail(r.Context(), db, email)
+		if err != nil {
+			return 0, "", err
+		}
+		if hash == nil {
+			return http.StatusUnauthorized, "invalid credentials", nil
+		}
+		if valid, _ := passwords.Verify(*hash, plain); !valid {
+			return http.StatusUnauthorized, "invalid credentials", nil
+		}
+	} else {
+		sessionID, _ := middleware.CurrentSessionID(r)
+		recent, err := repo.IsRecentSession(r.Context(), db, userID, sessionID, recentSignInWindow)
+		if err != nil {
+			return 0, "", err
+		}
+		if !recent {
+			return http.StatusForbidden, "recent sign-in required", nil
+		}
+	}
+
+	twoFactor, err := repo.IsTwoFactorEnabled(r.Context(), db, userID)
+	if err != nil {
+		return 0, "", err
+	}
+	if twoFactor {
+		valid, err := verifySecondFactor(r.Context(), db, userID, code)
+		if err != nil {
+			return 0, "", err
+		}
+		if !valid {
+			return http.StatusUnauthorized, "invalid code", nil
+		}
+	}
+	return 0, "", nil
+}
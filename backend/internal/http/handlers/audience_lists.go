@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"backend/internal/http/middleware"
+	"backend/internal/repo"
+)
+
+const (
+	maxAudienceListName    = 50
+	maxAudienceListMembers = 1000
+)
+
+// ListAudienceLists returns the current user's audience lists.
+func ListAudienceLists(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		lists, err := repo.ListAudienceLists(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "lists failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"lists": lists})
+	}
+}
+
+func GetAudienceList(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		id, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		list, found, err := repo.GetAudienceList(r.Context(), db, current.ID, id)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "list failed"})
+			return
+		}
+		if !found {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "list not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	}
+}
+
+type audienceListRequest struct {
+	Name      *string `json:"name"`
+	MemberIDs []int64 `json:"member_ids"`
+}
+
+// validate normalizes the request and returns the error to answer, if any.
+// Members must follow the owner, like allowed_follower_ids on posts.
+func (req *audienceListRequest) validate(r *http.Request, db *sql.DB, ownerID int64) (string, error) {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || utf8.RuneCountInString(name) > maxAudienceListName {
+			return "name must be 1-" + strconv.Itoa(maxAudienceListName) + " characters", nil
+		}
+		req.Name = &name
+	}
+	if req.MemberIDs != nil {
+		req.MemberIDs = removeID(uniquePositiveIDs(req.MemberIDs), ownerID)
+		if len(req.MemberIDs) > maxAudienceListMembers {
+			return "too many member_ids", nil
+		}
+		ok, err := repo.EnsureAllowedFollowers(r.Context(), db, ownerID, req.MemberIDs)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "member_ids must be followers", nil
+		}
+	}
+	return "", nil
+}
+
+func CreateAudienceList(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		var req audienceListRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		if req.Name == nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "name required"})
+			return
+		}
+		message, err := req.validate(r, db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "create failed"})
+			return
+		}
+		if message != "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: message})
+			return
+		}
+		list, taken, err := repo.CreateAudienceList(r.Context(), db, current.ID, *req.Name, req.MemberIDs)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "create failed"})
+			return
+		}
+		if taken {
+			writeJSON(w, http.StatusConflict, errorResponse{Error: "list name taken"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, list)
+	}
+}
+
+// UpdateAudienceList renames a list and/or replaces its members. Posts
+// already shared with the list follow the new members.
+func UpdateAudienceList(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		id, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		var req audienceListRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		message, err := req.validate(r, db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "update failed"})
+			return
+		}
+		if message != "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: message})
+			return
+		}
+		list, found, taken, err := repo.UpdateAudienceList(r.Context(), db, current.ID, id, req.Name, req.MemberIDs)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "update failed"})
+			return
+		}
+		if !found {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "list not found"})
+			return
+		}
+		if taken {
+			writeJSON(w, http.StatusConflict, errorResponse{Error: "list name taken"})
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	}
+}
+
+// DeleteAudienceList removes a list. Private posts shared with it are left
+// visible to their author and any allowed_follower_ids only.
+func DeleteAudienceList(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		id, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		deleted, err := repo.DeleteAudienceList(r.Context(), db, current.ID, id)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "delete failed"})
+			return
+		}
+		if !deleted {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "list not found"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
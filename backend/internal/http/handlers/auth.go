@@ -3,15 +3,17 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"backend/internal/config"
+	"backend/internal/domain/profile"
 	"backend/internal/http/middleware"
+	"backend/internal/platform/mailer"
+	"backend/internal/platform/password"
 	"backend/internal/repo"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 type authResponse struct {
@@ -19,7 +21,7 @@ type authResponse struct {
 	Email string `json:"email"`
 }
 
-func Register(cfg config.Config, db *sql.DB) http.HandlerFunc {
+func Register(cfg config.Config, db *sql.DB, mail mailer.Mailer, passwords password.Hasher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
 			Email     string  `json:"email"`
@@ -30,33 +32,99 @@ func Register(cfg config.Config, db *sql.DB) http.HandlerFunc {
 			Avatar    *string `json:"avatar"`
 			Nickname  *string `json:"nickname"`
 			About     *string `json:"about"`
+			Handle    string  `json:"handle"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
 			return
 		}
-		if strings.TrimSpace(req.Email) == "" || strings.TrimSpace(req.Password) == "" ||
-			strings.TrimSpace(req.FirstName) == "" || strings.TrimSpace(req.LastName) == "" || strings.TrimSpace(req.DOB) == "" {
-			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "missing required fields"})
+		email := profile.NormalizeEmail(req.Email)
+		fields := profile.FieldErrors{}
+		fields.Check("email", profile.ValidateEmail(email))
+		if req.Password == "" {
+			fields["password"] = "password is required"
+		}
+		fields.Check("password", password.NewPolicy(cfg).Validate(req.Password, email))
+		fields.Check("first_name", profile.ValidateName(req.FirstName))
+		fields.Check("last_name", profile.ValidateName(req.LastName))
+		dob, err := profile.ParseDOB(req.DOB, cfg.MinimumAge, time.Now())
+		fields.Check("dob", err)
+		fields.Check("nickname", profile.ValidateNickname(req.Nickname))
+		fields.Check("about", profile.ValidateAbout(req.About))
+		// Uploads need an account, so a new account cannot own an avatar yet.
+		if req.Avatar != nil && strings.TrimSpace(*req.Avatar) != "" {
+			fields["avatar"] = errAvatarNotOwned.Error()
+		}
+
+		// A handle is optional; without one it is derived from the nickname
+		// or name once the account exists.
+		handle := profile.NormalizeHandle(req.Handle)
+		if handle != "" {
+			fields.Check("handle", profile.ValidateHandle(handle))
+		} else {
+			nickname := ""
+			if req.Nickname != nil {
+				nickname = *req.Nickname
+			}
+			handle = profile.SuggestHandle(nickname, req.FirstName+" "+req.LastName)
+		}
+		if len(fields) > 0 {
+			writeFieldErrors(w, fields)
 			return
 		}
+		if req.Handle != "" {
+			available, err := repo.HandleAvailable(r.Context(), db, handle, cfg.HandleHoldPeriod)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "register failed"})
+				return
+			}
+			if !available {
+				writeJSON(w, http.StatusConflict, errorResponse{Error: "handle taken"})
+				return
+			}
+		}
 
-		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		hash, err := passwords.Hash(req.Password)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "hash failed"})
 			return
 		}
 
-		id, err := repo.CreateUser(r.Context(), db, req.Email, string(hash), req.FirstName, req.LastName, req.DOB, req.Avatar, req.Nickname, req.About)
+		id, err := repo.CreateUser(r.Context(), db, email, hash, strings.TrimSpace(req.FirstName), strings.TrimSpace(req.LastName), dob, nil, req.Nickname, req.About)
 		if err != nil {
 			writeJSON(w, http.StatusConflict, errorResponse{Error: "email already exists"})
 			return
 		}
-		writeJSON(w, http.StatusCreated, authResponse{ID: id, Email: req.Email})
+		// A requested handle is given exactly or not at all, since it may
+		// have been claimed since the check above; a derived one falls back
+		// to a free variant. Without a handle the account is unusable.
+		claimed := true
+		if req.Handle != "" {
+			claimed, err = repo.ClaimHandle(r.Context(), db, id, handle, cfg.HandleHoldPeriod)
+		} else {
+			_, err = repo.AssignHandle(r.Context(), db, id, handle, cfg.HandleHoldPeriod)
+		}
+		if err != nil || !claimed {
+			if discardErr := repo.DiscardNewUser(r.Context(), db, id); discardErr != nil {
+				log.Printf("register: discard user %d: %v", id, discardErr)
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "register failed"})
+			} else {
+				writeJSON(w, http.StatusConflict, errorResponse{Error: "handle taken"})
+			}
+			return
+		}
+		if err := sendVerificationEmail(r.Context(), cfg, db, mail, id, email); err != nil {
+			log.Printf("register: verification email: %v", err)
+		}
+		writeJSON(w, http.StatusCreated, authResponse{ID: id, Email: email})
 	}
 }
 
-func Login(cfg config.Config, db *sql.DB) http.HandlerFunc {
+// Login checks the password and, when the stored hash predates the current
+// hashing settings, upgrades it in place while the plaintext is at hand.
+func Login(cfg config.Config, db *sql.DB, mail mailer.Mailer, passwords password.Hasher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
 			Email    string `json:"email"`
@@ -71,22 +139,60 @@ func Login(cfg config.Config, db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		id, hash, err := repo.GetUserByEmail(r.Context(), db, req.Email)
+		throttleKeys := loginThrottleKeys(cfg, req.Email, middleware.ClientIP(r))
+		lockedUntil, err := loginLockedUntil(r.Context(), db, throttleKeys)
 		if err != nil {
-			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid credentials"})
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
+			return
+		}
+		if !lockedUntil.IsZero() {
+			writeLockedOut(w, lockedUntil)
 			return
 		}
-		if hash == nil || bcrypt.CompareHashAndPassword([]byte(*hash), []byte(req.Password)) != nil {
+
+		id, hash, err := repo.GetUserByEmail(r.Context(), db, profile.NormalizeEmail(req.Email))
+		var valid, rehash bool
+		if err == nil && hash != nil {
+			valid, rehash = passwords.Verify(*hash, req.Password)
+		}
+		if !valid {
+			// Unknown emails are counted too so lockouts don't reveal which
+			// accounts exist.
+			if until := recordLoginFailure(r.Context(), db, mail, throttleKeys, id, req.Email); !until.IsZero() {
+				writeLockedOut(w, until)
+				return
+			}
 			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid credentials"})
 			return
 		}
+		_ = repo.ClearLoginFailures(r.Context(), db, repo.ThrottleScopeEmail, strings.ToLower(strings.TrimSpace(req.Email)))
+		if rehash {
+			if upgraded, err := passwords.Hash(req.Password); err == nil {
+				if err := repo.RehashPassword(r.Context(), db, id, *hash, upgraded); err != nil {
+					log.Printf("login: rehash password: %v", err)
+				}
+			}
+		}
 
-		token, expiresAt, err := repo.CreateSession(r.Context(), db, id, 7*24*time.Hour)
+		twoFactor, err := repo.IsTwoFactorEnabled(r.Context(), db, id)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
 			return
 		}
-		middleware.SetSessionCookie(cfg, w, token, expiresAt)
+		if twoFactor {
+			challenge, err := repo.CreateLoginChallenge(r.Context(), db, id, loginChallengeTTL)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"two_factor_required": true, "challenge_token": challenge})
+			return
+		}
+
+		if err := startSession(cfg, db, w, r, id); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
+			return
+		}
 		writeJSON(w, http.StatusOK, authResponse{ID: id, Email: req.Email})
 	}
 }
@@ -102,6 +208,23 @@ func Logout(cfg config.Config, db *sql.DB) http.HandlerFunc {
 	}
 }
 
+// startSession creates a session for userID and sets the session cookie,
+// recording the client's user agent and IP so it shows up in /api/sessions.
+// Signing in also cancels a pending account deletion.
+func startSession(cfg config.Config, db *sql.DB, w http.ResponseWriter, r *http.Request, userID int64) error {
+	if cancelled, err := repo.CancelAccountDeletion(r.Context(), db, userID); err != nil {
+		return err
+	} else if cancelled {
+		log.Printf("account %d: deletion cancelled by sign-in", userID)
+	}
+	token, expiresAt, err := repo.CreateSession(r.Context(), db, userID, cfg.SessionIdleTTL, cfg.SessionAbsoluteTTL, r.UserAgent(), middleware.ClientIP(r))
+	if err != nil {
+		return err
+	}
+	middleware.SetSessionCookie(cfg, w, token, expiresAt)
+	return nil
+}
+
 func Me() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user, ok := middleware.CurrentUser(r)
@@ -112,3 +235,16 @@ func Me() http.HandlerFunc {
 		writeJSON(w, http.StatusOK, user)
 	}
 }
+
+// CSRFToken hands the CSRF token to the frontend, which cannot read the
+// API's cookies when it is served from another origin.
+func CSRFToken(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := middleware.EnsureCSRFToken(cfg, w, r)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "csrf failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"csrf_token": token})
+	}
+}
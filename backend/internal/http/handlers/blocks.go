@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"backend/internal/http/middleware"
+	"backend/internal/repo"
+)
+
+// BlockUser hides the two users from each other everywhere and ends any
+// follow between them.
+func BlockUser(db *sql.DB) http.HandlerFunc {
+	return relationHandler(db, "block failed", "blocked", repo.BlockUser)
+}
+
+func UnblockUser(db *sql.DB) http.HandlerFunc {
+	return relationHandler(db, "unblock failed", "", repo.UnblockUser)
+}
+
+// MuteUser hides a user's posts from the feed and their notifications
+// without them knowing.
+func MuteUser(db *sql.DB) http.HandlerFunc {
+	return relationHandler(db, "mute failed", "muted", repo.MuteUser)
+}
+
+func UnmuteUser(db *sql.DB) http.HandlerFunc {
+	return relationHandler(db, "unmute failed", "", repo.UnmuteUser)
+}
+
+// relationHandler applies apply between the current user and the user in
+// the {id} param. An empty status answers 204 like other removals.
+func relationHandler(db *sql.DB, failure, status string, apply func(context.Context, *sql.DB, int64, int64) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		id, ok := parseIDParam(r, "id")
+		if !ok || id == current.ID {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		_, found, err := repo.IsUserPublic(r.Context(), db, id)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: failure})
+			return
+		}
+		if !found {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "user not found"})
+			return
+		}
+		if err := apply(r.Context(), db, current.ID, id); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: failure})
+			return
+		}
+		if status == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": status})
+	}
+}
+
+func ListBlocks(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		users, err := repo.ListBlockedUsers(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "blocks failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"users": users})
+	}
+}
+
+func ListMutes(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		users, err := repo.ListMutedUsers(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "mutes failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"users": users})
+	}
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
+
+	"backend/internal/config"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -12,12 +16,31 @@ type errorResponse struct {
 	Error string `json:"error"`
 }
 
+// fieldErrorResponse tells the client which fields to fix and why.
+type fieldErrorResponse struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields"`
+}
+
+func writeFieldErrors(w http.ResponseWriter, fields map[string]string) {
+	writeJSON(w, http.StatusBadRequest, fieldErrorResponse{Error: "invalid fields", Fields: fields})
+}
+
 func writeJSON(w http.ResponseWriter, status int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
+// frontendURL is the base URL used for redirects and links in emails.
+func frontendURL(cfg config.Config) string {
+	base := cfg.FrontendURL
+	if strings.TrimSpace(base) == "" {
+		base = cfg.CORSOrigin
+	}
+	return strings.TrimRight(base, "/")
+}
+
 func parseIDParam(r *http.Request, key string) (int64, bool) {
 	val := chi.URLParam(r, key)
 	id, err := strconv.ParseInt(val, 10, 64)
@@ -28,15 +51,11 @@ func parseIDParam(r *http.Request, key string) (int64, bool) {
 }
 
 func parsePagination(r *http.Request) (int, int, error) {
-	limit := 20
-	offset := 0
-	if raw := r.URL.Query().Get("limit"); raw != "" {
-		value, err := strconv.Atoi(raw)
-		if err != nil || value <= 0 {
-			return 0, 0, errInvalid("limit")
-		}
-		limit = value
+	limit, err := parseLimit(r)
+	if err != nil {
+		return 0, 0, err
 	}
+	offset := 0
 	if raw := r.URL.Query().Get("offset"); raw != "" {
 		value, err := strconv.Atoi(raw)
 		if err != nil || value < 0 {
@@ -44,10 +63,41 @@ func parsePagination(r *http.Request) (int, int, error) {
 		}
 		offset = value
 	}
+	return limit, offset, nil
+}
+
+func parseLimit(r *http.Request) (int, error) {
+	limit := 20
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 {
+			return 0, errInvalid("limit")
+		}
+		limit = value
+	}
 	if limit > 50 {
 		limit = 50
 	}
-	return limit, offset, nil
+	return limit, nil
+}
+
+// encodeCursor and decodeCursor wrap the sort key of the last item of a
+// page, a timestamp plus an ID to break ties, into an opaque token.
+func encodeCursor(at string, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(at + "|" + strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (string, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, errInvalid("cursor")
+	}
+	at, rawID, ok := strings.Cut(string(raw), "|")
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if !ok || at == "" || err != nil || id <= 0 {
+		return "", 0, errInvalid("cursor")
+	}
+	return at, id, nil
 }
 
 type invalidParamError struct{ field string }
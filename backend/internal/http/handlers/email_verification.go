@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/http/middleware"
+	"backend/internal/platform/mailer"
+	"backend/internal/repo"
+)
+
+const verificationResendInterval = time.Minute
+
+func VerifyEmail(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		if strings.TrimSpace(req.Token) == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "token required"})
+			return
+		}
+		_, ok, err := repo.VerifyEmail(r.Context(), db, strings.TrimSpace(req.Token))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "verify failed"})
+			return
+		}
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid or expired token"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "verified"})
+	}
+}
+
+func ResendVerificationEmail(cfg config.Config, db *sql.DB, mail mailer.Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		if current.EmailVerified {
+			writeJSON(w, http.StatusConflict, errorResponse{Error: "email already verified"})
+			return
+		}
+		sentAt, found, err := repo.LastEmailVerificationSentAt(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "resend failed"})
+			return
+		}
+		if wait := time.Until(sentAt.Add(verificationResendInterval)); found && wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: "too many requests"})
+			return
+		}
+		if err := sendVerificationEmail(r.Context(), cfg, db, mail, current.ID, current.Email); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "resend failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "sent"})
+	}
+}
+
+// sendVerificationEmail issues a fresh token for email and mails the link in
+// the background.
+func sendVerificationEmail(ctx context.Context, cfg config.Config, db *sql.DB, mail mailer.Mailer, userID int64, email string) error {
+	token, err := repo.CreateEmailVerification(ctx, db, userID, email, cfg.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	link := frontendURL(cfg) + "/verify-email?token=" + url.QueryEscape(token)
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Confirm your email address",
+		Body: "Welcome! Please confirm this is your email address by opening the link below:\n" +
+			link + "\n\n" +
+			"The link expires in " + cfg.EmailVerificationTTL.String() + ".\n",
+	}
+	go func() {
+		if err := mail.Send(context.Background(), msg); err != nil {
+			log.Printf("verification mail: %v", err)
+		}
+	}()
+	return nil
+}
@@ -51,7 +51,7 @@ func CreateEvent(db *sql.DB) http.HandlerFunc {
 				if member.ID == current.ID {
 					continue
 				}
-				payload := "{\"event_id\":" + intToString(id) + ",\"group_id\":" + intToString(groupID) + "}"
+				payload := "{\"event_id\":" + intToString(id) + ",\"group_id\":" + intToString(groupID) + ",\"from_user_id\":" + intToString(current.ID) + "}"
 				_ = repo.CreateNotification(r.Context(), db, member.ID, "group_event", payload)
 			}
 		}
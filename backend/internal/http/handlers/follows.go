@@ -38,6 +38,15 @@ func FollowRequest(db *sql.DB) http.HandlerFunc {
 			writeJSON(w, http.StatusNotFound, errorResponse{Error: "user not found"})
 			return
 		}
+		blocked, err := repo.IsBlocked(r.Context(), db, current.ID, req.ToUserID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "follow failed"})
+			return
+		}
+		if blocked {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "forbidden"})
+			return
+		}
 
 		if isPublic {
 			if err := repo.CreateFollow(r.Context(), db, current.ID, req.ToUserID); err != nil {
@@ -135,6 +144,54 @@ func Unfollow(db *sql.DB) http.HandlerFunc {
 	}
 }
 
+// CancelFollowRequest lets the requester withdraw a request that is still
+// pending.
+func CancelFollowRequest(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		id, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		found, err := repo.CancelFollowRequest(r.Context(), db, id, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "cancel failed"})
+			return
+		}
+		if !found {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "request not found"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RemoveFollower is Unfollow from the followee's side.
+func RemoveFollower(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		id, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		if err := repo.DeleteFollow(r.Context(), db, id, current.ID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "remove failed"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
 func ListIncomingFollowRequests(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		current, ok := middleware.CurrentUser(r)
@@ -170,3 +227,26 @@ func ListOutgoingFollowRequests(db *sql.DB) http.HandlerFunc {
 func intToString(id int64) string {
 	return strconv.FormatInt(id, 10)
 }
+
+// SuggestUsers lists people the current user may know, from suggestions
+// refreshed in the background.
+func SuggestUsers(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		limit, err := parseLimit(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		users, err := repo.ListSuggestions(r.Context(), db, current.ID, limit)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "suggestions failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"users": users, "limit": limit})
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/internal/domain/profile"
+	"backend/internal/http/middleware"
+	"backend/internal/repo"
+)
+
+// UpdateGaming edits the gaming section of the current user's profile. Each
+// of platforms and games replaces the whole list when present and is left
+// alone when absent; an account's visibility defaults to public.
+func UpdateGaming(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		var req struct {
+			Platforms []struct {
+				Platform   string `json:"platform"`
+				AccountID  string `json:"account_id"`
+				Visibility string `json:"visibility"`
+			} `json:"platforms"`
+			Games []struct {
+				Name  string   `json:"name"`
+				Rank  string   `json:"rank"`
+				Roles []string `json:"roles"`
+			} `json:"games"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+
+		fields := profile.FieldErrors{}
+		var platforms []repo.PlatformAccount
+		if req.Platforms != nil {
+			platforms = make([]repo.PlatformAccount, 0, len(req.Platforms))
+			seen := map[string]bool{}
+			for i, item := range req.Platforms {
+				account := repo.PlatformAccount{
+					Platform:   strings.ToLower(strings.TrimSpace(item.Platform)),
+					AccountID:  strings.TrimSpace(item.AccountID),
+					Visibility: strings.ToLower(strings.TrimSpace(item.Visibility)),
+				}
+				if account.Visibility == "" {
+					account.Visibility = "public"
+				}
+				field := "platforms[" + strconv.Itoa(i) + "]"
+				if seen[account.Platform] {
+					fields[field] = "platform listed twice"
+					continue
+				}
+				seen[account.Platform] = true
+				fields.Check(field, profile.ValidatePlatformAccount(account.Platform, account.AccountID, account.Visibility))
+				platforms = append(platforms, account)
+			}
+		}
+		var games []repo.FavoriteGame
+		if req.Games != nil {
+			if len(req.Games) > profile.MaxFavoriteGames {
+				fields["games"] = "at most " + strconv.Itoa(profile.MaxFavoriteGames) + " games"
+			}
+			games = make([]repo.FavoriteGame, 0, len(req.Games))
+			seen := map[string]bool{}
+			for i, item := range req.Games {
+				game := repo.FavoriteGame{Name: strings.TrimSpace(item.Name), Roles: make([]string, 0, len(item.Roles))}
+				game.Slug = profile.GameSlug(game.Name)
+				if rank := strings.TrimSpace(item.Rank); rank != "" {
+					game.Rank = &rank
+				}
+				for _, role := range item.Roles {
+					game.Roles = append(game.Roles, strings.TrimSpace(role))
+				}
+				field := "games[" + strconv.Itoa(i) + "]"
+				if game.Slug != "" && seen[game.Slug] {
+					fields[field] = "game listed twice"
+					continue
+				}
+				seen[game.Slug] = true
+				fields.Check(field, profile.ValidateFavoriteGame(game.Name, strings.TrimSpace(item.Rank), game.Roles))
+				games = append(games, game)
+			}
+		}
+		if len(fields) > 0 {
+			writeFieldErrors(w, fields)
+			return
+		}
+
+		if err := repo.UpdateGamingProfile(r.Context(), db, current.ID, platforms, games); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "update failed"})
+			return
+		}
+		gaming, err := repo.GetGamingProfile(r.Context(), db, current.ID, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "update failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, gaming)
+	}
+}
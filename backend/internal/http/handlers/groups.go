@@ -104,7 +104,7 @@ func InviteToGroup(db *sql.DB) http.HandlerFunc {
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "invite failed"})
 			return
 		}
-		payload := "{\"invite_id\":" + intToString(inviteID) + ",\"group_id\":" + intToString(groupID) + "}"
+		payload := "{\"invite_id\":" + intToString(inviteID) + ",\"group_id\":" + intToString(groupID) + ",\"from_user_id\":" + intToString(current.ID) + "}"
 		_ = repo.CreateNotification(r.Context(), db, req.UserID, "group_invite", payload)
 		writeJSON(w, http.StatusOK, map[string]string{"status": "invited"})
 	}
@@ -192,7 +192,7 @@ func RequestJoinGroup(db *sql.DB) http.HandlerFunc {
 		}
 		creatorID, err := repo.GroupCreator(r.Context(), db, groupID)
 		if err == nil {
-			payload := "{\"join_request_id\":" + intToString(requestID) + ",\"group_id\":" + intToString(groupID) + "}"
+			payload := "{\"join_request_id\":" + intToString(requestID) + ",\"group_id\":" + intToString(groupID) + ",\"from_user_id\":" + intToString(current.ID) + "}"
 			_ = repo.CreateNotification(r.Context(), db, creatorID, "group_join_request", payload)
 		}
 		writeJSON(w, http.StatusOK, map[string]string{"status": "requested"})
@@ -319,11 +319,12 @@ func CreateGroupPost(db *sql.DB) http.HandlerFunc {
 			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "text required"})
 			return
 		}
-		post, err := repo.CreatePost(r.Context(), db, current.ID, text, "group", req.MediaPath, nil, &groupID)
+		post, err := repo.CreatePost(r.Context(), db, current.ID, text, "group", req.MediaPath, nil, nil, &groupID)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "create failed"})
 			return
 		}
+		indexPost(r.Context(), db, post)
 		writeJSON(w, http.StatusCreated, toPostResponse(post))
 	}
 }
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/domain/profile"
+	"backend/internal/http/middleware"
+	"backend/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetUserByHandle looks a profile up by handle, ignoring case. An old handle
+// redirects to the current one; the redirect is temporary because the old
+// handle can later go to someone else.
+func GetUserByHandle(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		handle := profile.NormalizeHandle(chi.URLParam(r, "handle"))
+		if profile.ValidateHandle(handle) == profile.ErrHandleInvalid {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "user not found"})
+			return
+		}
+		id, currentHandle, found, err := repo.ResolveHandle(r.Context(), db, handle)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "profile failed"})
+			return
+		}
+		if !found {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "user not found"})
+			return
+		}
+		if currentHandle != handle {
+			w.Header().Set("Location", "/api/users/by-handle/"+url.PathEscape(currentHandle))
+			writeJSON(w, http.StatusFound, map[string]string{"handle": currentHandle})
+			return
+		}
+		writeVisibleProfile(w, r, db, current.ID, id)
+	}
+}
+
+// ChangeHandle lets a user pick a new handle, at most once per cooldown.
+func ChangeHandle(cfg config.Config, db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		var req struct {
+			Handle string `json:"handle"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		handle := profile.NormalizeHandle(req.Handle)
+		if err := profile.ValidateHandle(handle); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		taken, retryAt, err := repo.ChangeHandle(r.Context(), db, current.ID, handle, cfg.HandleChangeCooldown, cfg.HandleHoldPeriod)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "handle change failed"})
+			return
+		}
+		if taken {
+			writeJSON(w, http.StatusConflict, errorResponse{Error: "handle taken"})
+			return
+		}
+		if !retryAt.IsZero() {
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(retryAt).Seconds())+1))
+			writeJSON(w, http.StatusTooManyRequests, map[string]any{"error": "handle changed too recently", "retry_at": retryAt})
+			return
+		}
+
+		updated, _, err := repo.GetUserByID(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "handle change failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, updated)
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"backend/internal/config"
+	"backend/internal/http/middleware"
+	"backend/internal/platform/password"
+	"backend/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func ListIdentities(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		identities, err := repo.ListOAuthAccounts(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "list failed"})
+			return
+		}
+		hasPassword, err := repo.HasPassword(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "list failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"identities": identities, "has_password": hasPassword})
+	}
+}
+
+func UnlinkIdentity(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		removed, lastMethod, err := repo.DeleteOAuthAccount(r.Context(), db, current.ID, chi.URLParam(r, "provider"))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "unlink failed"})
+			return
+		}
+		if lastMethod {
+			writeJSON(w, http.StatusConflict, errorResponse{Error: "cannot remove last login method"})
+			return
+		}
+		if !removed {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "identity not found"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SetPassword lets an account created through OAuth add a password. Accounts
+// that already have one must use the password change flow instead.
+func SetPassword(cfg config.Config, db *sql.DB, passwords password.Hasher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		var req struct {
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		if strings.TrimSpace(req.Password) == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "password required"})
+			return
+		}
+		if err := password.NewPolicy(cfg).Validate(req.Password, current.Email); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		hash, err := passwords.Hash(req.Password)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "hash failed"})
+			return
+		}
+		set, err := repo.SetInitialPassword(r.Context(), db, current.ID, hash)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "set password failed"})
+			return
+		}
+		if !set {
+			writeJSON(w, http.StatusConflict, errorResponse{Error: "password already set"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/platform/mailer"
+	"backend/internal/repo"
+)
+
+type loginThrottleKey struct {
+	scope  string
+	key    string
+	policy repo.LockoutPolicy
+}
+
+// loginThrottleKeys returns the (scope, key) pairs a login attempt counts
+// against, each with its own failure threshold.
+func loginThrottleKeys(cfg config.Config, email, ip string) []loginThrottleKey {
+	base := repo.LockoutPolicy{Window: cfg.LoginFailureWindow, Base: cfg.LoginLockoutBase, Max: cfg.LoginLockoutMax}
+	emailPolicy, ipPolicy := base, base
+	emailPolicy.MaxFailures = cfg.LoginMaxFailuresEmail
+	ipPolicy.MaxFailures = cfg.LoginMaxFailuresIP
+	return []loginThrottleKey{
+		{scope: repo.ThrottleScopeEmail, key: strings.ToLower(strings.TrimSpace(email)), policy: emailPolicy},
+		{scope: repo.ThrottleScopeIP, key: ip, policy: ipPolicy},
+	}
+}
+
+// loginLockedUntil returns the end of the longest active lock among keys.
+func loginLockedUntil(ctx context.Context, db *sql.DB, keys []loginThrottleKey) (time.Time, error) {
+	var latest time.Time
+	for _, k := range keys {
+		if k.policy.MaxFailures == 0 || k.key == "" {
+			continue
+		}
+		until, err := repo.LoginLockedUntil(ctx, db, k.scope, k.key)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if until.After(latest) {
+			latest = until
+		}
+	}
+	return latest, nil
+}
+
+// recordLoginFailure counts a failed attempt against every key. When the
+// email key gets locked and belongs to an account, its owner is told in-app
+// and by email. It returns the longest lock started by this attempt.
+func recordLoginFailure(ctx context.Context, db *sql.DB, mail mailer.Mailer, keys []loginThrottleKey, userID int64, email string) time.Time {
+	var latest time.Time
+	for _, k := range keys {
+		if k.policy.MaxFailures == 0 || k.key == "" {
+			continue
+		}
+		until, locked, err := repo.RecordLoginFailure(ctx, db, k.scope, k.key, k.policy)
+		if err != nil {
+			log.Printf("login throttle: %v", err)
+			continue
+		}
+		if !locked {
+			continue
+		}
+		if until.After(latest) {
+			latest = until
+		}
+		if k.scope == repo.ThrottleScopeEmail && userID > 0 {
+			notifyLockout(ctx, db, mail, userID, email, until)
+		}
+	}
+	return latest
+}
+
+func notifyLockout(ctx context.Context, db *sql.DB, mail mailer.Mailer, userID int64, email string, until time.Time) {
+	lockedUntil := until.UTC().Format(time.RFC3339)
+	payload := "{\"locked_until\":\"" + lockedUntil + "\"}"
+	_ = repo.CreateNotification(ctx, db, userID, "login_lockout", payload)
+
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Sign-in temporarily locked",
+		Body: "There were several failed attempts to sign in to your account, so signing in is locked until " +
+			lockedUntil + ".\n\n" +
+			"If this was not you, consider resetting your password.\n",
+	}
+	go func() {
+		if err := mail.Send(context.Background(), msg); err != nil {
+			log.Printf("lockout mail: %v", err)
+		}
+	}()
+}
+
+func writeLockedOut(w http.ResponseWriter, until time.Time) {
+	seconds := int(time.Until(until).Seconds()) + 1
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: "too many failed attempts"})
+}
@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/domain/profile"
+	"backend/internal/http/middleware"
+	"backend/internal/platform/oauth"
+	"backend/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	oauthStateCookie = "oauth_state"
+	oauthIntentLogin = "login"
+	oauthIntentLink  = "link"
+)
+
+var errOAuthEmailTaken = errors.New("email already registered")
+
+// oauthState is kept in a short-lived cookie between the start and callback
+// requests. Binding the provider and intent to the random state stops a
+// callback for one provider or flow being replayed against another. Link
+// flows also record the user who started them.
+type oauthState struct {
+	Value    string
+	Provider string
+	Intent   string
+	UserID   int64
+}
+
+func (s oauthState) encode() string {
+	return s.Value + "." + s.Provider + "." + s.Intent + "." + intToString(s.UserID)
+}
+
+func decodeOAuthState(raw string) (oauthState, bool) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 4 || parts[0] == "" {
+		return oauthState{}, false
+	}
+	userID, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return oauthState{}, false
+	}
+	return oauthState{Value: parts[0], Provider: parts[1], Intent: parts[2], UserID: userID}, true
+}
+
+func OAuthProviders(providers *oauth.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"providers": providers.Names()})
+	}
+}
+
+func OAuthStart(cfg config.Config, providers *oauth.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startOAuth(cfg, providers, w, r, oauthIntentLogin, 0)
+	}
+}
+
+// LinkOAuthStart begins the provider flow for a signed-in user who wants to
+// add that provider as another way to log in.
+func LinkOAuthStart(cfg config.Config, providers *oauth.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		startOAuth(cfg, providers, w, r, oauthIntentLink, current.ID)
+	}
+}
+
+func startOAuth(cfg config.Config, providers *oauth.Registry, w http.ResponseWriter, r *http.Request, intent string, userID int64) {
+	provider, ok := providers.Get(chi.URLParam(r, "provider"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "unknown provider"})
+		return
+	}
+
+	value, err := randomState(32)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "state failed"})
+		return
+	}
+	state := oauthState{Value: value, Provider: provider.Name, Intent: intent, UserID: userID}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state.encode(),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   cfg.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().Add(10 * time.Minute),
+	})
+
+	http.Redirect(w, r, provider.AuthCodeURL(state.Value), http.StatusFound)
+}
+
+func OAuthCallback(cfg config.Config, db *sql.DB, providers *oauth.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider, ok := providers.Get(chi.URLParam(r, "provider"))
+		if !ok {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "unknown provider"})
+			return
+		}
+
+		value := r.URL.Query().Get("state")
+		if value == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "missing state"})
+			return
+		}
+		cookie, err := r.Cookie(oauthStateCookie)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid state"})
+			return
+		}
+		state, ok := decodeOAuthState(cookie.Value)
+		if !ok || state.Value != value || state.Provider != provider.Name {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid state"})
+			return
+		}
+
+		code := r.URL.Query().Get("code")
+		if code == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "missing code"})
+			return
+		}
+
+		identity, err := provider.Exchange(r.Context(), code)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		if state.Intent == oauthIntentLink {
+			linkOAuthIdentity(cfg, db, w, r, state.UserID, provider.Name, identity)
+			return
+		}
+		if state.Intent != oauthIntentLogin {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid state"})
+			return
+		}
+		if !identity.EmailVerified {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "email not verified"})
+			return
+		}
+
+		userID, err := ensureOAuthUser(r, cfg, db, provider.Name, identity)
+		if err == errOAuthEmailTaken {
+			// Linking must be an explicit choice made while signed in,
+			// otherwise whoever controls the provider account takes over
+			// the local one.
+			writeJSON(w, http.StatusConflict, errorResponse{Error: "email already registered, sign in and link this provider instead"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "oauth failed"})
+			return
+		}
+
+		// A provider sign-in stands in for the password only: with 2FA on,
+		// the frontend gets a challenge to finish at /api/auth/login/2fa, as
+		// after the password step. It rides in the fragment so it stays out
+		// of server logs and Referer headers.
+		twoFactor, err := repo.IsTwoFactorEnabled(r.Context(), db, userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
+			return
+		}
+		if twoFactor {
+			challenge, err := repo.CreateLoginChallenge(r.Context(), db, userID, loginChallengeTTL)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
+				return
+			}
+			clearOAuthStateCookie(cfg, w)
+			http.Redirect(w, r, frontendURL(cfg)+"/login#challenge_token="+url.QueryEscape(challenge), http.StatusFound)
+			return
+		}
+
+		if err := startSession(cfg, db, w, r, userID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
+			return
+		}
+
+		clearOAuthStateCookie(cfg, w)
+		http.Redirect(w, r, frontendURL(cfg), http.StatusFound)
+	}
+}
+
+// linkOAuthIdentity attaches identity to the account that started the link
+// flow. The session cookie must still belong to that account.
+func linkOAuthIdentity(cfg config.Config, db *sql.DB, w http.ResponseWriter, r *http.Request, userID int64, provider string, identity oauth.Identity) {
+	session, err := r.Cookie(cfg.CookieName)
+	if err != nil || session.Value == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+		return
+	}
+	current, _, ok, err := repo.GetSessionUser(r.Context(), db, session.Value)
+	if err != nil || !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+		return
+	}
+	if current.ID != userID {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid state"})
+		return
+	}
+
+	ownerID, found, err := repo.GetUserIDByOAuth(r.Context(), db, provider, identity.Subject)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "link failed"})
+		return
+	}
+	if found && ownerID != userID {
+		writeJSON(w, http.StatusConflict, errorResponse{Error: "identity linked to another account"})
+		return
+	}
+	if !found {
+		linked, err := repo.HasOAuthAccount(r.Context(), db, userID, provider)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "link failed"})
+			return
+		}
+		if linked {
+			writeJSON(w, http.StatusConflict, errorResponse{Error: "provider already linked"})
+			return
+		}
+		if err := repo.CreateOAuthAccount(r.Context(), db, userID, provider, identity.Subject, identity.Email); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "link failed"})
+			return
+		}
+	}
+
+	clearOAuthStateCookie(cfg, w)
+	http.Redirect(w, r, frontendURL(cfg), http.StatusFound)
+}
+
+func clearOAuthStateCookie(cfg config.Config, w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   cfg.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+	})
+}
+
+func ensureOAuthUser(r *http.Request, cfg config.Config, db *sql.DB, provider string, identity oauth.Identity) (int64, error) {
+	userID, found, err := repo.GetUserIDByOAuth(r.Context(), db, provider, identity.Subject)
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return userID, nil
+	}
+
+	email := profile.NormalizeEmail(identity.Email)
+	userID, exists, err := repo.GetUserIDByEmail(r.Context(), db, email)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, errOAuthEmailTaken
+	}
+	firstName, lastName := deriveNames(identity)
+	userID, err = repo.CreateOAuthUser(r.Context(), db, email, firstName, lastName, stringPtr(identity.Picture))
+	if err != nil {
+		return 0, err
+	}
+	// A half-created account would block the email from signing in again,
+	// so it is dropped if the handle or the provider link can't be stored.
+	localPart, _, _ := strings.Cut(email, "@")
+	_, err = repo.AssignHandle(r.Context(), db, userID, profile.SuggestHandle(identity.Name, firstName+" "+lastName, localPart), cfg.HandleHoldPeriod)
+	if err == nil {
+		err = repo.CreateOAuthAccount(r.Context(), db, userID, provider, identity.Subject, identity.Email)
+	}
+	if err != nil {
+		if discardErr := repo.DiscardNewUser(r.Context(), db, userID); discardErr != nil {
+			log.Printf("oauth: discard user %d: %v", userID, discardErr)
+		}
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func deriveNames(identity oauth.Identity) (string, string) {
+	first := strings.TrimSpace(identity.GivenName)
+	last := strings.TrimSpace(identity.FamilyName)
+	if first != "" || last != "" {
+		return firstOrFallback(first, identity), lastOrFallback(last, identity)
+	}
+
+	full := strings.Fields(strings.TrimSpace(identity.Name))
+	if len(full) > 1 {
+		return full[0], strings.Join(full[1:], " ")
+	}
+	if len(full) == 1 {
+		return full[0], ""
+	}
+
+	local := strings.Split(strings.TrimSpace(identity.Email), "@")
+	if len(local) > 0 && local[0] != "" {
+		return local[0], ""
+	}
+	return "User", ""
+}
+
+func firstOrFallback(value string, identity oauth.Identity) string {
+	if strings.TrimSpace(value) != "" {
+		return strings.TrimSpace(value)
+	}
+	local := strings.Split(strings.TrimSpace(identity.Email), "@")
+	if len(local) > 0 && local[0] != "" {
+		return local[0]
+	}
+	return "User"
+}
+
+func lastOrFallback(value string, identity oauth.Identity) string {
+	if strings.TrimSpace(value) != "" {
+		return strings.TrimSpace(value)
+	}
+	return ""
+}
+
+func randomState(length int) (string, error) {
+	buf := make([]byte, length)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func stringPtr(value string) *string {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
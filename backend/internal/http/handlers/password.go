@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"backend/internal/config"
+	"backend/internal/http/middleware"
+	"backend/internal/platform/mailer"
+	"backend/internal/platform/password"
+	"backend/internal/repo"
+)
+
+// ChangePassword replaces the password of the signed-in user after checking
+// the current one. Every other session and all access tokens are revoked,
+// and the user is told by email, in case it was not them.
+func ChangePassword(cfg config.Config, db *sql.DB, mail mailer.Mailer, passwords password.Hasher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		var req struct {
+			CurrentPassword string `json:"current_password"`
+			NewPassword     string `json:"new_password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		if strings.TrimSpace(req.CurrentPassword) == "" || strings.TrimSpace(req.NewPassword) == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "current_password and new_password required"})
+			return
+		}
+
+		_, hash, err := repo.GetUserByEmail(r.Context(), db, current.Email)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "change failed"})
+			return
+		}
+		if hash == nil {
+			writeJSON(w, http.StatusConflict, errorResponse{Error: "no password set"})
+			return
+		}
+		if valid, _ := passwords.Verify(*hash, req.CurrentPassword); !valid {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid credentials"})
+			return
+		}
+		if req.NewPassword == req.CurrentPassword {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "new password must differ"})
+			return
+		}
+		if err := password.NewPolicy(cfg).Validate(req.NewPassword, current.Email); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		newHash, err := passwords.Hash(req.NewPassword)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "hash failed"})
+			return
+		}
+		sessionID, _ := middleware.CurrentSessionID(r)
+		revoked, err := repo.ChangePassword(r.Context(), db, current.ID, newHash, sessionID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "change failed"})
+			return
+		}
+
+		msg := mailer.Message{
+			To:      current.Email,
+			Subject: "Your password was changed",
+			Body: "The password for your account was just changed and your other sessions were signed out and your access tokens revoked.\n\n" +
+				"If this was not you, reset your password at " + frontendURL(cfg) + "/forgot-password right away.\n",
+		}
+		go func() {
+			if err := mail.Send(context.Background(), msg); err != nil {
+				log.Printf("password changed mail: %v", err)
+			}
+		}()
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "revoked_sessions": revoked})
+	}
+}
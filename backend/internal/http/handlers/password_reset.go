@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"backend/internal/config"
+	"backend/internal/domain/profile"
+	"backend/internal/platform/mailer"
+	"backend/internal/platform/password"
+	"backend/internal/repo"
+)
+
+// ForgotPassword emails a reset link when the address belongs to an account.
+// The response is the same either way so it cannot be used to probe which
+// emails are registered, and the mail is sent in the background for the
+// same reason.
+func ForgotPassword(cfg config.Config, db *sql.DB, mail mailer.Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		email := profile.NormalizeEmail(req.Email)
+		if email == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "email required"})
+			return
+		}
+
+		userID, found, err := repo.GetUserIDByEmail(r.Context(), db, email)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "request failed"})
+			return
+		}
+		if found {
+			token, err := repo.CreatePasswordReset(r.Context(), db, userID, cfg.PasswordResetTTL)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "request failed"})
+				return
+			}
+			link := frontendURL(cfg) + "/reset-password?token=" + url.QueryEscape(token)
+			msg := mailer.Message{
+				To:      email,
+				Subject: "Reset your password",
+				Body: "Someone asked to reset the password for your account.\n\n" +
+					"Open this link to choose a new one:\n" + link + "\n\n" +
+					"The link expires in " + cfg.PasswordResetTTL.String() + ". If you did not ask for this, you can ignore this email.\n",
+			}
+			go func() {
+				if err := mail.Send(context.Background(), msg); err != nil {
+					log.Printf("password reset mail: %v", err)
+				}
+			}()
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
+
+// ResetPassword sets a new password from a reset token. Every existing
+// session and access token of the account is revoked, including any an
+// attacker may hold.
+func ResetPassword(cfg config.Config, db *sql.DB, passwords password.Hasher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Token    string `json:"token"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		if strings.TrimSpace(req.Token) == "" || strings.TrimSpace(req.Password) == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "token and password required"})
+			return
+		}
+
+		if err := password.NewPolicy(cfg).Validate(req.Password, ""); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		hash, err := passwords.Hash(req.Password)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "hash failed"})
+			return
+		}
+		_, ok, err := repo.ResetPassword(r.Context(), db, strings.TrimSpace(req.Token), hash)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reset failed"})
+			return
+		}
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid or expired token"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "reset"})
+	}
+}
@@ -17,7 +17,49 @@ type postResponse struct {
 	Text      string  `json:"text"`
 	Visibility string `json:"visibility"`
 	MediaPath *string `json:"media_path,omitempty"`
+	AudienceListID *int64 `json:"audience_list_id,omitempty"`
 	CreatedAt string  `json:"created_at"`
+	EditedAt  *string `json:"edited_at,omitempty"`
+	Reactions map[string]int `json:"reactions"`
+	MyReaction *string `json:"my_reaction,omitempty"`
+}
+
+// checkPostAudience validates who a new or edited post is shown to. It
+// returns the allowed follower ids to store, or the message to answer 400
+// with.
+func checkPostAudience(r *http.Request, db *sql.DB, authorID int64, visibility string, allowedIDs []int64, audienceListID *int64) ([]int64, string, error) {
+	if visibility != "public" && visibility != "followers" && visibility != "private" {
+		return nil, "invalid visibility", nil
+	}
+	allowed := uniquePositiveIDs(allowedIDs)
+	allowed = removeID(allowed, authorID)
+
+	if visibility != "private" {
+		if len(allowed) > 0 || audienceListID != nil {
+			return nil, "allowed_follower_ids and audience_list_id only for private", nil
+		}
+		return allowed, "", nil
+	}
+	if len(allowed) == 0 && audienceListID == nil {
+		return nil, "allowed_follower_ids or audience_list_id required", nil
+	}
+	ok, err := repo.EnsureAllowedFollowers(r.Context(), db, authorID, allowed)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return nil, "allowed_follower_ids must be followers", nil
+	}
+	if audienceListID != nil {
+		owned, err := repo.IsAudienceListOwner(r.Context(), db, *audienceListID, authorID)
+		if err != nil {
+			return nil, "", err
+		}
+		if !owned {
+			return nil, "audience_list_id must be one of your lists", nil
+		}
+	}
+	return allowed, "", nil
 }
 
 func CreatePost(db *sql.DB) http.HandlerFunc {
@@ -32,6 +74,7 @@ func CreatePost(db *sql.DB) http.HandlerFunc {
 			Text              string  `json:"text"`
 			Visibility        string  `json:"visibility"`
 			AllowedFollowerIDs []int64 `json:"allowed_follower_ids"`
+			AudienceListID    *int64  `json:"audience_list_id"`
 			MediaPath         *string `json:"media_path"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -43,33 +86,22 @@ func CreatePost(db *sql.DB) http.HandlerFunc {
 			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "text required"})
 			return
 		}
-		if req.Visibility != "public" && req.Visibility != "followers" && req.Visibility != "private" {
-			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid visibility"})
+		allowed, message, err := checkPostAudience(r, db, current.ID, req.Visibility, req.AllowedFollowerIDs, req.AudienceListID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "create failed"})
 			return
 		}
-		allowed := uniquePositiveIDs(req.AllowedFollowerIDs)
-		allowed = removeID(allowed, current.ID)
-
-		if req.Visibility == "private" {
-			if len(allowed) == 0 {
-				writeJSON(w, http.StatusBadRequest, errorResponse{Error: "allowed_follower_ids required"})
-				return
-			}
-			ok, err := repo.EnsureAllowedFollowers(r.Context(), db, current.ID, allowed)
-			if err != nil || !ok {
-				writeJSON(w, http.StatusBadRequest, errorResponse{Error: "allowed_follower_ids must be followers"})
-				return
-			}
-		} else if len(allowed) > 0 {
-			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "allowed_follower_ids only for private"})
+		if message != "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: message})
 			return
 		}
 
-		post, err := repo.CreatePost(r.Context(), db, current.ID, text, req.Visibility, req.MediaPath, allowed, nil)
+		post, err := repo.CreatePost(r.Context(), db, current.ID, text, req.Visibility, req.MediaPath, allowed, req.AudienceListID, nil)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "create failed"})
 			return
 		}
+		indexPost(r.Context(), db, post)
 		writeJSON(w, http.StatusCreated, toPostResponse(post))
 	}
 }
@@ -121,6 +153,169 @@ func UserPosts(db *sql.DB) http.HandlerFunc {
 	}
 }
 
+// UpdatePost lets the author change a post's text, media and audience. The
+// version it replaces is kept as a revision. Sending visibility replaces
+// allowed_follower_ids and audience_list_id as on creation.
+func UpdatePost(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		postID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		var req struct {
+			Text              *string `json:"text"`
+			Visibility        *string `json:"visibility"`
+			AllowedFollowerIDs []int64 `json:"allowed_follower_ids"`
+			AudienceListID    *int64  `json:"audience_list_id"`
+			MediaPath         *string `json:"media_path"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		if req.Text == nil && req.Visibility == nil && req.MediaPath == nil && req.AllowedFollowerIDs == nil && req.AudienceListID == nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "nothing to update"})
+			return
+		}
+
+		post, err := repo.GetPostByID(r.Context(), db, postID)
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "post not found"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "update failed"})
+			return
+		}
+		if post.UserID != current.ID {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "forbidden"})
+			return
+		}
+
+		edit := repo.PostEdit{Text: post.Text, MediaPath: post.MediaPath, Visibility: post.Visibility}
+		if req.Text != nil {
+			edit.Text = strings.TrimSpace(*req.Text)
+			if edit.Text == "" {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: "text required"})
+				return
+			}
+		}
+		if req.MediaPath != nil {
+			edit.MediaPath = req.MediaPath
+		}
+		if req.Visibility != nil {
+			if post.GroupID != nil {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: "group posts keep their visibility"})
+				return
+			}
+			allowed, message, err := checkPostAudience(r, db, current.ID, *req.Visibility, req.AllowedFollowerIDs, req.AudienceListID)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "update failed"})
+				return
+			}
+			if message != "" {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: message})
+				return
+			}
+			edit.Visibility = *req.Visibility
+			edit.ReplaceAudience = true
+			edit.AllowedIDs = allowed
+			edit.AudienceListID = req.AudienceListID
+		} else if req.AllowedFollowerIDs != nil || req.AudienceListID != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "visibility required with allowed_follower_ids or audience_list_id"})
+			return
+		}
+
+		post, err = repo.UpdatePost(r.Context(), db, postID, edit)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "update failed"})
+			return
+		}
+		indexPost(r.Context(), db, post)
+		posts := []repo.Post{post}
+		if err := repo.LoadPostReactions(r.Context(), db, current.ID, posts); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "update failed"})
+			return
+		}
+		post = posts[0]
+		writeJSON(w, http.StatusOK, toPostResponse(post))
+	}
+}
+
+// DeletePost removes a post. Group posts can also be removed by the group's
+// creator.
+func DeletePost(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		postID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		post, err := repo.GetPostByID(r.Context(), db, postID)
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "post not found"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "delete failed"})
+			return
+		}
+		allowed := post.UserID == current.ID
+		if !allowed && post.GroupID != nil {
+			creatorID, err := repo.GroupCreator(r.Context(), db, *post.GroupID)
+			allowed = err == nil && creatorID == current.ID
+		}
+		if !allowed {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "forbidden"})
+			return
+		}
+		if _, err := repo.DeletePost(r.Context(), db, postID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "delete failed"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListPostRevisions returns a post's earlier versions to anyone who can see
+// the post.
+func ListPostRevisions(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		postID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		canView, err := repo.CanViewPost(r.Context(), db, current.ID, postID)
+		if err != nil || !canView {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "forbidden"})
+			return
+		}
+		revisions, err := repo.ListPostRevisions(r.Context(), db, postID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "revisions failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"revisions": revisions})
+	}
+}
+
 func CreateComment(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		current, ok := middleware.CurrentUser(r)
@@ -156,6 +351,7 @@ func CreateComment(db *sql.DB) http.HandlerFunc {
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "comment failed"})
 			return
 		}
+		indexComment(r.Context(), db, comment)
 		writeJSON(w, http.StatusCreated, toCommentResponse(comment))
 	}
 }
@@ -182,7 +378,7 @@ func ListComments(db *sql.DB) http.HandlerFunc {
 			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
 			return
 		}
-		comments, err := repo.ListComments(r.Context(), db, postID, limit, offset)
+		comments, err := repo.ListComments(r.Context(), db, current.ID, postID, limit, offset)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "comments failed"})
 			return
@@ -192,6 +388,10 @@ func ListComments(db *sql.DB) http.HandlerFunc {
 }
 
 func toPostResponse(post repo.Post) postResponse {
+	reactions := post.Reactions
+	if reactions == nil {
+		reactions = map[string]int{}
+	}
 	return postResponse{
 		ID: post.ID,
 		UserID: post.UserID,
@@ -199,7 +399,11 @@ func toPostResponse(post repo.Post) postResponse {
 		Text: post.Text,
 		Visibility: post.Visibility,
 		MediaPath: post.MediaPath,
+		AudienceListID: post.AudienceListID,
 		CreatedAt: post.CreatedAt,
+		EditedAt: post.EditedAt,
+		Reactions: reactions,
+		MyReaction: post.MyReaction,
 	}
 }
 
@@ -212,6 +416,10 @@ func toPostResponses(posts []repo.Post) []postResponse {
 }
 
 func toCommentResponse(comment repo.Comment) map[string]any {
+	reactions := comment.Reactions
+	if reactions == nil {
+		reactions = map[string]int{}
+	}
 	return map[string]any{
 		"id": comment.ID,
 		"post_id": comment.PostID,
@@ -219,6 +427,8 @@ func toCommentResponse(comment repo.Comment) map[string]any {
 		"text": comment.Text,
 		"media_path": comment.MediaPath,
 		"created_at": comment.CreatedAt,
+		"reactions": reactions,
+		"my_reaction": comment.MyReaction,
 	}
 }
 
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/internal/http/middleware"
+	"backend/internal/repo"
+	"backend/internal/ws"
+)
+
+const maxPresenceUsers = 100
+
+// GetPresence reports the status of the users in user_ids, a comma
+// separated list. Users whose presence the viewer may not see are left out
+// rather than shown offline.
+func GetPresence(db *sql.DB, hub *ws.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		var ids []int64
+		seen := map[int64]bool{}
+		for _, raw := range strings.Split(r.URL.Query().Get("user_ids"), ",") {
+			if raw = strings.TrimSpace(raw); raw == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || id <= 0 {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid user_ids"})
+				return
+			}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "user_ids required"})
+			return
+		}
+		if len(ids) > maxPresenceUsers {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "too many user_ids"})
+			return
+		}
+
+		records, err := repo.ListPresence(r.Context(), db, current.ID, ids)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "presence failed"})
+			return
+		}
+		presence := make([]ws.Presence, 0, len(records))
+		for _, record := range records {
+			presence = append(presence, hub.Presence(record))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"presence": presence})
+	}
+}
+
+// UpdatePresence sets the current user's status (online or away), what
+// they are playing and who may see their presence. The same update can be
+// sent over the websocket as a presence_set message.
+func UpdatePresence(db *sql.DB, hub *ws.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		var req ws.PresenceUpdate
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		if fields := req.Normalize(); len(fields) > 0 {
+			writeFieldErrors(w, fields)
+			return
+		}
+		presence, err := ws.UpdatePresence(r.Context(), db, hub, current.ID, req)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "presence failed"})
+			return
+		}
+		record, err := repo.GetPresence(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "presence failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"presence": presence, "visibility": record.Visibility})
+	}
+}
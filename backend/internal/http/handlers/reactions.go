@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/domain/content"
+	"backend/internal/http/middleware"
+	"backend/internal/repo"
+	"backend/internal/ws"
+)
+
+// decodeReaction reads {"emoji": "..."} and answers 400 unless it is one of
+// content.Reactions.
+func decodeReaction(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req struct {
+		Emoji string `json:"emoji"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+		return "", false
+	}
+	emoji := strings.TrimSpace(req.Emoji)
+	if !content.IsReaction(emoji) {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "emoji must be one of " + strings.Join(content.Reactions, ", ")})
+		return "", false
+	}
+	return emoji, true
+}
+
+// parseReactionFilter reads the optional emoji filter of a "who reacted"
+// list.
+func parseReactionFilter(r *http.Request) (string, bool) {
+	emoji := strings.TrimSpace(r.URL.Query().Get("emoji"))
+	return emoji, emoji == "" || content.IsReaction(emoji)
+}
+
+// ReactToPost sets the current user's reaction to a post, replacing any
+// earlier one. The author is notified at most once per post every
+// REACTION_NOTIFY_WINDOW.
+func ReactToPost(cfg config.Config, db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		postID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		emoji, ok := decodeReaction(w, r)
+		if !ok {
+			return
+		}
+		canView, err := repo.CanViewPost(r.Context(), db, current.ID, postID)
+		if err != nil || !canView {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "forbidden"})
+			return
+		}
+		if err := repo.SetPostReaction(r.Context(), db, postID, current.ID, emoji); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reaction failed"})
+			return
+		}
+		post, err := repo.GetPostByID(r.Context(), db, postID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reaction failed"})
+			return
+		}
+		if post.UserID != current.ID {
+			payload := "{\"post_id\":" + intToString(postID) + ",\"from_user_id\":" + intToString(current.ID) + ",\"emoji\":\"" + emoji + "\"}"
+			_ = repo.NotifyPostReaction(r.Context(), db, post.UserID, postID, payload, time.Now().Add(-cfg.ReactionNotifyWindow))
+		}
+		posts := []repo.Post{post}
+		if err := repo.LoadPostReactions(r.Context(), db, current.ID, posts); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reaction failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"reactions": posts[0].Reactions, "my_reaction": posts[0].MyReaction})
+	}
+}
+
+func RemovePostReaction(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		postID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		if err := repo.RemovePostReaction(r.Context(), db, postID, current.ID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reaction failed"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListPostReactions lists who reacted to a post the current user can see,
+// optionally only with ?emoji=.
+func ListPostReactions(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		postID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		emoji, ok := parseReactionFilter(r)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid emoji"})
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		canView, err := repo.CanViewPost(r.Context(), db, current.ID, postID)
+		if err != nil || !canView {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "forbidden"})
+			return
+		}
+		reactors, err := repo.ListPostReactions(r.Context(), db, current.ID, postID, emoji, limit, offset)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reactions failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"users": reactors, "limit": limit, "offset": offset})
+	}
+}
+
+func ReactToComment(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		commentID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		emoji, ok := decodeReaction(w, r)
+		if !ok {
+			return
+		}
+		canView, err := repo.CanViewComment(r.Context(), db, current.ID, commentID)
+		if err != nil || !canView {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "forbidden"})
+			return
+		}
+		if err := repo.SetCommentReaction(r.Context(), db, commentID, current.ID, emoji); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reaction failed"})
+			return
+		}
+		comment, err := repo.GetCommentByID(r.Context(), db, commentID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reaction failed"})
+			return
+		}
+		comments := []repo.Comment{comment}
+		if err := repo.LoadCommentReactions(r.Context(), db, current.ID, comments); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reaction failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"reactions": comments[0].Reactions, "my_reaction": comments[0].MyReaction})
+	}
+}
+
+func RemoveCommentReaction(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		commentID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		if err := repo.RemoveCommentReaction(r.Context(), db, commentID, current.ID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reaction failed"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func ListCommentReactions(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		commentID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		emoji, ok := parseReactionFilter(r)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid emoji"})
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		canView, err := repo.CanViewComment(r.Context(), db, current.ID, commentID)
+		if err != nil || !canView {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "forbidden"})
+			return
+		}
+		reactors, err := repo.ListCommentReactions(r.Context(), db, current.ID, commentID, emoji, limit, offset)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reactions failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"users": reactors, "limit": limit, "offset": offset})
+	}
+}
+
+// ReactToMessage sets the current user's reaction to a direct or group
+// message they take part in, and tells everyone in the conversation.
+func ReactToMessage(db *sql.DB, hub *ws.Hub, kind repo.MessageKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		messageID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		emoji, ok := decodeReaction(w, r)
+		if !ok {
+			return
+		}
+		canView, err := repo.CanViewMessage(r.Context(), db, kind, current.ID, messageID)
+		if err != nil || !canView {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "forbidden"})
+			return
+		}
+		if err := repo.SetMessageReaction(r.Context(), db, kind, messageID, current.ID, emoji); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reaction failed"})
+			return
+		}
+		reactions, mine, err := publishMessageReaction(r, db, hub, kind, messageID, current.ID, &emoji)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reaction failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"reactions": reactions, "my_reaction": mine})
+	}
+}
+
+func RemoveMessageReaction(db *sql.DB, hub *ws.Hub, kind repo.MessageKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		messageID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		canView, err := repo.CanViewMessage(r.Context(), db, kind, current.ID, messageID)
+		if err != nil || !canView {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "forbidden"})
+			return
+		}
+		if err := repo.RemoveMessageReaction(r.Context(), db, kind, messageID, current.ID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reaction failed"})
+			return
+		}
+		if _, _, err := publishMessageReaction(r, db, hub, kind, messageID, current.ID, nil); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reaction failed"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// publishMessageReaction sends the new reaction counts of a message to its
+// conversation and returns them with the current user's own reaction.
+func publishMessageReaction(r *http.Request, db *sql.DB, hub *ws.Hub, kind repo.MessageKind, messageID, userID int64, emoji *string) (map[string]int, *string, error) {
+	reactions, mine, err := repo.LoadMessageReactions(r.Context(), db, kind, userID, messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	audience, _, err := repo.MessageAudience(r.Context(), db, kind, messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	eventType := "dm_reaction"
+	if kind == repo.GroupMessage {
+		eventType = "group_reaction"
+	}
+	ws.PublishMessageReaction(hub, audience, ws.MessageReaction{
+		Type:      eventType,
+		MessageID: messageID,
+		UserID:    userID,
+		Emoji:     emoji,
+		Reactions: reactions,
+	})
+	return reactions, mine, nil
+}
+
+func ListMessageReactions(db *sql.DB, kind repo.MessageKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		messageID, ok := parseIDParam(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+			return
+		}
+		emoji, ok := parseReactionFilter(r)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid emoji"})
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		canView, err := repo.CanViewMessage(r.Context(), db, kind, current.ID, messageID)
+		if err != nil || !canView {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "forbidden"})
+			return
+		}
+		reactors, err := repo.ListMessageReactions(r.Context(), db, kind, current.ID, messageID, emoji, limit, offset)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "reactions failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"users": reactors, "limit": limit, "offset": offset})
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"backend/internal/config"
+	"backend/internal/http/middleware"
+	"backend/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func ListSessions(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		sessions, err := repo.ListSessions(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "sessions failed"})
+			return
+		}
+		currentID, _ := middleware.CurrentSessionID(r)
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID == currentID
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"sessions": sessions})
+	}
+}
+
+func GetSession(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		sessionID := strings.TrimSpace(chi.URLParam(r, "id"))
+		session, found, err := repo.GetSession(r.Context(), db, current.ID, sessionID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "session failed"})
+			return
+		}
+		if !found {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "session not found"})
+			return
+		}
+		currentID, _ := middleware.CurrentSessionID(r)
+		session.Current = session.ID == currentID
+		writeJSON(w, http.StatusOK, session)
+	}
+}
+
+func RevokeSession(cfg config.Config, db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		sessionID := strings.TrimSpace(chi.URLParam(r, "id"))
+		deleted, err := repo.DeleteSessionByID(r.Context(), db, current.ID, sessionID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "revoke failed"})
+			return
+		}
+		if !deleted {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "session not found"})
+			return
+		}
+		if currentID, _ := middleware.CurrentSessionID(r); currentID == sessionID {
+			middleware.ClearSessionCookie(cfg, w)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func RevokeOtherSessions(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		currentID, ok := middleware.CurrentSessionID(r)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "no current session"})
+			return
+		}
+		revoked, err := repo.DeleteOtherSessions(r.Context(), db, current.ID, currentID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "revoke failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"revoked": revoked})
+	}
+}
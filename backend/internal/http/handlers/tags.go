@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+
+	"backend/internal/domain/content"
+	"backend/internal/http/middleware"
+	"backend/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// indexPost stores the #tags and @mentions of a post's text. Only users
+// who can see the post count as mentioned, so one who later gains access
+// through an edit is mentioned then. Each newly mentioned user gets a
+// mention notification. Failures are logged: the post itself is already
+// saved.
+func indexPost(ctx context.Context, db *sql.DB, post repo.Post) {
+	mentioned, err := visibleMentions(ctx, db, post.UserID, post.Text, func(userID int64) (bool, error) {
+		return repo.CanViewPost(ctx, db, userID, post.ID)
+	})
+	if err == nil {
+		mentioned, err = repo.IndexPost(ctx, db, post.ID, content.ParseTags(post.Text), mentioned)
+	}
+	if err != nil {
+		log.Printf("index post %d: %v", post.ID, err)
+		return
+	}
+	payload := "{\"post_id\":" + intToString(post.ID) + ",\"from_user_id\":" + intToString(post.UserID) + "}"
+	for _, userID := range mentioned {
+		_ = repo.CreateNotification(ctx, db, userID, "mention", payload)
+	}
+}
+
+// indexComment is indexPost for comments.
+func indexComment(ctx context.Context, db *sql.DB, comment repo.Comment) {
+	mentioned, err := visibleMentions(ctx, db, comment.UserID, comment.Text, func(userID int64) (bool, error) {
+		return repo.CanViewComment(ctx, db, userID, comment.ID)
+	})
+	if err == nil {
+		mentioned, err = repo.IndexComment(ctx, db, comment.ID, content.ParseTags(comment.Text), mentioned)
+	}
+	if err != nil {
+		log.Printf("index comment %d: %v", comment.ID, err)
+		return
+	}
+	payload := "{\"post_id\":" + intToString(comment.PostID) + ",\"comment_id\":" + intToString(comment.ID) + ",\"from_user_id\":" + intToString(comment.UserID) + "}"
+	for _, userID := range mentioned {
+		_ = repo.CreateNotification(ctx, db, userID, "mention", payload)
+	}
+}
+
+// visibleMentions resolves the @handles of text to users other than the
+// author and keeps those canView allows.
+func visibleMentions(ctx context.Context, db *sql.DB, authorID int64, text string, canView func(userID int64) (bool, error)) ([]int64, error) {
+	userIDs, err := repo.UsersByHandle(ctx, db, authorID, content.ParseMentions(text))
+	if err != nil {
+		return nil, err
+	}
+	visible := []int64{}
+	for _, userID := range userIDs {
+		ok, err := canView(userID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			visible = append(visible, userID)
+		}
+	}
+	return visible, nil
+}
+
+// TagPosts is the timeline of a tag: the tagged posts the current user may
+// see, newest first.
+func TagPosts(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		tag := content.NormalizeTag(chi.URLParam(r, "tag"))
+		if !content.IsTag(tag) {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid tag"})
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		posts, err := repo.TagPosts(r.Context(), db, current.ID, tag, limit, offset)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "posts failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"tag": tag, "posts": toPostResponses(posts), "limit": limit, "offset": offset})
+	}
+}
+
+// SearchTags autocompletes ?q= against the tags used on public posts. An
+// empty q lists the most used tags.
+func SearchTags(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := middleware.CurrentUser(r); !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		limit, err := parseLimit(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		tags, err := repo.SearchTags(r.Context(), db, content.NormalizeTag(r.URL.Query().Get("q")), limit)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "tags failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"tags": tags})
+	}
+}
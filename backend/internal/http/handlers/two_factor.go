@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/http/middleware"
+	"backend/internal/platform/mailer"
+	"backend/internal/platform/totp"
+	"backend/internal/repo"
+)
+
+const (
+	totpIssuer             = "Gaming Network"
+	loginChallengeTTL      = 5 * time.Minute
+	maxLoginChallengeTries = 5
+	recoveryCodeCount      = 10
+)
+
+func TwoFactorStatus(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		enabled, err := repo.IsTwoFactorEnabled(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "2fa failed"})
+			return
+		}
+		remaining, err := repo.CountRecoveryCodes(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "2fa failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"enabled": enabled, "recovery_codes_remaining": remaining})
+	}
+}
+
+func SetupTwoFactor(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		enabled, err := repo.IsTwoFactorEnabled(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "setup failed"})
+			return
+		}
+		if enabled {
+			writeJSON(w, http.StatusConflict, errorResponse{Error: "2fa already enabled"})
+			return
+		}
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "setup failed"})
+			return
+		}
+		if err := repo.SaveTOTPSecret(r.Context(), db, current.ID, secret); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "setup failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"secret":      secret,
+			"otpauth_uri": totp.URI(totpIssuer, current.Email, secret),
+		})
+	}
+}
+
+func ConfirmTwoFactor(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		state, found, err := repo.GetTOTP(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "confirm failed"})
+			return
+		}
+		if !found {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "2fa setup required"})
+			return
+		}
+		if state.Confirmed {
+			writeJSON(w, http.StatusConflict, errorResponse{Error: "2fa already enabled"})
+			return
+		}
+		step, valid := totp.Validate(state.Secret, req.Code, time.Now())
+		if !valid {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid code"})
+			return
+		}
+		if _, err := repo.MarkTOTPStepUsed(r.Context(), db, current.ID, step); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "confirm failed"})
+			return
+		}
+		codes, err := issueRecoveryCodes(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "confirm failed"})
+			return
+		}
+		if err := repo.ConfirmTOTP(r.Context(), db, current.ID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "confirm failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"enabled": true, "recovery_codes": codes})
+	}
+}
+
+func DisableTwoFactor(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		valid, err := verifySecondFactor(r.Context(), db, current.ID, req.Code)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "disable failed"})
+			return
+		}
+		if !valid {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid code"})
+			return
+		}
+		if err := repo.DeleteTwoFactor(r.Context(), db, current.ID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "disable failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"enabled": false})
+	}
+}
+
+func RegenerateRecoveryCodes(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		valid, err := verifySecondFactor(r.Context(), db, current.ID, req.Code)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "regenerate failed"})
+			return
+		}
+		if !valid {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid code"})
+			return
+		}
+		codes, err := issueRecoveryCodes(r.Context(), db, current.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "regenerate failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+	}
+}
+
+// LoginTwoFactor completes a login started by Login for an account with 2FA
+// enabled. The session is only created once the code checks out.
+func LoginTwoFactor(cfg config.Config, db *sql.DB, mail mailer.Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ChallengeToken string `json:"challenge_token"`
+			Code           string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
+			return
+		}
+		if strings.TrimSpace(req.ChallengeToken) == "" || strings.TrimSpace(req.Code) == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "challenge_token and code required"})
+			return
+		}
+		userID, ok, err := repo.ClaimLoginChallengeAttempt(r.Context(), db, req.ChallengeToken, maxLoginChallengeTries)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
+			return
+		}
+		if !ok {
+			_ = repo.DeleteLoginChallenge(r.Context(), db, req.ChallengeToken)
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "challenge expired"})
+			return
+		}
+		profile, found, err := repo.GetUserByID(r.Context(), db, userID)
+		if err != nil || !found {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
+			return
+		}
+
+		// Wrong codes count against the account like wrong passwords, so
+		// fresh challenges don't buy an attacker holding the password more
+		// guesses than the lockout allows.
+		throttleKeys := loginThrottleKeys(cfg, profile.Email, middleware.ClientIP(r))
+		lockedUntil, err := loginLockedUntil(r.Context(), db, throttleKeys)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
+			return
+		}
+		if !lockedUntil.IsZero() {
+			writeLockedOut(w, lockedUntil)
+			return
+		}
+		valid, err := verifySecondFactor(r.Context(), db, userID, req.Code)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
+			return
+		}
+		if !valid {
+			if until := recordLoginFailure(r.Context(), db, mail, throttleKeys, userID, profile.Email); !until.IsZero() {
+				writeLockedOut(w, until)
+				return
+			}
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid code"})
+			return
+		}
+		_ = repo.DeleteLoginChallenge(r.Context(), db, req.ChallengeToken)
+		_ = repo.ClearLoginFailures(r.Context(), db, repo.ThrottleScopeEmail, strings.ToLower(strings.TrimSpace(profile.Email)))
+
+		if err := startSession(cfg, db, w, r, userID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, authResponse{ID: userID, Email: profile.Email})
+	}
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused
+// recovery code. Recovery codes are consumed on success.
+func verifySecondFactor(ctx context.Context, db *sql.DB, userID int64, code string) (bool, error) {
+	state, found, err := repo.GetTOTP(ctx, db, userID)
+	if err != nil || !found || !state.Confirmed {
+		return false, err
+	}
+	code = strings.TrimSpace(code)
+	if step, valid := totp.Validate(state.Secret, code, time.Now()); valid {
+		return repo.MarkTOTPStepUsed(ctx, db, userID, step)
+	}
+	return repo.UseRecoveryCode(ctx, db, userID, normalizeRecoveryCode(code))
+}
+
+func issueRecoveryCodes(ctx context.Context, db *sql.DB, userID int64) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	normalized := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := randomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		normalized = append(normalized, normalizeRecoveryCode(code))
+	}
+	if err := repo.ReplaceRecoveryCodes(ctx, db, userID, normalized); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// recoveryCodeAlphabet is lowercase base32: 32 symbols, so every random byte
+// maps onto it without bias.
+const recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+// randomRecoveryCode returns a code like "k3m9p-x2q7r".
+func randomRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	out := make([]byte, 0, 11)
+	for i, b := range buf {
+		if i == 5 {
+			out = append(out, '-')
+		}
+		out = append(out, recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+	}
+	return string(out), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
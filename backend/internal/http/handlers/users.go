@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
+	"backend/internal/domain/profile"
 	"backend/internal/http/middleware"
 	"backend/internal/repo"
 )
@@ -21,30 +25,111 @@ func GetUser(db *sql.DB) http.HandlerFunc {
 			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
 			return
 		}
-		profile, found, err := repo.GetUserByID(r.Context(), db, id)
+		writeVisibleProfile(w, r, db, current.ID, id)
+	}
+}
+
+// writeVisibleProfile responds with a user's profile if the viewer may see
+// it, along with the gaming accounts they're allowed to see.
+func writeVisibleProfile(w http.ResponseWriter, r *http.Request, db *sql.DB, viewerID, id int64) {
+	profile, status, message := profileAccess(r, db, viewerID, id)
+	if status != http.StatusOK {
+		writeJSON(w, status, errorResponse{Error: message})
+		return
+	}
+	gaming, err := repo.GetGamingProfile(r.Context(), db, viewerID, id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "profile failed"})
+		return
+	}
+	profile.Gaming = &gaming
+	writeJSON(w, http.StatusOK, profile)
+}
+
+// profileAccess loads a profile and checks the viewer may see it: a private
+// profile only shows to its owner and their followers, and users who blocked
+// each other don't see each other at all. Anything but 200 comes with the
+// error to answer.
+func profileAccess(r *http.Request, db *sql.DB, viewerID, id int64) (repo.UserProfile, int, string) {
+	profile, found, err := repo.GetUserByID(r.Context(), db, id)
+	if err != nil {
+		return repo.UserProfile{}, http.StatusInternalServerError, "profile failed"
+	}
+	if found && id != viewerID {
+		blocked, err := repo.IsBlocked(r.Context(), db, viewerID, id)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "profile failed"})
-			return
+			return repo.UserProfile{}, http.StatusInternalServerError, "profile failed"
 		}
-		if !found {
-			writeJSON(w, http.StatusNotFound, errorResponse{Error: "user not found"})
+		found = !blocked
+	}
+	if !found {
+		return repo.UserProfile{}, http.StatusNotFound, "user not found"
+	}
+	if !profile.IsPublic && profile.ID != viewerID {
+		follows, err := repo.IsFollowing(r.Context(), db, viewerID, profile.ID)
+		if err != nil {
+			return repo.UserProfile{}, http.StatusInternalServerError, "profile failed"
+		}
+		if !follows {
+			return repo.UserProfile{}, http.StatusForbidden, "forbidden"
+		}
+	}
+	return profile, http.StatusOK, ""
+}
+
+// SearchUsers looks people up by handle, nickname or name, or by exact email,
+// optionally narrowed to a game or platform. Either q or a filter is needed.
+func SearchUsers(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
 			return
 		}
-		if !profile.IsPublic && profile.ID != current.ID {
-			follows, err := repo.IsFollowing(r.Context(), db, current.ID, profile.ID)
-			if err != nil {
-				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "profile failed"})
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		var filter repo.UserSearchFilter
+		if game := r.URL.Query().Get("game"); game != "" {
+			if filter.Game = profile.GameSlug(game); filter.Game == "" {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid game"})
 				return
 			}
-			if !follows {
-				writeJSON(w, http.StatusForbidden, errorResponse{Error: "forbidden"})
+		}
+		if platform := r.URL.Query().Get("platform"); platform != "" {
+			if filter.Platform = strings.ToLower(platform); !profile.IsPlatform(filter.Platform) {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid platform"})
 				return
 			}
 		}
-		writeJSON(w, http.StatusOK, profile)
+		if query == "" && filter == (repo.UserSearchFilter{}) {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "q required"})
+			return
+		}
+		if len(query) > maxSearchQueryLength {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "q too long"})
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		users, err := repo.SearchUsers(r.Context(), db, current.ID, query, filter, limit, offset)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "search failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"users": users, "limit": limit, "offset": offset})
 	}
 }
 
+const maxSearchQueryLength = 100
+
+// errAvatarNotOwned keeps users from pointing their avatar at someone else's
+// upload or at an arbitrary URL.
+var errAvatarNotOwned = errors.New("avatar must be an image you uploaded")
+
+// UpdateMe edits the current user's profile. Making the profile public with
+// accept_pending_requests also accepts every pending follow request.
 func UpdateMe(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		current, ok := middleware.CurrentUser(r)
@@ -57,48 +142,107 @@ func UpdateMe(db *sql.DB) http.HandlerFunc {
 			Avatar   *string `json:"avatar"`
 			Nickname *string `json:"nickname"`
 			About    *string `json:"about"`
+
+			AcceptPendingRequests bool `json:"accept_pending_requests"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json"})
 			return
 		}
-		profile, err := repo.UpdateMe(r.Context(), db, current.ID, req.IsPublic, req.Avatar, req.Nickname, req.About)
+		fields := profile.FieldErrors{}
+		fields.Check("nickname", profile.ValidateNickname(req.Nickname))
+		fields.Check("about", profile.ValidateAbout(req.About))
+		if req.AcceptPendingRequests && (req.IsPublic == nil || !*req.IsPublic) {
+			fields["accept_pending_requests"] = "only applies when setting is_public to true"
+		}
+		if req.Avatar != nil && strings.TrimSpace(*req.Avatar) != "" {
+			owned, err := repo.IsMediaOwner(r.Context(), db, strings.TrimSpace(*req.Avatar), current.ID)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "update failed"})
+				return
+			}
+			if !owned {
+				fields["avatar"] = errAvatarNotOwned.Error()
+			}
+		}
+		if len(fields) > 0 {
+			writeFieldErrors(w, fields)
+			return
+		}
+		updated, err := repo.UpdateMe(r.Context(), db, current.ID, req.IsPublic, req.Avatar, req.Nickname, req.About)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "update failed"})
 			return
 		}
-		writeJSON(w, http.StatusOK, profile)
+		if req.AcceptPendingRequests {
+			if _, err := repo.AcceptPendingFollowRequests(r.Context(), db, current.ID); err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "update failed"})
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, updated)
 	}
 }
 
+// ListFollowers and ListFollowing page through a user's follow lists when
+// the viewer may see that user's profile. Pass next_cursor back as cursor
+// for the next page; q searches within the list.
 func ListFollowers(db *sql.DB) http.HandlerFunc {
+	return followListHandler(db, "followers failed", repo.ListFollowers)
+}
+
+func ListFollowing(db *sql.DB) http.HandlerFunc {
+	return followListHandler(db, "following failed", repo.ListFollowing)
+}
+
+type followLister func(ctx context.Context, db *sql.DB, viewerID, userID int64, page repo.FollowListPage) ([]repo.FollowCard, error)
+
+func followListHandler(db *sql.DB, failure string, list followLister) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := middleware.CurrentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
 		id, ok := parseIDParam(r, "id")
 		if !ok {
 			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
 			return
 		}
-		followers, err := repo.ListFollowers(r.Context(), db, id)
+		limit, err := parseLimit(r)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "followers failed"})
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
 			return
 		}
-		writeJSON(w, http.StatusOK, map[string]any{"users": followers})
-	}
-}
-
-func ListFollowing(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		id, ok := parseIDParam(r, "id")
-		if !ok {
-			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid id"})
+		page := repo.FollowListPage{Search: strings.TrimSpace(r.URL.Query().Get("q")), Limit: limit + 1}
+		if len(page.Search) > maxSearchQueryLength {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "q too long"})
 			return
 		}
-		following, err := repo.ListFollowing(r.Context(), db, id)
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			if page.AfterFollowedAt, page.AfterID, err = decodeCursor(cursor); err != nil {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+				return
+			}
+		}
+		if _, status, message := profileAccess(r, db, current.ID, id); status != http.StatusOK {
+			writeJSON(w, status, errorResponse{Error: message})
+			return
+		}
+
+		users, err := list(r.Context(), db, current.ID, id, page)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "following failed"})
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: failure})
 			return
 		}
-		writeJSON(w, http.StatusOK, map[string]any{"users": following})
+		// One extra card is fetched to tell whether another page exists.
+		var next *string
+		if len(users) > limit {
+			users = users[:limit]
+			last := users[limit-1]
+			cursor := encodeCursor(last.FollowedAt, last.ID)
+			next = &cursor
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"users": users, "limit": limit, "next_cursor": next})
 	}
 }
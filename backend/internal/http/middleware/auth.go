@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"backend/internal/config"
@@ -13,7 +15,21 @@ import (
 
 type contextKey string
 
-const userContextKey contextKey = "user"
+const (
+	userContextKey    contextKey = "user"
+	sessionContextKey contextKey = "session"
+	scopesContextKey  contextKey = "scopes"
+)
+
+// Scopes a personal access token can be granted. Session cookies implicitly
+// carry all of them.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeChat  = "chat"
+)
+
+var TokenScopes = []string{ScopeRead, ScopeWrite, ScopeChat}
 
 type errorResponse struct {
 	Error string `json:"error"`
@@ -22,30 +38,129 @@ type errorResponse struct {
 func RequireAuth(cfg config.Config, db *sql.DB) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token, ok := bearerToken(r); ok {
+				authenticateToken(db, w, r, next, token)
+				return
+			}
+
 			session, err := r.Cookie(cfg.CookieName)
 			if err != nil || session.Value == "" {
 				writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
 				return
 			}
 
-			profile, ok, err := repo.GetSessionUser(r.Context(), db, session.Value)
+			profile, sessionID, ok, err := repo.GetSessionUser(r.Context(), db, session.Value)
 			if err != nil || !ok {
 				writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
 				return
 			}
 
+			// The websocket handshake response can't carry a new cookie, so
+			// never rotate on upgrade requests.
+			rotateEvery := cfg.SessionRotateInterval
+			if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				rotateEvery = 0
+			}
+			token, expiresAt, changed, err := repo.RefreshSession(r.Context(), db, session.Value, r.UserAgent(), ClientIP(r), cfg.SessionIdleTTL, rotateEvery)
+			if err == nil && changed {
+				SetSessionCookie(cfg, w, token, expiresAt)
+			}
+
 			ctx := context.WithValue(r.Context(), userContextKey, profile)
+			ctx = context.WithValue(ctx, sessionContextKey, sessionID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// authenticateToken serves requests that carry a personal access token in
+// place of the session cookie.
+func authenticateToken(db *sql.DB, w http.ResponseWriter, r *http.Request, next http.Handler, token string) {
+	tokenID, userID, scopes, found, err := repo.LookupAccessToken(r.Context(), db, token)
+	if err != nil || !found {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+		return
+	}
+	profile, found, err := repo.GetUserByID(r.Context(), db, userID)
+	if err != nil || !found {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+		return
+	}
+	_ = repo.TouchAccessToken(r.Context(), db, tokenID, ClientIP(r))
+
+	ctx := context.WithValue(r.Context(), userContextKey, profile)
+	ctx = context.WithValue(ctx, scopesContextKey, scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
+
+// RequireScope lets token-authenticated requests through only when the token
+// was granted scope. Cookie sessions always pass. It must run after
+// RequireAuth.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scopes, ok := r.Context().Value(scopesContextKey).([]string); ok && !hasScope(scopes, scope) {
+				writeJSON(w, http.StatusForbidden, errorResponse{Error: "insufficient scope"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects requests that are not made with a session cookie.
+// Account security endpoints use it so a leaked token cannot be used to take
+// over the account.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := CurrentSessionID(r); !ok {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "session required"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, item := range scopes {
+		if item == scope {
+			return true
+		}
+	}
+	return false
+}
+
 func CurrentUser(r *http.Request) (repo.UserProfile, bool) {
 	value := r.Context().Value(userContextKey)
 	profile, ok := value.(repo.UserProfile)
 	return profile, ok
 }
 
+func CurrentSessionID(r *http.Request) (string, bool) {
+	value := r.Context().Value(sessionContextKey)
+	sessionID, ok := value.(string)
+	return sessionID, ok && sessionID != ""
+}
+
+// ClientIP returns the caller address without its port. chimw.RealIP has
+// already replaced RemoteAddr with the forwarded address when one is present.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func SetSessionCookie(cfg config.Config, w http.ResponseWriter, token string, expiresAt time.Time) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     cfg.CookieName,
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"backend/internal/config"
+)
+
+// RequireVerifiedEmail rejects the request when cfg reserves action for
+// verified accounts and the current user has not verified their email yet.
+// It must run after RequireAuth.
+func RequireVerifiedEmail(cfg config.Config, action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.RequiresVerifiedEmail(action) {
+				user, ok := CurrentUser(r)
+				if !ok {
+					writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+					return
+				}
+				if !user.EmailVerified {
+					writeJSON(w, http.StatusForbidden, errorResponse{Error: "email not verified"})
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -8,6 +8,10 @@ import (
 	"backend/internal/config"
 	"backend/internal/http/handlers"
 	appmw "backend/internal/http/middleware"
+	"backend/internal/platform/mailer"
+	"backend/internal/platform/oauth"
+	"backend/internal/platform/password"
+	"backend/internal/repo"
 	"backend/internal/ws"
 
 	"github.com/go-chi/chi/v5"
@@ -23,60 +27,157 @@ func NewRouter(cfg config.Config, db *sql.DB) http.Handler {
 	r.Use(chimw.NoCache)
 	r.Use(chimw.CleanPath)
 
-	r.Use(appmw.CORS(cfg.CORSOrigin))
+	r.Use(appmw.CORS(cfg.TrustedOrigins()))
+
+	mail := mailer.New(cfg)
+	passwords := password.New(cfg)
+	providers := oauth.NewRegistry(cfg.OAuthProviders)
+	hub := ws.NewHub()
 
 	r.Get("/health", handlers.Health())
 
 	r.Route("/api", func(api chi.Router) {
-		api.Post("/auth/register", handlers.Register(cfg, db))
-		api.Post("/auth/login", handlers.Login(cfg, db))
+		api.Use(appmw.CSRF(cfg))
+
+		api.Get("/auth/csrf", handlers.CSRFToken(cfg))
+		api.Post("/auth/register", handlers.Register(cfg, db, mail, passwords))
+		api.Post("/auth/login", handlers.Login(cfg, db, mail, passwords))
+		api.Post("/auth/login/2fa", handlers.LoginTwoFactor(cfg, db, mail))
 		api.Post("/auth/logout", handlers.Logout(cfg, db))
-		api.Get("/auth/google/start", handlers.GoogleStart(cfg))
-		api.Get("/auth/google/callback", handlers.GoogleCallback(cfg, db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/me", handlers.Me())
-
-		api.With(appmw.RequireAuth(cfg, db)).Get("/users/{id}", handlers.GetUser(db))
-		api.With(appmw.RequireAuth(cfg, db)).Patch("/users/me", handlers.UpdateMe(db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/users/{id}/followers", handlers.ListFollowers(db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/users/{id}/following", handlers.ListFollowing(db))
-
-		api.With(appmw.RequireAuth(cfg, db)).Post("/follows/request", handlers.FollowRequest(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/follows/request/{id}/accept", handlers.AcceptFollow(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/follows/request/{id}/refuse", handlers.RefuseFollow(db))
-		api.With(appmw.RequireAuth(cfg, db)).Delete("/follows/{id}", handlers.Unfollow(db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/follows/requests/incoming", handlers.ListIncomingFollowRequests(db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/follows/requests/outgoing", handlers.ListOutgoingFollowRequests(db))
-
-		api.With(appmw.RequireAuth(cfg, db)).Post("/posts", handlers.CreatePost(db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/feed", handlers.Feed(db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/users/{id}/posts", handlers.UserPosts(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/posts/{id}/comments", handlers.CreateComment(db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/posts/{id}/comments", handlers.ListComments(db))
-
-		api.With(appmw.RequireAuth(cfg, db)).Post("/media/upload", handlers.UploadMedia(cfg, db))
-
-		api.With(appmw.RequireAuth(cfg, db)).Post("/groups", handlers.CreateGroup(db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/groups", handlers.ListGroups(db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/groups/{id}", handlers.GetGroup(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/groups/{id}/invite", handlers.InviteToGroup(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/groups/invites/{id}/accept", handlers.AcceptGroupInvite(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/groups/invites/{id}/refuse", handlers.RefuseGroupInvite(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/groups/{id}/join-request", handlers.RequestJoinGroup(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/groups/join-requests/{id}/accept", handlers.AcceptJoinRequest(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/groups/join-requests/{id}/refuse", handlers.RefuseJoinRequest(db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/groups/{id}/members", handlers.ListGroupMembers(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/groups/{id}/posts", handlers.CreateGroupPost(db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/groups/{id}/posts", handlers.ListGroupPosts(db))
-
-		api.With(appmw.RequireAuth(cfg, db)).Post("/groups/{id}/events", handlers.CreateEvent(db))
-		api.With(appmw.RequireAuth(cfg, db)).Get("/groups/{id}/events", handlers.ListEvents(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/events/{id}/respond", handlers.RespondEvent(db))
-
-		api.With(appmw.RequireAuth(cfg, db)).Get("/notifications", handlers.ListNotifications(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/notifications/{id}/read", handlers.MarkNotificationRead(db))
-		api.With(appmw.RequireAuth(cfg, db)).Post("/notifications/read-all", handlers.MarkAllNotificationsRead(db))
-
-		api.With(appmw.RequireAuth(cfg, db)).Get("/ws", ws.NewHandler(cfg, db))
+		api.Post("/auth/password/forgot", handlers.ForgotPassword(cfg, db, mail))
+		api.Post("/auth/password/reset", handlers.ResetPassword(cfg, db, passwords))
+		api.Post("/auth/verify-email", handlers.VerifyEmail(db))
+		api.Get("/auth/providers", handlers.OAuthProviders(providers))
+		api.Get("/auth/{provider}/start", handlers.OAuthStart(cfg, providers))
+		api.Get("/auth/{provider}/callback", handlers.OAuthCallback(cfg, db, providers))
+
+		api.Group(func(authed chi.Router) {
+			authed.Use(appmw.RequireAuth(cfg, db))
+
+			// Account security is only reachable with a session cookie,
+			// never with a personal access token.
+			authed.Group(func(account chi.Router) {
+				account.Use(appmw.RequireSession)
+
+				account.Post("/auth/verify-email/resend", handlers.ResendVerificationEmail(cfg, db, mail))
+
+				account.Get("/me/2fa", handlers.TwoFactorStatus(db))
+				account.Post("/me/2fa/setup", handlers.SetupTwoFactor(db))
+				account.Post("/me/2fa/confirm", handlers.ConfirmTwoFactor(db))
+				account.Post("/me/2fa/disable", handlers.DisableTwoFactor(db))
+				account.Post("/me/2fa/recovery-codes", handlers.RegenerateRecoveryCodes(db))
+
+				account.Get("/me/identities", handlers.ListIdentities(db))
+				account.Get("/me/identities/{provider}/link", handlers.LinkOAuthStart(cfg, providers))
+				account.Delete("/me/identities/{provider}", handlers.UnlinkIdentity(db))
+				account.Post("/me/password", handlers.ChangePassword(cfg, db, mail, passwords))
+				account.Post("/me/password/set", handlers.SetPassword(cfg, db, passwords))
+
+				account.Get("/me/export", handlers.ExportAccount(cfg, db))
+				account.Delete("/me", handlers.DeleteAccount(cfg, db, mail, passwords))
+
+				account.Get("/me/tokens", handlers.ListAccessTokens(db))
+				account.Post("/me/tokens", handlers.CreateAccessToken(db))
+				account.Delete("/me/tokens/{id}", handlers.RevokeAccessToken(db))
+
+				account.Get("/sessions", handlers.ListSessions(db))
+				account.Post("/sessions/revoke-others", handlers.RevokeOtherSessions(db))
+				account.Get("/sessions/{id}", handlers.GetSession(db))
+				account.Delete("/sessions/{id}", handlers.RevokeSession(cfg, db))
+			})
+
+			authed.Group(func(read chi.Router) {
+				read.Use(appmw.RequireScope(appmw.ScopeRead))
+
+				read.Get("/me", handlers.Me())
+				read.Get("/users/search", handlers.SearchUsers(db))
+				read.Get("/users/by-handle/{handle}", handlers.GetUserByHandle(db))
+				read.Get("/users/{id}", handlers.GetUser(db))
+				read.Get("/users/{id}/followers", handlers.ListFollowers(db))
+				read.Get("/users/{id}/following", handlers.ListFollowing(db))
+				read.Get("/suggestions/users", handlers.SuggestUsers(db))
+				read.Get("/follows/requests/incoming", handlers.ListIncomingFollowRequests(db))
+				read.Get("/follows/requests/outgoing", handlers.ListOutgoingFollowRequests(db))
+				read.Get("/blocks", handlers.ListBlocks(db))
+				read.Get("/mutes", handlers.ListMutes(db))
+				read.Get("/feed", handlers.Feed(db))
+				read.Get("/users/{id}/posts", handlers.UserPosts(db))
+				read.Get("/audience-lists", handlers.ListAudienceLists(db))
+				read.Get("/audience-lists/{id}", handlers.GetAudienceList(db))
+				read.Get("/posts/{id}/comments", handlers.ListComments(db))
+				read.Get("/posts/{id}/revisions", handlers.ListPostRevisions(db))
+				read.Get("/posts/{id}/reactions", handlers.ListPostReactions(db))
+				read.Get("/comments/{id}/reactions", handlers.ListCommentReactions(db))
+				read.Get("/messages/{id}/reactions", handlers.ListMessageReactions(db, repo.DirectMessage))
+				read.Get("/group-messages/{id}/reactions", handlers.ListMessageReactions(db, repo.GroupMessage))
+				read.Get("/tags", handlers.SearchTags(db))
+				read.Get("/tags/{tag}/posts", handlers.TagPosts(db))
+				read.Get("/groups", handlers.ListGroups(db))
+				read.Get("/groups/{id}", handlers.GetGroup(db))
+				read.Get("/groups/{id}/members", handlers.ListGroupMembers(db))
+				read.Get("/groups/{id}/posts", handlers.ListGroupPosts(db))
+				read.Get("/groups/{id}/events", handlers.ListEvents(db))
+				read.Get("/notifications", handlers.ListNotifications(db))
+				read.Get("/presence", handlers.GetPresence(db, hub))
+			})
+
+			authed.Group(func(write chi.Router) {
+				write.Use(appmw.RequireScope(appmw.ScopeWrite))
+
+				write.Patch("/users/me", handlers.UpdateMe(db))
+				write.Patch("/users/me/handle", handlers.ChangeHandle(cfg, db))
+				write.Patch("/users/me/gaming", handlers.UpdateGaming(db))
+				write.Patch("/users/me/presence", handlers.UpdatePresence(db, hub))
+
+				write.Post("/follows/request", handlers.FollowRequest(db))
+				write.Post("/follows/request/{id}/accept", handlers.AcceptFollow(db))
+				write.Post("/follows/request/{id}/refuse", handlers.RefuseFollow(db))
+				write.Delete("/follows/request/{id}", handlers.CancelFollowRequest(db))
+				write.Delete("/follows/{id}", handlers.Unfollow(db))
+				write.Delete("/followers/{id}", handlers.RemoveFollower(db))
+
+				write.Post("/blocks/{id}", handlers.BlockUser(db))
+				write.Delete("/blocks/{id}", handlers.UnblockUser(db))
+				write.Post("/mutes/{id}", handlers.MuteUser(db))
+				write.Delete("/mutes/{id}", handlers.UnmuteUser(db))
+
+				write.With(appmw.RequireVerifiedEmail(cfg, "post")).Post("/posts", handlers.CreatePost(db))
+				write.With(appmw.RequireVerifiedEmail(cfg, "post")).Patch("/posts/{id}", handlers.UpdatePost(db))
+				write.Delete("/posts/{id}", handlers.DeletePost(db))
+				write.Post("/posts/{id}/reactions", handlers.ReactToPost(cfg, db))
+				write.Delete("/posts/{id}/reactions", handlers.RemovePostReaction(db))
+				write.Post("/comments/{id}/reactions", handlers.ReactToComment(db))
+				write.Delete("/comments/{id}/reactions", handlers.RemoveCommentReaction(db))
+				write.Post("/messages/{id}/reactions", handlers.ReactToMessage(db, hub, repo.DirectMessage))
+				write.Delete("/messages/{id}/reactions", handlers.RemoveMessageReaction(db, hub, repo.DirectMessage))
+				write.Post("/group-messages/{id}/reactions", handlers.ReactToMessage(db, hub, repo.GroupMessage))
+				write.Delete("/group-messages/{id}/reactions", handlers.RemoveMessageReaction(db, hub, repo.GroupMessage))
+				write.With(appmw.RequireVerifiedEmail(cfg, "comment")).Post("/posts/{id}/comments", handlers.CreateComment(db))
+
+				write.Post("/media/upload", handlers.UploadMedia(cfg, db))
+
+				write.Post("/audience-lists", handlers.CreateAudienceList(db))
+				write.Patch("/audience-lists/{id}", handlers.UpdateAudienceList(db))
+				write.Delete("/audience-lists/{id}", handlers.DeleteAudienceList(db))
+
+				write.With(appmw.RequireVerifiedEmail(cfg, "group")).Post("/groups", handlers.CreateGroup(db))
+				write.Post("/groups/{id}/invite", handlers.InviteToGroup(db))
+				write.Post("/groups/invites/{id}/accept", handlers.AcceptGroupInvite(db))
+				write.Post("/groups/invites/{id}/refuse", handlers.RefuseGroupInvite(db))
+				write.Post("/groups/{id}/join-request", handlers.RequestJoinGroup(db))
+				write.Post("/groups/join-requests/{id}/accept", handlers.AcceptJoinRequest(db))
+				write.Post("/groups/join-requests/{id}/refuse", handlers.RefuseJoinRequest(db))
+				write.With(appmw.RequireVerifiedEmail(cfg, "post")).Post("/groups/{id}/posts", handlers.CreateGroupPost(db))
+
+				write.Post("/groups/{id}/events", handlers.CreateEvent(db))
+				write.Post("/events/{id}/respond", handlers.RespondEvent(db))
+
+				write.Post("/notifications/{id}/read", handlers.MarkNotificationRead(db))
+				write.Post("/notifications/read-all", handlers.MarkAllNotificationsRead(db))
+			})
+
+			authed.With(appmw.RequireScope(appmw.ScopeChat)).Get("/ws", ws.NewHandler(cfg, db, hub))
+		})
 	})
 
 	r.Handle("/media/*", handlers.Media(cfg))
@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+
+	"backend/internal/config"
+	"backend/internal/repo"
+)
+
+// PurgeDeletedAccounts removes accounts whose deletion grace period has
+// run out, along with the files they uploaded.
+func PurgeDeletedAccounts(ctx context.Context, cfg config.Config, db *sql.DB) {
+	ids, err := repo.ListAccountsDueForDeletion(ctx, db)
+	if err != nil {
+		log.Printf("account purge: %v", err)
+		return
+	}
+	for _, id := range ids {
+		paths, err := repo.DeleteAccount(ctx, db, id)
+		if err != nil {
+			log.Printf("account purge: user %d: %v", id, err)
+			continue
+		}
+		for _, path := range paths {
+			if err := os.Remove(cfg.MediaFilePath(path)); err != nil && !os.IsNotExist(err) {
+				log.Printf("account purge: user %d: %v", id, err)
+			}
+		}
+		log.Printf("account purge: removed user %d", id)
+	}
+}
+
+// ReportEmailConflicts logs accounts left with clashing emails when emails
+// were normalized, so an operator can resolve them. Conflicts resolved since
+// the last start are forgotten first.
+func ReportEmailConflicts(ctx context.Context, db *sql.DB) {
+	resolved, err := repo.PruneEmailConflicts(ctx, db)
+	if err != nil {
+		log.Printf("email conflicts: %v", err)
+		return
+	}
+	if resolved > 0 {
+		log.Printf("email conflicts: %d resolved", resolved)
+	}
+	conflicts, err := repo.ListEmailConflicts(ctx, db)
+	if err != nil {
+		log.Printf("email conflicts: %v", err)
+		return
+	}
+	for _, conflict := range conflicts {
+		log.Printf("email conflict: user %d has %q, which normalizes to %q like another account", conflict.UserID, conflict.Email, conflict.NormalizedEmail)
+	}
+}
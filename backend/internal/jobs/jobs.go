@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"backend/internal/config"
+)
+
+// Start launches the background maintenance loops. They run until ctx is
+// cancelled.
+func Start(ctx context.Context, cfg config.Config, db *sql.DB) {
+	go every(ctx, cfg.SessionSweepInterval, func(ctx context.Context) {
+		sweepSessions(ctx, db)
+	})
+	go every(ctx, cfg.SessionSweepInterval, func(ctx context.Context) {
+		PurgeDeletedAccounts(ctx, cfg, db)
+	})
+	go every(ctx, cfg.SuggestionsInterval, func(ctx context.Context) {
+		RefreshSuggestions(ctx, cfg, db)
+	})
+}
+
+func every(ctx context.Context, interval time.Duration, fn func(context.Context)) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	fn(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn(ctx)
+		}
+	}
+}
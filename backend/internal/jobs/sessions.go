@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"backend/internal/repo"
+)
+
+// loginThrottleRetention is how long a quiet login throttle entry is kept,
+// which is also how long lockout backoff is remembered.
+const loginThrottleRetention = 24 * time.Hour
+
+func sweepSessions(ctx context.Context, db *sql.DB) {
+	deleted, err := repo.DeleteExpiredSessions(ctx, db)
+	if err != nil {
+		log.Printf("session sweep: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("session sweep: removed %d expired sessions", deleted)
+	}
+	if _, err := repo.DeleteExpiredLoginChallenges(ctx, db); err != nil {
+		log.Printf("session sweep: login challenges: %v", err)
+	}
+	if _, err := repo.DeleteExpiredPasswordResets(ctx, db); err != nil {
+		log.Printf("session sweep: password resets: %v", err)
+	}
+	if _, err := repo.DeleteExpiredEmailVerifications(ctx, db); err != nil {
+		log.Printf("session sweep: email verifications: %v", err)
+	}
+	if _, err := repo.DeleteExpiredAccessTokens(ctx, db); err != nil {
+		log.Printf("session sweep: access tokens: %v", err)
+	}
+	if _, err := repo.DeleteStaleLoginThrottles(ctx, db, loginThrottleRetention); err != nil {
+		log.Printf("session sweep: login throttle: %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"backend/internal/config"
+	"backend/internal/repo"
+)
+
+// RefreshSuggestions precomputes follow suggestions so the endpoint only
+// reads them.
+func RefreshSuggestions(ctx context.Context, cfg config.Config, db *sql.DB) {
+	stored, err := repo.RefreshSuggestions(ctx, db, cfg.SuggestionsPerUser)
+	if err != nil {
+		log.Printf("suggestions: %v", err)
+		return
+	}
+	log.Printf("suggestions: stored %d", stored)
+}
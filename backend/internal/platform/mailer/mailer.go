@@ -0,0 +1,127 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"backend/internal/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional email. Handlers only depend on this
+// interface so local setups and tests can avoid a real SMTP server.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// CheckDriver reports a MAIL_DRIVER that would not deliver mail outside
+// dev and test. There only "smtp" and "file" are accepted, so a missing or
+// mistyped setting stops the server at startup instead of quietly turning
+// reset and verification emails into log lines.
+func CheckDriver(cfg config.Config) error {
+	switch strings.ToLower(strings.TrimSpace(cfg.Env)) {
+	case "dev", "test":
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.MailDriver)) {
+	case "smtp", "file":
+		return nil
+	}
+	return fmt.Errorf("MAIL_DRIVER %q does not deliver mail; set it to smtp or file when APP_ENV is %q", cfg.MailDriver, cfg.Env)
+}
+
+// New picks the implementation named by cfg.MailDriver: "smtp", "file" or
+// "log". In dev and test, unknown drivers fall back to "log" so a typo never
+// drops mail silently; elsewhere CheckDriver rejects them at startup.
+func New(cfg config.Config) Mailer {
+	switch strings.ToLower(strings.TrimSpace(cfg.MailDriver)) {
+	case "smtp":
+		return SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		}
+	case "file":
+		return FileMailer{Dir: cfg.MailDir, From: cfg.MailFrom}
+	case "", "log":
+		return LogMailer{From: cfg.MailFrom}
+	default:
+		log.Printf("mailer: unknown driver %q, logging messages instead", cfg.MailDriver)
+		return LogMailer{From: cfg.MailFrom}
+	}
+}
+
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	addr := net.JoinHostPort(m.Host, m.Port)
+	return smtp.SendMail(addr, auth, m.From, []string{msg.To}, format(m.From, msg))
+}
+
+// FileMailer writes each message to its own .eml file in Dir.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitize(msg.To))
+	return os.WriteFile(filepath.Join(m.Dir, name), format(m.From, msg), 0o600)
+}
+
+type LogMailer struct {
+	From string
+}
+
+func (m LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func sanitize(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, value)
+}
@@ -0,0 +1,292 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"backend/internal/config"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Identity is what a provider tells us about the signed-in account, after
+// claim mapping.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+	Picture       string
+}
+
+// Claims names the userinfo fields that hold each Identity value.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified string
+	Name          string
+	GivenName     string
+	FamilyName    string
+	Picture       string
+}
+
+var oidcClaims = Claims{
+	Subject:       "sub",
+	Email:         "email",
+	EmailVerified: "email_verified",
+	Name:          "name",
+	GivenName:     "given_name",
+	FamilyName:    "family_name",
+	Picture:       "picture",
+}
+
+type Provider struct {
+	Name        string
+	UserInfoURL string
+	Claims      Claims
+	conf        *oauth2.Config
+	authParams  map[string]string
+}
+
+type builtin struct {
+	endpoint    oauth2.Endpoint
+	userInfoURL string
+	scopes      []string
+	claims      Claims
+	authParams  map[string]string
+}
+
+var builtins = map[string]builtin{
+	"google": {
+		endpoint:    google.Endpoint,
+		userInfoURL: "https://www.googleapis.com/oauth2/v3/userinfo",
+		scopes:      []string{"openid", "email", "profile"},
+		claims:      oidcClaims,
+	},
+	"discord": {
+		endpoint: oauth2.Endpoint{
+			AuthURL:  "https://discord.com/oauth2/authorize",
+			TokenURL: "https://discord.com/api/oauth2/token",
+		},
+		userInfoURL: "https://discord.com/api/users/@me",
+		scopes:      []string{"identify", "email"},
+		claims: Claims{
+			Subject:       "id",
+			Email:         "email",
+			EmailVerified: "verified",
+			Name:          "global_name",
+		},
+	},
+	"twitch": {
+		endpoint: oauth2.Endpoint{
+			AuthURL:   "https://id.twitch.tv/oauth2/authorize",
+			TokenURL:  "https://id.twitch.tv/oauth2/token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+		userInfoURL: "https://id.twitch.tv/oauth2/userinfo",
+		scopes:      []string{"openid", "user:read:email"},
+		claims: Claims{
+			Subject:       "sub",
+			Email:         "email",
+			EmailVerified: "email_verified",
+			Name:          "preferred_username",
+			Picture:       "picture",
+		},
+		// Twitch only returns these claims from userinfo when asked for them.
+		authParams: map[string]string{
+			"claims": `{"userinfo":{"email":null,"email_verified":null,"preferred_username":null,"picture":null}}`,
+		},
+	},
+}
+
+// Registry holds the providers that are configured well enough to use.
+type Registry struct {
+	providers map[string]*Provider
+}
+
+// NewRegistry builds providers from configuration, filling gaps from the
+// built-in defaults. Incomplete providers are logged and left out.
+func NewRegistry(list []config.OAuthProvider) *Registry {
+	registry := &Registry{providers: map[string]*Provider{}}
+	for _, item := range list {
+		provider, err := newProvider(item)
+		if err != nil {
+			log.Printf("oauth: provider %q disabled: %v", item.Name, err)
+			continue
+		}
+		registry.providers[provider.Name] = provider
+	}
+	return registry
+}
+
+func (r *Registry) Get(name string) (*Provider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// Names lists the configured providers in a stable order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newProvider(item config.OAuthProvider) (*Provider, error) {
+	name := strings.ToLower(strings.TrimSpace(item.Name))
+	if name == "" {
+		return nil, errors.New("missing name")
+	}
+	defaults, known := builtins[name]
+	if !known {
+		defaults.claims = oidcClaims
+		defaults.scopes = []string{"openid", "email", "profile"}
+	}
+
+	endpoint := defaults.endpoint
+	if item.AuthURL != "" {
+		endpoint.AuthURL = item.AuthURL
+	}
+	if item.TokenURL != "" {
+		endpoint.TokenURL = item.TokenURL
+	}
+	userInfoURL := defaults.userInfoURL
+	if item.UserInfoURL != "" {
+		userInfoURL = item.UserInfoURL
+	}
+	scopes := defaults.scopes
+	if len(item.Scopes) > 0 {
+		scopes = item.Scopes
+	}
+
+	if item.ClientID == "" || item.ClientSecret == "" || item.RedirectURL == "" {
+		return nil, errors.New("client id, secret and redirect url are required")
+	}
+	if endpoint.AuthURL == "" || endpoint.TokenURL == "" || userInfoURL == "" {
+		return nil, errors.New("auth, token and userinfo urls are required")
+	}
+
+	return &Provider{
+		Name:        name,
+		UserInfoURL: userInfoURL,
+		Claims:      overrideClaims(defaults.claims, item.Claims),
+		authParams:  defaults.authParams,
+		conf: &oauth2.Config{
+			ClientID:     item.ClientID,
+			ClientSecret: item.ClientSecret,
+			RedirectURL:  item.RedirectURL,
+			Endpoint:     endpoint,
+			Scopes:       scopes,
+		},
+	}, nil
+}
+
+func overrideClaims(claims Claims, overrides map[string]string) Claims {
+	for field, claim := range overrides {
+		switch field {
+		case "subject":
+			claims.Subject = claim
+		case "email":
+			claims.Email = claim
+		case "email_verified":
+			claims.EmailVerified = claim
+		case "name":
+			claims.Name = claim
+		case "given_name":
+			claims.GivenName = claim
+		case "family_name":
+			claims.FamilyName = claim
+		case "picture":
+			claims.Picture = claim
+		}
+	}
+	return claims
+}
+
+func (p *Provider) AuthCodeURL(state string) string {
+	opts := []oauth2.AuthCodeOption{oauth2.AccessTypeOnline}
+	for key, value := range p.authParams {
+		opts = append(opts, oauth2.SetAuthURLParam(key, value))
+	}
+	return p.conf.AuthCodeURL(state, opts...)
+}
+
+// Exchange trades the authorization code for a token and loads the user's
+// identity from the userinfo endpoint.
+func (p *Provider) Exchange(ctx context.Context, code string) (Identity, error) {
+	token, err := p.conf.Exchange(ctx, code)
+	if err != nil {
+		return Identity{}, errors.New("exchange failed")
+	}
+
+	resp, err := p.conf.Client(ctx, token).Get(p.UserInfoURL)
+	if err != nil {
+		return Identity{}, errors.New("userinfo failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Identity{}, errors.New("userinfo failed")
+	}
+	var raw map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return Identity{}, errors.New("userinfo invalid")
+	}
+
+	identity := Identity{
+		Subject:       claimString(raw, p.Claims.Subject),
+		Email:         claimString(raw, p.Claims.Email),
+		EmailVerified: claimBool(raw, p.Claims.EmailVerified),
+		Name:          claimString(raw, p.Claims.Name),
+		GivenName:     claimString(raw, p.Claims.GivenName),
+		FamilyName:    claimString(raw, p.Claims.FamilyName),
+		Picture:       claimString(raw, p.Claims.Picture),
+	}
+	if identity.Subject == "" || identity.Email == "" {
+		return Identity{}, errors.New("userinfo incomplete")
+	}
+	return identity, nil
+}
+
+func claimString(raw map[string]any, claim string) string {
+	if claim == "" {
+		return ""
+	}
+	switch value := raw[claim].(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// claimBool accepts real booleans as well as "true" strings, which some
+// providers send.
+func claimBool(raw map[string]any, claim string) bool {
+	if claim == "" {
+		return false
+	}
+	switch value := raw[claim].(type) {
+	case bool:
+		return value
+	case string:
+		parsed, _ := strconv.ParseBool(value)
+		return parsed
+	default:
+		return false
+	}
+}
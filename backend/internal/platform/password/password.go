@@ -0,0 +1,144 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend/internal/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// Argon2Params are the argon2id cost settings. Memory is in KiB.
+type Argon2Params struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// Hasher hashes new passwords with the configured algorithm and verifies
+// hashes made by any supported one, so the algorithm or its cost can be
+// raised without invalidating existing passwords.
+type Hasher struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+func New(cfg config.Config) Hasher {
+	hasher := Hasher{
+		Algorithm:  strings.ToLower(strings.TrimSpace(cfg.PasswordHash)),
+		BcryptCost: cfg.BcryptCost,
+		Argon2: Argon2Params{
+			Memory:  uint32(cfg.Argon2MemoryKiB),
+			Time:    uint32(cfg.Argon2Time),
+			Threads: uint8(cfg.Argon2Threads),
+		},
+	}
+	if hasher.Algorithm != AlgorithmArgon2id {
+		hasher.Algorithm = AlgorithmBcrypt
+	}
+	if hasher.BcryptCost < bcrypt.MinCost || hasher.BcryptCost > bcrypt.MaxCost {
+		hasher.BcryptCost = bcrypt.DefaultCost
+	}
+	if hasher.Argon2.Memory == 0 {
+		hasher.Argon2.Memory = 64 * 1024
+	}
+	if hasher.Argon2.Time == 0 {
+		hasher.Argon2.Time = 3
+	}
+	if hasher.Argon2.Threads == 0 {
+		hasher.Argon2.Threads = 2
+	}
+	return hasher
+}
+
+func (h Hasher) Hash(plain string) (string, error) {
+	if h.Algorithm == AlgorithmArgon2id {
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(plain), salt, h.Argon2.Time, h.Argon2.Memory, h.Argon2.Threads, argon2KeyLength)
+		return encodeArgon2(h.Argon2, salt, key), nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(plain), h.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify reports whether plain matches hash and, if it does, whether the
+// hash should be replaced because it was made with another algorithm or
+// weaker settings than the current ones.
+func (h Hasher) Verify(hash, plain string) (ok bool, rehash bool) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return false, false
+		}
+		candidate := argon2.IDKey([]byte(plain), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(candidate, key) != 1 {
+			return false, false
+		}
+		return true, h.Algorithm != AlgorithmArgon2id || params != h.Argon2
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) != nil {
+		return false, false
+	}
+	if h.Algorithm != AlgorithmBcrypt {
+		return true, true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, err == nil && cost < h.BcryptCost
+}
+
+// encodeArgon2 uses the PHC string format shared by most argon2 libraries.
+func encodeArgon2(params Argon2Params, salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Time, params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+var errMalformedHash = errors.New("malformed argon2id hash")
+
+func decodeArgon2(hash string) (Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	var params Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,75 @@
+package password
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"backend/internal/config"
+)
+
+// Policy is the set of rules a new password has to satisfy. MinClasses
+// counts how many of lowercase, uppercase, digits and symbols must appear.
+type Policy struct {
+	MinLength  int
+	MaxLength  int
+	MinClasses int
+}
+
+// bcryptMaxBytes is the most bcrypt will hash; longer input is rejected
+// rather than silently truncated.
+const bcryptMaxBytes = 72
+
+func NewPolicy(cfg config.Config) Policy {
+	policy := Policy{
+		MinLength:  cfg.PasswordMinLength,
+		MaxLength:  cfg.PasswordMaxLength,
+		MinClasses: cfg.PasswordMinClasses,
+	}
+	if policy.MinLength <= 0 {
+		policy.MinLength = 8
+	}
+	if policy.MaxLength <= 0 || policy.MaxLength > bcryptMaxBytes {
+		policy.MaxLength = bcryptMaxBytes
+	}
+	if policy.MinClasses > 4 {
+		policy.MinClasses = 4
+	}
+	return policy
+}
+
+// Validate returns an error with a message fit to show the user when plain
+// breaks the policy. The account's email is never accepted as a password.
+func (p Policy) Validate(plain, email string) error {
+	if len([]rune(plain)) < p.MinLength {
+		return errors.New("password must be at least " + strconv.Itoa(p.MinLength) + " characters")
+	}
+	if len(plain) > p.MaxLength {
+		return errors.New("password must be at most " + strconv.Itoa(p.MaxLength) + " bytes")
+	}
+	if classes(plain) < p.MinClasses {
+		return errors.New("password must mix at least " + strconv.Itoa(p.MinClasses) + " of lowercase, uppercase, digits and symbols")
+	}
+	if email != "" && strings.EqualFold(strings.TrimSpace(plain), strings.TrimSpace(email)) {
+		return errors.New("password must not be your email")
+	}
+	return nil
+}
+
+func classes(plain string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range plain {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// accessTokenPrefix marks personal access tokens so they are recognisable in
+// logs and secret scanners.
+const accessTokenPrefix = "gnp_"
+
+type AccessToken struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expires_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	LastUsedIP *string  `json:"last_used_ip"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// CreateAccessToken stores a new token for userID and returns the plain
+// value, which is never retrievable again.
+func CreateAccessToken(ctx context.Context, db *sql.DB, userID int64, name string, scopes []string, ttl time.Duration) (AccessToken, string, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return AccessToken{}, "", err
+	}
+	token := accessTokenPrefix + secret
+	prefix := token[:len(accessTokenPrefix)+8]
+	expiresAt := sqliteTime(time.Now().Add(ttl))
+
+	result, err := db.ExecContext(ctx, `INSERT INTO access_tokens (user_id, name, token_hash, prefix, scopes, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, userID, name, hashToken(token), prefix, strings.Join(scopes, " "), expiresAt)
+	if err != nil {
+		return AccessToken{}, "", err
+	}
+	id, _ := result.LastInsertId()
+	created, _, err := GetAccessToken(ctx, db, userID, id)
+	if err != nil {
+		return AccessToken{}, "", err
+	}
+	return created, token, nil
+}
+
+func ListAccessTokens(ctx context.Context, db *sql.DB, userID int64) ([]AccessToken, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, name, prefix, scopes, expires_at, last_used_at, last_used_ip, created_at
+		FROM access_tokens WHERE user_id = ? AND expires_at > CURRENT_TIMESTAMP ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []AccessToken{}
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, token)
+	}
+	return list, rows.Err()
+}
+
+func GetAccessToken(ctx context.Context, db *sql.DB, userID, tokenID int64) (AccessToken, bool, error) {
+	row := db.QueryRowContext(ctx, `SELECT id, name, prefix, scopes, expires_at, last_used_at, last_used_ip, created_at
+		FROM access_tokens WHERE user_id = ? AND id = ?`, userID, tokenID)
+	token, err := scanAccessToken(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return AccessToken{}, false, nil
+		}
+		return AccessToken{}, false, err
+	}
+	return token, true, nil
+}
+
+func DeleteAccessToken(ctx context.Context, db *sql.DB, userID, tokenID int64) (bool, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM access_tokens WHERE user_id = ? AND id = ?", userID, tokenID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// LookupAccessToken resolves a bearer token to its owner and scopes. Unknown
+// and expired tokens report found=false.
+func LookupAccessToken(ctx context.Context, db *sql.DB, token string) (int64, int64, []string, bool, error) {
+	if !strings.HasPrefix(token, accessTokenPrefix) {
+		return 0, 0, nil, false, nil
+	}
+	var tokenID, userID int64
+	var scopes string
+	row := db.QueryRowContext(ctx, `SELECT id, user_id, scopes FROM access_tokens
+		WHERE token_hash = ? AND expires_at > CURRENT_TIMESTAMP`, hashToken(token))
+	if err := row.Scan(&tokenID, &userID, &scopes); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, nil, false, nil
+		}
+		return 0, 0, nil, false, err
+	}
+	return tokenID, userID, strings.Fields(scopes), true, nil
+}
+
+// TouchAccessToken records token use, at most once a minute per token.
+func TouchAccessToken(ctx context.Context, db *sql.DB, tokenID int64, ip string) error {
+	_, err := db.ExecContext(ctx, `UPDATE access_tokens SET last_used_at = CURRENT_TIMESTAMP, last_used_ip = ?
+		WHERE id = ? AND (last_used_at IS NULL OR last_used_at <= datetime('now', '-1 minute'))`, nullableString(&ip), tokenID)
+	return err
+}
+
+func DeleteExpiredAccessTokens(ctx context.Context, db *sql.DB) (int64, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM access_tokens WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
+
+func scanAccessToken(row rowScanner) (AccessToken, error) {
+	var token AccessToken
+	var scopes string
+	var lastUsedAt sql.NullString
+	var lastUsedIP sql.NullString
+	if err := row.Scan(&token.ID, &token.Name, &token.Prefix, &scopes, &token.ExpiresAt, &lastUsedAt, &lastUsedIP, &token.CreatedAt); err != nil {
+		return AccessToken{}, err
+	}
+	token.Scopes = strings.Fields(scopes)
+	token.LastUsedAt = nullableStringPtr(lastUsedAt)
+	token.LastUsedIP = nullableStringPtr(lastUsedIP)
+	return token, nil
+}
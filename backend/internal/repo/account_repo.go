@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ScheduleAccountDeletion marks the account for removal at the given time
+// and signs it out everywhere. Signing back in before then cancels it.
+func ScheduleAccountDeletion(ctx context.Context, db *sql.DB, userID int64, at time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET deletion_scheduled_at = ? WHERE id = ?", sqliteTime(at), userID); err != nil {
+		return err
+	}
+	for _, query := range []string{
+		"DELETE FROM sessions WHERE user_id = ?",
+		"DELETE FROM access_tokens WHERE user_id = ?",
+		"DELETE FROM login_challenges WHERE user_id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CancelAccountDeletion clears a pending deletion. It reports whether one
+// was pending.
+func CancelAccountDeletion(ctx context.Context, db *sql.DB, userID int64) (bool, error) {
+	result, err := db.ExecContext(ctx, "UPDATE users SET deletion_scheduled_at = NULL WHERE id = ? AND deletion_scheduled_at IS NOT NULL", userID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func ListAccountsDueForDeletion(ctx context.Context, db *sql.DB) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id FROM users WHERE deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeleteAccount removes the user and everything that cascades from it, and
+// returns the paths of the media they uploaded so the caller can remove the
+// files once the rows are gone. Groups the user created are handed to their
+// longest-standing member rather than deleted with everyone's posts in them.
+func DeleteAccount(ctx context.Context, db *sql.DB, userID int64) ([]string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT path FROM media WHERE owner_user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE groups SET creator_id = (
+			SELECT user_id FROM group_members
+			WHERE group_members.group_id = groups.id AND group_members.user_id <> ?
+			ORDER BY created_at ASC, user_id ASC LIMIT 1)
+		WHERE creator_id = ? AND EXISTS (
+			SELECT 1 FROM group_members WHERE group_members.group_id = groups.id AND group_members.user_id <> ?)`,
+		userID, userID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE group_members SET role = 'creator'
+		WHERE EXISTS (SELECT 1 FROM groups WHERE groups.id = group_members.group_id AND groups.creator_id = group_members.user_id)
+			AND role <> 'creator'`); err != nil {
+		return nil, err
+	}
+	// Notifications sent to others on the user's behalf would point at an
+	// account that no longer exists.
+	if _, err := tx.ExecContext(ctx, "DELETE FROM notifications WHERE json_valid(payload_json) AND json_extract(payload_json, '$.from_user_id') = ?", userID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", userID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// EmailConflict is an account whose email only differs by case from
+// another one's, found when emails were normalized.
+type EmailConflict struct {
+	UserID          int64
+	Email           string
+	NormalizedEmail string
+}
+
+// ListEmailConflicts lists the conflicts still open, with each account's
+// current email.
+func ListEmailConflicts(ctx context.Context, db *sql.DB) ([]EmailConflict, error) {
+	rows, err := db.QueryContext(ctx, `SELECT users.id, users.email, lower(trim(users.email))
+		FROM email_conflicts
+		JOIN users ON users.id = email_conflicts.user_id
+		ORDER BY lower(trim(users.email)), users.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var conflicts []EmailConflict
+	for rows.Next() {
+		var conflict EmailConflict
+		if err := rows.Scan(&conflict.UserID, &conflict.Email, &conflict.NormalizedEmail); err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts, rows.Err()
+}
+
+// PruneEmailConflicts forgets the conflicts an operator resolved: accounts
+// whose email no longer normalizes like another account's. Deleted accounts
+// drop out on their own.
+func PruneEmailConflicts(ctx context.Context, db *sql.DB) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM email_conflicts WHERE NOT EXISTS (
+		SELECT 1 FROM users
+		JOIN users AS other ON lower(trim(other.email)) = lower(trim(users.email)) AND other.id != users.id
+		WHERE users.id = email_conflicts.user_id)`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AudienceList is a named group of followers a private post can target.
+// Posts follow the list's current members.
+type AudienceList struct {
+	ID        int64   `json:"id"`
+	Name      string  `json:"name"`
+	MemberIDs []int64 `json:"member_ids"`
+	CreatedAt string  `json:"created_at"`
+}
+
+// CreateAudienceList stores a list for ownerID. taken is set when the owner
+// already has a list with that name, ignoring case.
+func CreateAudienceList(ctx context.Context, db *sql.DB, ownerID int64, name string, memberIDs []int64) (AudienceList, bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return AudienceList{}, false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO audience_lists (owner_id, name) VALUES (?, ?)", ownerID, name)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return AudienceList{}, true, nil
+		}
+		return AudienceList{}, false, err
+	}
+	id, _ := result.LastInsertId()
+	if err := setAudienceListMembers(ctx, tx, id, memberIDs); err != nil {
+		return AudienceList{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return AudienceList{}, false, err
+	}
+	list, _, err := GetAudienceList(ctx, db, ownerID, id)
+	return list, false, err
+}
+
+// UpdateAudienceList renames the list when name is set and replaces its
+// members when memberIDs is not nil. found is false when ownerID has no such
+// list; taken as for CreateAudienceList.
+func UpdateAudienceList(ctx context.Context, db *sql.DB, ownerID, listID int64, name *string, memberIDs []int64) (list AudienceList, found, taken bool, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return AudienceList{}, false, false, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM audience_lists WHERE id = ? AND owner_id = ?)", listID, ownerID).Scan(&exists); err != nil || !exists {
+		return AudienceList{}, false, false, err
+	}
+	if name != nil {
+		if _, err := tx.ExecContext(ctx, "UPDATE audience_lists SET name = ? WHERE id = ?", *name, listID); err != nil {
+			if isUniqueViolation(err) {
+				return AudienceList{}, true, true, nil
+			}
+			return AudienceList{}, false, false, err
+		}
+	}
+	if memberIDs != nil {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM audience_list_members WHERE list_id = ?", listID); err != nil {
+			return AudienceList{}, false, false, err
+		}
+		if err := setAudienceListMembers(ctx, tx, listID, memberIDs); err != nil {
+			return AudienceList{}, false, false, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return AudienceList{}, false, false, err
+	}
+	list, found, err = GetAudienceList(ctx, db, ownerID, listID)
+	return list, found, false, err
+}
+
+func setAudienceListMembers(ctx context.Context, tx *sql.Tx, listID int64, memberIDs []int64) error {
+	for _, id := range memberIDs {
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO audience_list_members (list_id, user_id) VALUES (?, ?)", listID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func DeleteAudienceList(ctx context.Context, db *sql.DB, ownerID, listID int64) (bool, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM audience_lists WHERE id = ? AND owner_id = ?", listID, ownerID)
+	if err != nil {
+		return false, err
+	}
+	deleted, _ := result.RowsAffected()
+	return deleted > 0, nil
+}
+
+func GetAudienceList(ctx context.Context, db *sql.DB, ownerID, listID int64) (AudienceList, bool, error) {
+	lists, err := queryAudienceLists(ctx, db, "audience_lists.owner_id = ? AND audience_lists.id = ?", ownerID, listID)
+	if err != nil || len(lists) == 0 {
+		return AudienceList{}, false, err
+	}
+	return lists[0], true, nil
+}
+
+func ListAudienceLists(ctx context.Context, db *sql.DB, ownerID int64) ([]AudienceList, error) {
+	return queryAudienceLists(ctx, db, "audience_lists.owner_id = ?", ownerID)
+}
+
+func queryAudienceLists(ctx context.Context, db *sql.DB, where string, args ...any) ([]AudienceList, error) {
+	rows, err := db.QueryContext(ctx, `SELECT audience_lists.id, audience_lists.name, audience_lists.created_at,
+			COALESCE(group_concat(audience_list_members.user_id), '')
+		FROM audience_lists
+		LEFT JOIN audience_list_members ON audience_list_members.list_id = audience_lists.id
+		WHERE `+where+`
+		GROUP BY audience_lists.id
+		ORDER BY lower(audience_lists.name), audience_lists.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []AudienceList{}
+	for rows.Next() {
+		var list AudienceList
+		var members string
+		if err := rows.Scan(&list.ID, &list.Name, &list.CreatedAt, &members); err != nil {
+			return nil, err
+		}
+		list.MemberIDs = []int64{}
+		for _, member := range strings.Split(members, ",") {
+			if id, err := strconv.ParseInt(member, 10, 64); err == nil {
+				list.MemberIDs = append(list.MemberIDs, id)
+			}
+		}
+		sort.Slice(list.MemberIDs, func(i, j int) bool { return list.MemberIDs[i] < list.MemberIDs[j] })
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+// IsAudienceListOwner reports whether listID exists and belongs to ownerID.
+func IsAudienceListOwner(ctx context.Context, db *sql.DB, listID, ownerID int64) (bool, error) {
+	var owned bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM audience_lists WHERE id = ? AND owner_id = ?)", listID, ownerID).Scan(&owned)
+	return owned, err
+}
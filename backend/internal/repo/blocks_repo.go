@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+)
+
+// blockedWith is a condition that holds when the user in column and the
+// viewer have blocked each other in either direction. It takes the viewer
+// ID twice.
+func blockedWith(column string) string {
+	return `EXISTS (SELECT 1 FROM user_blocks
+		WHERE (user_blocks.blocker_id = ? AND user_blocks.blocked_id = ` + column + `)
+			OR (user_blocks.blocker_id = ` + column + ` AND user_blocks.blocked_id = ?))`
+}
+
+// mutedBy is a condition that holds when the viewer muted the user in
+// column. It takes the viewer ID once.
+func mutedBy(column string) string {
+	return `EXISTS (SELECT 1 FROM user_mutes WHERE user_mutes.muter_id = ? AND user_mutes.muted_id = ` + column + `)`
+}
+
+// BlockUser blocks blockedID for blockerID. Follows, pending follow
+// requests and audience list memberships between the two are removed in
+// both directions.
+func BlockUser(ctx context.Context, db *sql.DB, blockerID, blockedID int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO user_blocks (blocker_id, blocked_id) VALUES (?, ?)", blockerID, blockedID); err != nil {
+		return err
+	}
+	for _, query := range []string{
+		"DELETE FROM follows WHERE (follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)",
+		"DELETE FROM follow_requests WHERE status = 'pending' AND ((from_user_id = ? AND to_user_id = ?) OR (from_user_id = ? AND to_user_id = ?))",
+		`DELETE FROM audience_list_members WHERE (user_id = ? AND list_id IN (SELECT id FROM audience_lists WHERE owner_id = ?))
+			OR (user_id = ? AND list_id IN (SELECT id FROM audience_lists WHERE owner_id = ?))`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, blockerID, blockedID, blockedID, blockerID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func UnblockUser(ctx context.Context, db *sql.DB, blockerID, blockedID int64) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?", blockerID, blockedID)
+	return err
+}
+
+// IsBlocked reports whether either user has blocked the other.
+func IsBlocked(ctx context.Context, db *sql.DB, a, b int64) (bool, error) {
+	var blocked bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM user_blocks
+		WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?))`, a, b, b, a).Scan(&blocked)
+	return blocked, err
+}
+
+func MuteUser(ctx context.Context, db *sql.DB, muterID, mutedID int64) error {
+	_, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO user_mutes (muter_id, muted_id) VALUES (?, ?)", muterID, mutedID)
+	return err
+}
+
+func UnmuteUser(ctx context.Context, db *sql.DB, muterID, mutedID int64) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM user_mutes WHERE muter_id = ? AND muted_id = ?", muterID, mutedID)
+	return err
+}
+
+// ListBlockedUsers lists the users userID blocked, most recent first. They
+// are shown as cards: blocking someone must not reveal their private profile.
+func ListBlockedUsers(ctx context.Context, db *sql.DB, userID int64) ([]UserCard, error) {
+	return listUserCards(ctx, db, `SELECT `+userCardColumns+`
+		FROM user_blocks
+		JOIN users ON users.id = user_blocks.blocked_id
+		WHERE user_blocks.blocker_id = ?
+		ORDER BY user_blocks.created_at DESC`, userID, userID, userID)
+}
+
+// ListMutedUsers is ListBlockedUsers for mutes.
+func ListMutedUsers(ctx context.Context, db *sql.DB, userID int64) ([]UserCard, error) {
+	return listUserCards(ctx, db, `SELECT `+userCardColumns+`
+		FROM user_mutes
+		JOIN users ON users.id = user_mutes.muted_id
+		WHERE user_mutes.muter_id = ?
+		ORDER BY user_mutes.created_at DESC`, userID, userID, userID)
+}
+
+func listUserCards(ctx context.Context, db *sql.DB, query string, args ...any) ([]UserCard, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := []UserCard{}
+	for rows.Next() {
+		card, err := scanUserCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
@@ -5,6 +5,75 @@ import (
 	"database/sql"
 )
 
+// MessageKind tells direct messages and group messages apart where both are
+// handled alike, such as reactions.
+type MessageKind int
+
+const (
+	DirectMessage MessageKind = iota
+	GroupMessage
+)
+
+func (kind MessageKind) reactions() itemTable {
+	if kind == GroupMessage {
+		return groupMessageReactions
+	}
+	return dmReactions
+}
+
+// MessageAudience lists who takes part in the conversation of a message:
+// both ends of a direct message, or the members of the group. found is
+// false when the message does not exist.
+func MessageAudience(ctx context.Context, db *sql.DB, kind MessageKind, messageID int64) ([]int64, bool, error) {
+	var rows *sql.Rows
+	var err error
+	if kind == GroupMessage {
+		rows, err = db.QueryContext(ctx, `SELECT group_members.user_id
+			FROM group_messages
+			JOIN group_members ON group_members.group_id = group_messages.group_id
+			WHERE group_messages.id = ?`, messageID)
+	} else {
+		rows, err = db.QueryContext(ctx, `SELECT from_user_id FROM dm_messages WHERE id = ?
+			UNION SELECT to_user_id FROM dm_messages WHERE id = ?`, messageID, messageID)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	var userIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, false, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, len(userIDs) > 0, rows.Err()
+}
+
+// CanViewMessage reports whether viewerID takes part in the conversation of
+// a message. A block between the two ends of a direct message shuts it.
+func CanViewMessage(ctx context.Context, db *sql.DB, kind MessageKind, viewerID, messageID int64) (bool, error) {
+	audience, found, err := MessageAudience(ctx, db, kind, messageID)
+	if err != nil || !found {
+		return false, err
+	}
+	member := false
+	var otherID int64
+	for _, id := range audience {
+		if id == viewerID {
+			member = true
+		} else if kind == DirectMessage {
+			otherID = id
+		}
+	}
+	if !member || otherID == 0 {
+		return member, nil
+	}
+	blocked, err := IsBlocked(ctx, db, viewerID, otherID)
+	return !blocked, err
+}
+
 func SaveDM(ctx context.Context, db *sql.DB, fromID, toID int64, text string) (Message, error) {
 	result, err := db.ExecContext(ctx, "INSERT INTO dm_messages (from_user_id, to_user_id, text) VALUES (?, ?, ?)", fromID, toID, text)
 	if err != nil {
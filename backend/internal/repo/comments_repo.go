@@ -31,8 +31,12 @@ func GetCommentByID(ctx context.Context, db *sql.DB, id int64) (Comment, error)
 	return comment, nil
 }
 
-func ListComments(ctx context.Context, db *sql.DB, postID int64, limit, offset int) ([]Comment, error) {
-	rows, err := db.QueryContext(ctx, "SELECT id, post_id, user_id, text, media_path, created_at FROM comments WHERE post_id = ? ORDER BY created_at ASC LIMIT ? OFFSET ?", postID, limit, offset)
+// ListComments leaves out comments by users the viewer blocked or was
+// blocked by.
+func ListComments(ctx context.Context, db *sql.DB, viewerID, postID int64, limit, offset int) ([]Comment, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, post_id, user_id, text, media_path, created_at FROM comments
+		WHERE post_id = ? AND NOT `+blockedWith("comments.user_id")+`
+		ORDER BY created_at ASC LIMIT ? OFFSET ?`, postID, viewerID, viewerID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -47,5 +51,25 @@ func ListComments(ctx context.Context, db *sql.DB, postID int64, limit, offset i
 		comment.MediaPath = nullableStringPtr(media)
 		comments = append(comments, comment)
 	}
-	return comments, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return comments, LoadCommentReactions(ctx, db, viewerID, comments)
+}
+
+// CanViewComment reports whether the viewer can see the comment: they can
+// see its post and have no block with its author.
+func CanViewComment(ctx context.Context, db *sql.DB, viewerID, commentID int64) (bool, error) {
+	comment, err := GetCommentByID(ctx, db, commentID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	blocked, err := IsBlocked(ctx, db, viewerID, comment.UserID)
+	if err != nil || blocked {
+		return false, err
+	}
+	return CanViewPost(ctx, db, viewerID, comment.PostID)
 }
@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// CreateEmailVerification issues a token proving ownership of email. The
+// address is stored with the token so a link sent before an email change
+// cannot verify the new address.
+func CreateEmailVerification(ctx context.Context, db *sql.DB, userID int64, email string, ttl time.Duration) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM email_verifications WHERE user_id = ?", userID); err != nil {
+		_ = tx.Rollback()
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO email_verifications (token_hash, user_id, email, expires_at) VALUES (?, ?, ?, ?)",
+		hashToken(token), userID, email, sqliteTime(time.Now().Add(ttl))); err != nil {
+		_ = tx.Rollback()
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// LastEmailVerificationSentAt reports when the latest verification email
+// was issued for userID, used to throttle resends.
+func LastEmailVerificationSentAt(ctx context.Context, db *sql.DB, userID int64) (time.Time, bool, error) {
+	var createdAt string
+	row := db.QueryRowContext(ctx, "SELECT created_at FROM email_verifications WHERE user_id = ? ORDER BY created_at DESC LIMIT 1", userID)
+	if err := row.Scan(&createdAt); err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	return parseSQLiteTime(createdAt), true, nil
+}
+
+// VerifyEmail consumes token and marks the address it was issued for as
+// verified. It reports false when the token is unknown or expired, or the
+// account's email has changed since.
+func VerifyEmail(ctx context.Context, db *sql.DB, token string) (int64, bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var email string
+	row := tx.QueryRowContext(ctx, `SELECT user_id, email FROM email_verifications
+		WHERE token_hash = ? AND expires_at > CURRENT_TIMESTAMP`, hashToken(token))
+	if err := row.Scan(&userID, &email); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM email_verifications WHERE token_hash = ?", hashToken(token)); err != nil {
+		return 0, false, err
+	}
+	result, err := tx.ExecContext(ctx, `UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+		WHERE id = ? AND email = ?`, userID, email)
+	if err != nil {
+		return 0, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	affected, _ := result.RowsAffected()
+	return userID, affected > 0, nil
+}
+
+func IsEmailVerified(ctx context.Context, db *sql.DB, userID int64) (bool, error) {
+	var verified bool
+	row := db.QueryRowContext(ctx, "SELECT email_verified_at IS NOT NULL FROM users WHERE id = ?", userID)
+	if err := row.Scan(&verified); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return verified, nil
+}
+
+func MarkEmailVerified(ctx context.Context, db *sql.DB, userID int64) error {
+	_, err := db.ExecContext(ctx, "UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP) WHERE id = ?", userID)
+	return err
+}
+
+func DeleteExpiredEmailVerifications(ctx context.Context, db *sql.DB) (int64, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM email_verifications WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
@@ -0,0 +1,228 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+// AccountExport is everything we hold about one user, as handed back to them
+// by GET /api/me/export.
+type AccountExport struct {
+	Profile        UserProfile           `json:"profile"`
+	Identities     []Identity            `json:"identities"`
+	Posts          []ExportPost          `json:"posts"`
+	Comments       []ExportComment       `json:"comments"`
+	DirectMessages []ExportDirectMessage `json:"direct_messages"`
+	GroupMessages  []ExportGroupMessage  `json:"group_messages"`
+	EventResponses []ExportEventResponse `json:"event_responses"`
+	Notifications  []ExportNotification  `json:"notifications"`
+	Media          []ExportMedia         `json:"media"`
+}
+
+type ExportPost struct {
+	ID         int64   `json:"id"`
+	GroupID    *int64  `json:"group_id"`
+	Text       string  `json:"text"`
+	Visibility string  `json:"visibility"`
+	MediaPath  *string `json:"media_path"`
+	CreatedAt  string  `json:"created_at"`
+	EditedAt   *string `json:"edited_at"`
+}
+
+type ExportComment struct {
+	ID        int64   `json:"id"`
+	PostID    int64   `json:"post_id"`
+	Text      string  `json:"text"`
+	MediaPath *string `json:"media_path"`
+	CreatedAt string  `json:"created_at"`
+}
+
+type ExportDirectMessage struct {
+	ID         int64  `json:"id"`
+	FromUserID int64  `json:"from_user_id"`
+	ToUserID   int64  `json:"to_user_id"`
+	Text       string `json:"text"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type ExportGroupMessage struct {
+	ID        int64  `json:"id"`
+	GroupID   int64  `json:"group_id"`
+	Text      string `json:"text"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ExportEventResponse struct {
+	EventID   int64  `json:"event_id"`
+	GroupID   int64  `json:"group_id"`
+	Title     string `json:"title"`
+	Datetime  string `json:"datetime"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ExportNotification struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	IsRead    bool            `json:"is_read"`
+	CreatedAt string          `json:"created_at"`
+}
+
+type ExportMedia struct {
+	ID        int64  `json:"id"`
+	Path      string `json:"path"`
+	MIME      string `json:"mime"`
+	CreatedAt string `json:"created_at"`
+}
+
+func ExportAccount(ctx context.Context, db *sql.DB, userID int64) (AccountExport, error) {
+	profile, found, err := GetUserByID(ctx, db, userID)
+	if err != nil {
+		return AccountExport{}, err
+	}
+	if !found {
+		return AccountExport{}, sql.ErrNoRows
+	}
+	export := AccountExport{
+		Profile:        profile,
+		Posts:          []ExportPost{},
+		Comments:       []ExportComment{},
+		DirectMessages: []ExportDirectMessage{},
+		GroupMessages:  []ExportGroupMessage{},
+		EventResponses: []ExportEventResponse{},
+		Notifications:  []ExportNotification{},
+		Media:          []ExportMedia{},
+	}
+	if export.Identities, err = ListOAuthAccounts(ctx, db, userID); err != nil {
+		return AccountExport{}, err
+	}
+	gaming, err := GetGamingProfile(ctx, db, userID, userID)
+	if err != nil {
+		return AccountExport{}, err
+	}
+	export.Profile.Gaming = &gaming
+
+	steps := []struct {
+		query string
+		scan  func(rowScanner) error
+	}{
+		{
+			"SELECT id, group_id, text, visibility, media_path, created_at, edited_at FROM posts WHERE user_id = ? ORDER BY id",
+			func(row rowScanner) error {
+				var post ExportPost
+				var groupID sql.NullInt64
+				var mediaPath, editedAt sql.NullString
+				if err := row.Scan(&post.ID, &groupID, &post.Text, &post.Visibility, &mediaPath, &post.CreatedAt, &editedAt); err != nil {
+					return err
+				}
+				if groupID.Valid {
+					post.GroupID = &groupID.Int64
+				}
+				post.MediaPath = nullableStringPtr(mediaPath)
+				post.EditedAt = nullableStringPtr(editedAt)
+				export.Posts = append(export.Posts, post)
+				return nil
+			},
+		},
+		{
+			"SELECT id, post_id, text, media_path, created_at FROM comments WHERE user_id = ? ORDER BY id",
+			func(row rowScanner) error {
+				var comment ExportComment
+				var mediaPath sql.NullString
+				if err := row.Scan(&comment.ID, &comment.PostID, &comment.Text, &mediaPath, &comment.CreatedAt); err != nil {
+					return err
+				}
+				comment.MediaPath = nullableStringPtr(mediaPath)
+				export.Comments = append(export.Comments, comment)
+				return nil
+			},
+		},
+		{
+			"SELECT id, from_user_id, to_user_id, text, created_at FROM dm_messages WHERE from_user_id = ?1 OR to_user_id = ?1 ORDER BY id",
+			func(row rowScanner) error {
+				var message ExportDirectMessage
+				if err := row.Scan(&message.ID, &message.FromUserID, &message.ToUserID, &message.Text, &message.CreatedAt); err != nil {
+					return err
+				}
+				export.DirectMessages = append(export.DirectMessages, message)
+				return nil
+			},
+		},
+		{
+			"SELECT id, group_id, text, created_at FROM group_messages WHERE from_user_id = ? ORDER BY id",
+			func(row rowScanner) error {
+				var message ExportGroupMessage
+				if err := row.Scan(&message.ID, &message.GroupID, &message.Text, &message.CreatedAt); err != nil {
+					return err
+				}
+				export.GroupMessages = append(export.GroupMessages, message)
+				return nil
+			},
+		},
+		{
+			`SELECT events.id, events.group_id, events.title, events.datetime, event_responses.status, event_responses.created_at
+			FROM event_responses JOIN events ON events.id = event_responses.event_id
+			WHERE event_responses.user_id = ? ORDER BY events.id`,
+			func(row rowScanner) error {
+				var response ExportEventResponse
+				if err := row.Scan(&response.EventID, &response.GroupID, &response.Title, &response.Datetime, &response.Status, &response.CreatedAt); err != nil {
+					return err
+				}
+				export.EventResponses = append(export.EventResponses, response)
+				return nil
+			},
+		},
+		{
+			"SELECT id, type, payload_json, is_read, created_at FROM notifications WHERE user_id = ? ORDER BY id",
+			func(row rowScanner) error {
+				var notification ExportNotification
+				var payload string
+				var isRead int
+				if err := row.Scan(&notification.ID, &notification.Type, &payload, &isRead, &notification.CreatedAt); err != nil {
+					return err
+				}
+				if json.Valid([]byte(payload)) {
+					notification.Payload = json.RawMessage(payload)
+				} else {
+					notification.Payload, _ = json.Marshal(payload)
+				}
+				notification.IsRead = isRead == 1
+				export.Notifications = append(export.Notifications, notification)
+				return nil
+			},
+		},
+		{
+			"SELECT id, path, mime, created_at FROM media WHERE owner_user_id = ? ORDER BY id",
+			func(row rowScanner) error {
+				var media ExportMedia
+				if err := row.Scan(&media.ID, &media.Path, &media.MIME, &media.CreatedAt); err != nil {
+					return err
+				}
+				export.Media = append(export.Media, media)
+				return nil
+			},
+		},
+	}
+	for _, step := range steps {
+		if err := exportRows(ctx, db, step.query, userID, step.scan); err != nil {
+			return AccountExport{}, err
+		}
+	}
+	return export, nil
+}
+
+func exportRows(ctx context.Context, db *sql.DB, query string, userID int64, scan func(rowScanner) error) error {
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
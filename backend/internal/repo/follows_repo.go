@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"strings"
 )
 
 func CreateFollowRequest(ctx context.Context, db *sql.DB, fromID, toID int64) (int64, error) {
@@ -36,14 +37,73 @@ func UpdateFollowRequestStatus(ctx context.Context, db *sql.DB, requestID int64,
 	return fromID, toID, true, nil
 }
 
+// CancelFollowRequest withdraws a pending request fromID sent, along with
+// the notification it raised. It reports false when fromID has no such
+// request.
+func CancelFollowRequest(ctx context.Context, db *sql.DB, requestID, fromID int64) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM follow_requests WHERE id = ? AND from_user_id = ? AND status = 'pending'", requestID, fromID)
+	if err != nil {
+		return false, err
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM notifications
+		WHERE type = 'follow_request' AND json_valid(payload_json) AND json_extract(payload_json, '$.request_id') = ?`, requestID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// AcceptPendingFollowRequests turns every pending request to userID into a
+// follow, for accounts going public, and returns how many were accepted.
+func AcceptPendingFollowRequests(ctx context.Context, db *sql.DB, userID int64) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO follows (follower_id, followee_id)
+		SELECT from_user_id, to_user_id FROM follow_requests WHERE to_user_id = ? AND status = 'pending'`, userID); err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, "UPDATE follow_requests SET status = 'accepted' WHERE to_user_id = ? AND status = 'pending'", userID)
+	if err != nil {
+		return 0, err
+	}
+	accepted, _ := result.RowsAffected()
+	return accepted, tx.Commit()
+}
+
 func CreateFollow(ctx context.Context, db *sql.DB, followerID, followeeID int64) error {
 	_, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO follows (follower_id, followee_id) VALUES (?, ?)", followerID, followeeID)
 	return err
 }
 
+// DeleteFollow ends a follow. The follower also leaves the followee's
+// audience lists, which may only hold followers.
 func DeleteFollow(ctx context.Context, db *sql.DB, followerID, followeeID int64) error {
-	_, err := db.ExecContext(ctx, "DELETE FROM follows WHERE follower_id = ? AND followee_id = ?", followerID, followeeID)
-	return err
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM follows WHERE follower_id = ? AND followee_id = ?", followerID, followeeID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM audience_list_members
+		WHERE user_id = ? AND list_id IN (SELECT id FROM audience_lists WHERE owner_id = ?)`, followerID, followeeID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func IsFollowing(ctx context.Context, db *sql.DB, followerID, followeeID int64) (bool, error) {
@@ -58,24 +118,81 @@ func IsFollowing(ctx context.Context, db *sql.DB, followerID, followeeID int64)
 	return true, nil
 }
 
-func ListFollowers(ctx context.Context, db *sql.DB, userID int64) ([]UserProfile, error) {
-	query := `SELECT users.id, users.email, users.first_name, users.last_name, users.dob, users.avatar_path,
-		users.nickname, users.about, users.is_public, users.created_at
-		FROM follows
-		JOIN users ON users.id = follows.follower_id
-		WHERE follows.followee_id = ?
-		ORDER BY follows.created_at DESC`
-	return queryProfiles(ctx, db, query, userID)
+// FollowCard is an entry of a follower or following list. The flags say how
+// the listed user and the viewer follow each other.
+type FollowCard struct {
+	UserCard
+	FollowedByViewer bool   `json:"followed_by_viewer"`
+	FollowsViewer    bool   `json:"follows_viewer"`
+	Mutual           bool   `json:"mutual"`
+	FollowedAt       string `json:"followed_at"`
+}
+
+// FollowListPage selects part of a follow list. Search narrows it the way
+// SearchUsers matches; AfterFollowedAt and AfterID are the FollowedAt and ID
+// of the last card of the previous page.
+type FollowListPage struct {
+	Search          string
+	Limit           int
+	AfterFollowedAt string
+	AfterID         int64
 }
 
-func ListFollowing(ctx context.Context, db *sql.DB, userID int64) ([]UserProfile, error) {
-	query := `SELECT users.id, users.email, users.first_name, users.last_name, users.dob, users.avatar_path,
-		users.nickname, users.about, users.is_public, users.created_at
+// ListFollowers lists who follows userID, most recent first, as seen by
+// viewerID.
+func ListFollowers(ctx context.Context, db *sql.DB, viewerID, userID int64, page FollowListPage) ([]FollowCard, error) {
+	return listFollowCards(ctx, db, "follower_id", "followee_id", viewerID, userID, page)
+}
+
+// ListFollowing lists who userID follows, most recent first, as seen by
+// viewerID.
+func ListFollowing(ctx context.Context, db *sql.DB, viewerID, userID int64, page FollowListPage) ([]FollowCard, error) {
+	return listFollowCards(ctx, db, "followee_id", "follower_id", viewerID, userID, page)
+}
+
+func listFollowCards(ctx context.Context, db *sql.DB, listed, owner string, viewerID, userID int64, page FollowListPage) ([]FollowCard, error) {
+	query := `SELECT ` + userCardColumns + `, follows.created_at,
+			EXISTS (SELECT 1 FROM follows AS f WHERE f.follower_id = ? AND f.followee_id = users.id),
+			EXISTS (SELECT 1 FROM follows AS f WHERE f.follower_id = users.id AND f.followee_id = ?)
 		FROM follows
-		JOIN users ON users.id = follows.followee_id
-		WHERE follows.follower_id = ?
-		ORDER BY follows.created_at DESC`
-	return queryProfiles(ctx, db, query, userID)
+		JOIN users ON users.id = follows.` + listed + `
+		WHERE follows.` + owner + ` = ? AND users.deletion_scheduled_at IS NULL
+			AND NOT ` + blockedWith("users.id")
+	args := []any{viewerID, viewerID, viewerID, viewerID, userID, viewerID, viewerID}
+	if page.Search != "" {
+		match := ftsQuery(page.Search)
+		if match == "" {
+			return []FollowCard{}, nil
+		}
+		query += ` AND (users.id IN (SELECT rowid FROM users_fts WHERE users_fts MATCH ?) AND ` + profileVisible + `
+			OR users.id IN (SELECT rowid FROM users_fts WHERE users_fts MATCH ?))`
+		args = append(args, match, viewerID, viewerID, limitedFTSQuery(match))
+	}
+	if page.AfterFollowedAt != "" {
+		query += ` AND (follows.created_at < ? OR (follows.created_at = ? AND users.id < ?))`
+		args = append(args, page.AfterFollowedAt, page.AfterFollowedAt, page.AfterID)
+	}
+	query += ` ORDER BY follows.created_at DESC, users.id DESC LIMIT ?`
+	args = append(args, page.Limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := []FollowCard{}
+	for rows.Next() {
+		var card FollowCard
+		userCard, err := scanUserCard(rows, &card.FollowedAt, &card.FollowedByViewer, &card.FollowsViewer)
+		if err != nil {
+			return nil, err
+		}
+		card.UserCard = userCard
+		card.Mutual = card.FollowedByViewer && card.FollowsViewer
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
 }
 
 func ListIncomingFollowRequests(ctx context.Context, db *sql.DB, userID int64) ([]FollowRequest, error) {
@@ -132,14 +249,18 @@ func queryProfiles(ctx context.Context, db *sql.DB, query string, args ...any) (
 	var profiles []UserProfile
 	for rows.Next() {
 		var profile UserProfile
+		var dob sql.NullString
 		var avatar sql.NullString
+		var handle sql.NullString
 		var nickname sql.NullString
 		var about sql.NullString
 		var isPublic int
-		if err := rows.Scan(&profile.ID, &profile.Email, &profile.FirstName, &profile.LastName, &profile.DOB,
-			&avatar, &nickname, &about, &isPublic, &profile.CreatedAt); err != nil {
+		if err := rows.Scan(&profile.ID, &profile.Email, &profile.FirstName, &profile.LastName, &handle, &dob,
+			&avatar, &nickname, &about, &isPublic, &profile.EmailVerified, &profile.CreatedAt); err != nil {
 			return nil, err
 		}
+		profile.Handle = handle.String
+		profile.DOB = strings.TrimSpace(dob.String)
 		profile.Avatar = nullableStringPtr(avatar)
 		profile.Nickname = nullableStringPtr(nickname)
 		profile.About = nullableStringPtr(about)
@@ -0,0 +1,114 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+// GamingProfile is the gaming section of a profile.
+type GamingProfile struct {
+	Platforms []PlatformAccount `json:"platforms"`
+	Games     []FavoriteGame    `json:"games"`
+}
+
+type PlatformAccount struct {
+	Platform   string `json:"platform"`
+	AccountID  string `json:"account_id"`
+	Visibility string `json:"visibility"`
+}
+
+// FavoriteGame is a game the user plays, with the rank and roles they
+// report for it.
+type FavoriteGame struct {
+	Slug  string   `json:"slug"`
+	Name  string   `json:"name"`
+	Rank  *string  `json:"rank"`
+	Roles []string `json:"roles"`
+}
+
+// platformVisible is a condition that holds when the viewer may see the
+// user_platform_accounts row. It takes the viewer ID twice.
+const platformVisible = `(user_platform_accounts.user_id = ?
+	OR user_platform_accounts.visibility = 'public'
+	OR (user_platform_accounts.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = user_platform_accounts.user_id)))`
+
+// GetGamingProfile returns userID's gaming profile with only the platform
+// accounts viewerID may see.
+func GetGamingProfile(ctx context.Context, db *sql.DB, viewerID, userID int64) (GamingProfile, error) {
+	gaming := GamingProfile{Platforms: []PlatformAccount{}, Games: []FavoriteGame{}}
+
+	rows, err := db.QueryContext(ctx, `SELECT platform, account_id, visibility FROM user_platform_accounts
+		WHERE user_id = ? AND `+platformVisible+`
+		ORDER BY platform`, userID, viewerID, viewerID)
+	if err != nil {
+		return GamingProfile{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var account PlatformAccount
+		if err := rows.Scan(&account.Platform, &account.AccountID, &account.Visibility); err != nil {
+			return GamingProfile{}, err
+		}
+		gaming.Platforms = append(gaming.Platforms, account)
+	}
+	if err := rows.Err(); err != nil {
+		return GamingProfile{}, err
+	}
+
+	rows, err = db.QueryContext(ctx, "SELECT game_slug, game_name, rank, roles_json FROM user_games WHERE user_id = ? ORDER BY position", userID)
+	if err != nil {
+		return GamingProfile{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var game FavoriteGame
+		var rank sql.NullString
+		var roles string
+		if err := rows.Scan(&game.Slug, &game.Name, &rank, &roles); err != nil {
+			return GamingProfile{}, err
+		}
+		game.Rank = nullableStringPtr(rank)
+		if err := json.Unmarshal([]byte(roles), &game.Roles); err != nil || game.Roles == nil {
+			game.Roles = []string{}
+		}
+		gaming.Games = append(gaming.Games, game)
+	}
+	return gaming, rows.Err()
+}
+
+// UpdateGamingProfile replaces the user's platform accounts and favorite
+// games. A nil list is left as it is; games keep the order given.
+func UpdateGamingProfile(ctx context.Context, db *sql.DB, userID int64, platforms []PlatformAccount, games []FavoriteGame) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if platforms != nil {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM user_platform_accounts WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+		for _, account := range platforms {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO user_platform_accounts (user_id, platform, account_id, visibility) VALUES (?, ?, ?, ?)",
+				userID, account.Platform, account.AccountID, account.Visibility); err != nil {
+				return err
+			}
+		}
+	}
+	if games != nil {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM user_games WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+		for i, game := range games {
+			roles, _ := json.Marshal(game.Roles)
+			if _, err := tx.ExecContext(ctx, "INSERT INTO user_games (user_id, game_slug, game_name, rank, roles_json, position) VALUES (?, ?, ?, ?, ?, ?)",
+				userID, game.Slug, game.Name, nullableString(game.Rank), string(roles), i); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
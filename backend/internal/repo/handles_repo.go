@@ -0,0 +1,168 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"backend/internal/domain/profile"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// maxHandleSuffix bounds how many numbered variants AssignHandle tries
+// before falling back to user<id>.
+const maxHandleSuffix = 50
+
+// ResolveHandle finds the user a handle belongs to, now or previously. The
+// returned handle is the user's current one, so callers can tell when the
+// lookup went through an old handle.
+func ResolveHandle(ctx context.Context, db *sql.DB, handle string) (int64, string, bool, error) {
+	var id int64
+	var current string
+	err := db.QueryRowContext(ctx, "SELECT id, handle FROM users WHERE handle = ?", handle).Scan(&id, &current)
+	if err == sql.ErrNoRows {
+		err = db.QueryRowContext(ctx, `SELECT users.id, users.handle
+			FROM handle_history
+			JOIN users ON users.id = handle_history.user_id
+			WHERE handle_history.handle = ?`, handle).Scan(&id, &current)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, "", false, nil
+		}
+		return 0, "", false, err
+	}
+	return id, current, true, nil
+}
+
+// HandleAvailable reports whether nobody uses handle and nobody else gave it
+// up within the hold period.
+func HandleAvailable(ctx context.Context, db *sql.DB, handle string, hold time.Duration) (bool, error) {
+	var taken bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE handle = ?)
+		OR EXISTS (SELECT 1 FROM handle_history WHERE handle = ? AND released_at > ?)`,
+		handle, handle, sqliteTime(time.Now().Add(-hold))).Scan(&taken)
+	return !taken, err
+}
+
+// AssignHandle gives a new account the first free handle among base, base2,
+// base3 and so on, and returns it. Handles someone else gave up within hold
+// are skipped, as HandleAvailable does, and so are variants ValidateHandle
+// refuses, such as user2 for the base user.
+func AssignHandle(ctx context.Context, db *sql.DB, userID int64, base string, hold time.Duration) (string, error) {
+	for i := 1; i <= maxHandleSuffix; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = base + strconv.Itoa(i)
+		}
+		if profile.ValidateHandle(candidate) != nil {
+			continue
+		}
+		ok, err := ClaimHandle(ctx, db, userID, candidate, hold)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return candidate, nil
+		}
+	}
+	// ValidateHandle keeps every other handle out of this shape, and IDs
+	// are never reused, so no one else can hold it.
+	handle := "user" + strconv.FormatInt(userID, 10)
+	if _, err := db.ExecContext(ctx, "UPDATE users SET handle = ? WHERE id = ?", handle, userID); err != nil {
+		return "", err
+	}
+	return handle, nil
+}
+
+// ClaimHandle gives a new account exactly handle. It reports false when
+// someone uses the handle or gave it up within hold. A hold that has run out
+// is dropped from the history along the way.
+func ClaimHandle(ctx context.Context, db *sql.DB, userID int64, handle string, hold time.Duration) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE users SET handle = ?
+		WHERE id = ? AND NOT EXISTS (SELECT 1 FROM handle_history WHERE handle = ? AND user_id != ? AND released_at > ?)`,
+		handle, userID, handle, userID, sqliteTime(time.Now().Add(-hold)))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM handle_history WHERE handle = ? AND user_id != ?", handle, userID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ChangeHandle moves the user to a new handle. The old one is kept in the
+// history so links to it keep working, and stays reserved for the user for
+// the hold period. A change within cooldown of the previous one is refused
+// and retryAt says when it will be allowed; taken is set when someone else
+// has or holds the handle.
+func ChangeHandle(ctx context.Context, db *sql.DB, userID int64, handle string, cooldown, hold time.Duration) (taken bool, retryAt time.Time, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	var changedAt sql.NullString
+	if err := tx.QueryRowContext(ctx, "SELECT handle, handle_changed_at FROM users WHERE id = ?", userID).Scan(&current, &changedAt); err != nil {
+		return false, time.Time{}, err
+	}
+	if current == handle {
+		return false, time.Time{}, nil
+	}
+	if changedAt.Valid {
+		if next := parseSQLiteTime(changedAt.String).Add(cooldown); next.After(time.Now()) {
+			return false, next, nil
+		}
+	}
+
+	var holder int64
+	var releasedAt string
+	err = tx.QueryRowContext(ctx, "SELECT user_id, released_at FROM handle_history WHERE handle = ?", handle).Scan(&holder, &releasedAt)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return false, time.Time{}, err
+	case holder != userID && parseSQLiteTime(releasedAt).Add(hold).After(time.Now()):
+		return true, time.Time{}, nil
+	default:
+		// Either the user is taking back one of their own old handles or the
+		// hold has run out; in both cases it no longer redirects anywhere.
+		if _, err := tx.ExecContext(ctx, "DELETE FROM handle_history WHERE handle = ?", handle); err != nil {
+			return false, time.Time{}, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO handle_history (handle, user_id, released_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(handle) DO UPDATE SET user_id = excluded.user_id, released_at = excluded.released_at`, current, userID); err != nil {
+		return false, time.Time{}, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET handle = ?, handle_changed_at = CURRENT_TIMESTAMP WHERE id = ?", handle, userID); err != nil {
+		if isUniqueViolation(err) {
+			return true, time.Time{}, nil
+		}
+		return false, time.Time{}, err
+	}
+	return false, time.Time{}, tx.Commit()
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
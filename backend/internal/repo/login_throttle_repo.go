@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Login throttle scopes.
+const (
+	ThrottleScopeEmail = "email"
+	ThrottleScopeIP    = "ip"
+)
+
+// LockoutPolicy describes when repeated login failures lock a key out.
+// Each consecutive lockout doubles the previous one, starting at Base and
+// capped at Max.
+type LockoutPolicy struct {
+	MaxFailures int
+	Window      time.Duration
+	Base        time.Duration
+	Max         time.Duration
+}
+
+// LoginLockedUntil returns when the lock on (scope, key) ends, or the zero
+// time when it is not locked.
+func LoginLockedUntil(ctx context.Context, db *sql.DB, scope, key string) (time.Time, error) {
+	var lockedUntil sql.NullString
+	row := db.QueryRowContext(ctx, "SELECT locked_until FROM login_throttle WHERE scope = ? AND key = ?", scope, key)
+	if err := row.Scan(&lockedUntil); err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	if !lockedUntil.Valid {
+		return time.Time{}, nil
+	}
+	until := parseSQLiteTime(lockedUntil.String)
+	if !until.After(time.Now()) {
+		return time.Time{}, nil
+	}
+	return until, nil
+}
+
+// RecordLoginFailure counts a failed attempt for (scope, key). When it
+// reaches policy.MaxFailures within policy.Window the key is locked; locked
+// reports whether this call started the lock.
+func RecordLoginFailure(ctx context.Context, db *sql.DB, scope, key string, policy LockoutPolicy) (time.Time, bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	failures, lockouts := 0, 0
+	windowStart := now
+	var windowStartedAt string
+	row := tx.QueryRowContext(ctx, "SELECT failures, lockouts, window_started_at FROM login_throttle WHERE scope = ? AND key = ?", scope, key)
+	switch err := row.Scan(&failures, &lockouts, &windowStartedAt); err {
+	case nil:
+		windowStart = parseSQLiteTime(windowStartedAt)
+	case sql.ErrNoRows:
+	default:
+		return time.Time{}, false, err
+	}
+	if windowStart.Add(policy.Window).Before(now) {
+		failures = 0
+		windowStart = now
+	}
+	failures++
+
+	var lockedUntil time.Time
+	locked := false
+	if policy.MaxFailures > 0 && failures >= policy.MaxFailures {
+		lockouts++
+		lockedUntil = now.Add(lockoutDuration(policy, lockouts))
+		locked = true
+		failures = 0
+		windowStart = now
+	}
+
+	var lockedValue any
+	if locked {
+		lockedValue = sqliteTime(lockedUntil)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO login_throttle (scope, key, failures, lockouts, window_started_at, locked_until, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(scope, key) DO UPDATE SET failures = excluded.failures, lockouts = excluded.lockouts,
+			window_started_at = excluded.window_started_at,
+			locked_until = COALESCE(excluded.locked_until, login_throttle.locked_until),
+			updated_at = CURRENT_TIMESTAMP`,
+		scope, key, failures, lockouts, sqliteTime(windowStart), lockedValue)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, false, err
+	}
+	return lockedUntil, locked, nil
+}
+
+func lockoutDuration(policy LockoutPolicy, lockouts int) time.Duration {
+	duration := policy.Base
+	for i := 1; i < lockouts && duration < policy.Max; i++ {
+		duration *= 2
+	}
+	if policy.Max > 0 && duration > policy.Max {
+		duration = policy.Max
+	}
+	return duration
+}
+
+func ClearLoginFailures(ctx context.Context, db *sql.DB, scope, key string) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM login_throttle WHERE scope = ? AND key = ?", scope, key)
+	return err
+}
+
+// DeleteStaleLoginThrottles forgets keys that are not locked and have been
+// quiet for retention, which also resets their lockout backoff.
+func DeleteStaleLoginThrottles(ctx context.Context, db *sql.DB, retention time.Duration) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM login_throttle
+		WHERE updated_at <= ? AND (locked_until IS NULL OR locked_until <= CURRENT_TIMESTAMP)`,
+		sqliteTime(time.Now().Add(-retention)))
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
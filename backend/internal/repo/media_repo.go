@@ -13,3 +13,10 @@ func CreateMedia(ctx context.Context, db *sql.DB, ownerID int64, path, mime stri
 	id, _ := result.LastInsertId()
 	return id, nil
 }
+
+// IsMediaOwner reports whether path was uploaded by userID.
+func IsMediaOwner(ctx context.Context, db *sql.DB, path string, userID int64) (bool, error) {
+	var owned bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM media WHERE path = ? AND owner_user_id = ?)", path, userID).Scan(&owned)
+	return owned, err
+}
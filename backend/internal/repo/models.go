@@ -5,12 +5,15 @@ type UserProfile struct {
 	Email     string `json:"email"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
+	Handle    string `json:"handle"`
 	DOB       string `json:"dob"`
 	Avatar    *string `json:"avatar_path"`
 	Nickname  *string `json:"nickname"`
 	About     *string `json:"about"`
 	IsPublic  bool   `json:"is_public"`
+	EmailVerified bool `json:"email_verified"`
 	CreatedAt string `json:"created_at"`
+	Gaming    *GamingProfile `json:"gaming,omitempty"`
 }
 
 type Post struct {
@@ -20,7 +23,11 @@ type Post struct {
 	Text      string
 	Visibility string
 	MediaPath *string
+	AudienceListID *int64
+	EditedAt  *string
 	CreatedAt string
+	Reactions map[string]int
+	MyReaction *string
 }
 
 type Comment struct {
@@ -30,6 +37,8 @@ type Comment struct {
 	Text      string
 	MediaPath *string
 	CreatedAt string
+	Reactions map[string]int
+	MyReaction *string
 }
 
 type Group struct {
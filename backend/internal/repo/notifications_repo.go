@@ -10,8 +10,17 @@ func CreateNotification(ctx context.Context, db *sql.DB, userID int64, ntype str
 	return err
 }
 
+// notificationSender extracts the user who caused a notification, for the
+// types that have one.
+const notificationSender = "(CASE WHEN json_valid(payload_json) THEN json_extract(payload_json, '$.from_user_id') END)"
+
+// ListNotifications hides notifications caused by users the recipient
+// muted or has a block with.
 func ListNotifications(ctx context.Context, db *sql.DB, userID int64, limit, offset int) ([]Notification, error) {
-	rows, err := db.QueryContext(ctx, "SELECT id, user_id, type, payload_json, is_read, created_at FROM notifications WHERE user_id = ? ORDER BY created_at DESC LIMIT ? OFFSET ?", userID, limit, offset)
+	rows, err := db.QueryContext(ctx, `SELECT id, user_id, type, payload_json, is_read, created_at FROM notifications
+		WHERE user_id = ?
+			AND (`+notificationSender+` IS NULL OR NOT (`+blockedWith(notificationSender)+` OR `+mutedBy(notificationSender)+`))
+		ORDER BY created_at DESC LIMIT ? OFFSET ?`, userID, userID, userID, userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
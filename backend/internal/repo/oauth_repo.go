@@ -29,3 +29,75 @@ func CreateOAuthAccount(ctx context.Context, db *sql.DB, userID int64, provider,
 	)
 	return err
 }
+
+type Identity struct {
+	Provider  string  `json:"provider"`
+	Email     *string `json:"email"`
+	CreatedAt string  `json:"created_at"`
+}
+
+func ListOAuthAccounts(ctx context.Context, db *sql.DB, userID int64) ([]Identity, error) {
+	rows, err := db.QueryContext(ctx, "SELECT provider, email, created_at FROM oauth_accounts WHERE user_id = ? ORDER BY created_at ASC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []Identity{}
+	for rows.Next() {
+		var identity Identity
+		var email sql.NullString
+		if err := rows.Scan(&identity.Provider, &email, &identity.CreatedAt); err != nil {
+			return nil, err
+		}
+		identity.Email = nullableStringPtr(email)
+		list = append(list, identity)
+	}
+	return list, rows.Err()
+}
+
+func HasOAuthAccount(ctx context.Context, db *sql.DB, userID int64, provider string) (bool, error) {
+	var exists int
+	row := db.QueryRowContext(ctx, "SELECT 1 FROM oauth_accounts WHERE user_id = ? AND provider = ?", userID, provider)
+	if err := row.Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteOAuthAccount unlinks provider from userID unless it is the account's
+// only way to sign in. removed is false when nothing was linked; lastMethod
+// is true when the unlink was refused.
+func DeleteOAuthAccount(ctx context.Context, db *sql.DB, userID int64, provider string) (removed bool, lastMethod bool, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, false, err
+	}
+	defer tx.Rollback()
+
+	var hasPassword bool
+	var identities int
+	row := tx.QueryRowContext(ctx, `SELECT users.password_hash IS NOT NULL,
+		(SELECT COUNT(*) FROM oauth_accounts WHERE oauth_accounts.user_id = users.id)
+		FROM users WHERE users.id = ?`, userID)
+	if err := row.Scan(&hasPassword, &identities); err != nil {
+		return false, false, err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM oauth_accounts WHERE user_id = ? AND provider = ?", userID, provider)
+	if err != nil {
+		return false, false, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, false, nil
+	}
+	if !hasPassword && identities <= 1 {
+		return false, true, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return false, false, err
+	}
+	return true, false, nil
+}
@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// CreatePasswordReset issues a reset token for userID. Any earlier unused
+// token for the same user stops working, so only the latest email is valid.
+func CreatePasswordReset(ctx context.Context, db *sql.DB, userID int64, ttl time.Duration) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM password_resets WHERE user_id = ? AND used_at IS NULL", userID); err != nil {
+		_ = tx.Rollback()
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES (?, ?, ?)",
+		hashToken(token), userID, sqliteTime(time.Now().Add(ttl))); err != nil {
+		_ = tx.Rollback()
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ResetPassword consumes token and, if it was valid, stores passwordHash and
+// signs the user out everywhere, access tokens included. It reports false for unknown, expired or
+// already used tokens.
+func ResetPassword(ctx context.Context, db *sql.DB, token, passwordHash string) (int64, bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE password_resets SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP`, hashToken(token))
+	if err != nil {
+		return 0, false, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return 0, false, nil
+	}
+
+	var userID int64
+	if err := tx.QueryRowContext(ctx, "SELECT user_id FROM password_resets WHERE token_hash = ?", hashToken(token)).Scan(&userID); err != nil {
+		return 0, false, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, userID); err != nil {
+		return 0, false, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+		return 0, false, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM access_tokens WHERE user_id = ?", userID); err != nil {
+		return 0, false, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM login_challenges WHERE user_id = ?", userID); err != nil {
+		return 0, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return userID, true, nil
+}
+
+func DeleteExpiredPasswordResets(ctx context.Context, db *sql.DB) (int64, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM password_resets WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
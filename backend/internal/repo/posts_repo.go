@@ -7,15 +7,18 @@ import (
 	"strings"
 )
 
-func CreatePost(ctx context.Context, db *sql.DB, userID int64, text string, visibility string, mediaPath *string, allowedIDs []int64, groupID *int64) (Post, error) {
+// CreatePost stores a post. A private post is shown to the users in
+// allowedIDs and to the current members of the audience list, if any.
+func CreatePost(ctx context.Context, db *sql.DB, userID int64, text string, visibility string, mediaPath *string, allowedIDs []int64, audienceListID *int64, groupID *int64) (Post, error) {
 	result, err := db.ExecContext(
 		ctx,
-		"INSERT INTO posts (user_id, group_id, text, visibility, media_path) VALUES (?, ?, ?, ?, ?)",
+		"INSERT INTO posts (user_id, group_id, text, visibility, media_path, audience_list_id) VALUES (?, ?, ?, ?, ?, ?)",
 		userID,
 		groupID,
 		text,
 		visibility,
 		nullableString(mediaPath),
+		audienceListID,
 	)
 	if err != nil {
 		return Post{}, err
@@ -45,43 +48,69 @@ func GetPostByID(ctx context.Context, db *sql.DB, postID int64) (Post, error) {
 	var post Post
 	var groupID sql.NullInt64
 	var media sql.NullString
+	var audienceListID sql.NullInt64
+	var editedAt sql.NullString
 
-	row := db.QueryRowContext(ctx, "SELECT id, user_id, group_id, text, visibility, media_path, created_at FROM posts WHERE id = ?", postID)
-	if err := row.Scan(&post.ID, &post.UserID, &groupID, &post.Text, &post.Visibility, &media, &post.CreatedAt); err != nil {
+	row := db.QueryRowContext(ctx, "SELECT id, user_id, group_id, text, visibility, media_path, audience_list_id, edited_at, created_at FROM posts WHERE id = ?", postID)
+	if err := row.Scan(&post.ID, &post.UserID, &groupID, &post.Text, &post.Visibility, &media, &audienceListID, &editedAt, &post.CreatedAt); err != nil {
 		return Post{}, err
 	}
 	if groupID.Valid {
 		post.GroupID = &groupID.Int64
 	}
+	if audienceListID.Valid {
+		post.AudienceListID = &audienceListID.Int64
+	}
 	post.MediaPath = nullableStringPtr(media)
+	post.EditedAt = nullableStringPtr(editedAt)
 	return post, nil
 }
 
-func Feed(ctx context.Context, db *sql.DB, userID int64, limit, offset int) ([]Post, error) {
-	query := `SELECT posts.id, posts.user_id, posts.group_id, posts.text, posts.visibility, posts.media_path, posts.created_at
-		FROM posts
+// inAudienceList is a condition that holds when the viewer is currently a
+// member of the audience list the post targets. It takes the viewer ID once.
+const inAudienceList = `EXISTS (SELECT 1 FROM audience_list_members
+	WHERE audience_list_members.list_id = posts.audience_list_id AND audience_list_members.user_id = ?)`
+
+// visiblePosts is the FROM and WHERE of a query over every post the viewer
+// may see. It takes the viewer ID five times; callers add conditions with
+// AND.
+const visiblePosts = `FROM posts
 		LEFT JOIN follows ON follows.followee_id = posts.user_id AND follows.follower_id = ?
 		LEFT JOIN post_allowed ON post_allowed.post_id = posts.id AND post_allowed.user_id = ?
 		LEFT JOIN group_members ON group_members.group_id = posts.group_id AND group_members.user_id = ?
-		WHERE posts.group_id IS NULL AND (
-			posts.user_id = ?
-			OR posts.visibility = 'public'
-			OR (posts.visibility = 'followers' AND follows.follower_id IS NOT NULL)
-			OR (posts.visibility = 'private' AND post_allowed.user_id IS NOT NULL)
-		)
-		OR (posts.group_id IS NOT NULL AND group_members.user_id IS NOT NULL)
+		WHERE (
+			(posts.group_id IS NULL AND (
+				posts.user_id = ?
+				OR posts.visibility = 'public'
+				OR (posts.visibility = 'followers' AND follows.follower_id IS NOT NULL)
+				OR (posts.visibility = 'private' AND (post_allowed.user_id IS NOT NULL OR ` + inAudienceList + `))
+			))
+			OR (posts.group_id IS NOT NULL AND group_members.user_id IS NOT NULL)
+		)`
+
+// Feed lists what the user may see, leaving out authors they blocked, muted
+// or were blocked by.
+func Feed(ctx context.Context, db *sql.DB, userID int64, limit, offset int) ([]Post, error) {
+	query := `SELECT posts.id, posts.user_id, posts.group_id, posts.text, posts.visibility, posts.media_path, posts.audience_list_id, posts.edited_at, posts.created_at
+		` + visiblePosts + `
+		AND NOT ` + blockedWith("posts.user_id") + `
+		AND NOT ` + mutedBy("posts.user_id") + `
 		ORDER BY posts.created_at DESC
 		LIMIT ? OFFSET ?`
-	rows, err := db.QueryContext(ctx, query, userID, userID, userID, userID, limit, offset)
+	rows, err := db.QueryContext(ctx, query, userID, userID, userID, userID, userID, userID, userID, userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	return scanPosts(rows)
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return posts, LoadPostReactions(ctx, db, userID, posts)
 }
 
 func UserPosts(ctx context.Context, db *sql.DB, viewerID, userID int64, limit, offset int) ([]Post, error) {
-	query := `SELECT posts.id, posts.user_id, posts.group_id, posts.text, posts.visibility, posts.media_path, posts.created_at
+	query := `SELECT posts.id, posts.user_id, posts.group_id, posts.text, posts.visibility, posts.media_path, posts.audience_list_id, posts.edited_at, posts.created_at
 		FROM posts
 		LEFT JOIN follows ON follows.followee_id = posts.user_id AND follows.follower_id = ?
 		LEFT JOIN post_allowed ON post_allowed.post_id = posts.id AND post_allowed.user_id = ?
@@ -89,53 +118,67 @@ func UserPosts(ctx context.Context, db *sql.DB, viewerID, userID int64, limit, o
 			posts.user_id = ?
 			OR posts.visibility = 'public'
 			OR (posts.visibility = 'followers' AND follows.follower_id IS NOT NULL)
-			OR (posts.visibility = 'private' AND post_allowed.user_id IS NOT NULL)
+			OR (posts.visibility = 'private' AND (post_allowed.user_id IS NOT NULL OR ` + inAudienceList + `))
 		)
+		AND NOT ` + blockedWith("posts.user_id") + `
 		ORDER BY posts.created_at DESC
 		LIMIT ? OFFSET ?`
-	rows, err := db.QueryContext(ctx, query, viewerID, viewerID, userID, viewerID, limit, offset)
+	rows, err := db.QueryContext(ctx, query, viewerID, viewerID, userID, viewerID, viewerID, viewerID, viewerID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	return scanPosts(rows)
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return posts, LoadPostReactions(ctx, db, viewerID, posts)
 }
 
 func GroupPosts(ctx context.Context, db *sql.DB, userID, groupID int64, limit, offset int) ([]Post, error) {
-	query := `SELECT posts.id, posts.user_id, posts.group_id, posts.text, posts.visibility, posts.media_path, posts.created_at
+	query := `SELECT posts.id, posts.user_id, posts.group_id, posts.text, posts.visibility, posts.media_path, posts.audience_list_id, posts.edited_at, posts.created_at
 		FROM posts
 		JOIN group_members ON group_members.group_id = posts.group_id AND group_members.user_id = ?
-		WHERE posts.group_id = ?
+		WHERE posts.group_id = ? AND NOT ` + blockedWith("posts.user_id") + `
 		ORDER BY posts.created_at DESC
 		LIMIT ? OFFSET ?`
-	rows, err := db.QueryContext(ctx, query, userID, groupID, limit, offset)
+	rows, err := db.QueryContext(ctx, query, userID, groupID, userID, userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	return scanPosts(rows)
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return posts, LoadPostReactions(ctx, db, userID, posts)
 }
 
 func CanViewPost(ctx context.Context, db *sql.DB, viewerID, postID int64) (bool, error) {
 	query := `SELECT posts.user_id, posts.group_id, posts.visibility,
 		(SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = posts.user_id LIMIT 1) AS follows,
-		(SELECT 1 FROM post_allowed WHERE user_id = ? AND post_id = posts.id LIMIT 1) AS allowed,
-		(SELECT 1 FROM group_members WHERE user_id = ? AND group_id = posts.group_id LIMIT 1) AS member
+		(EXISTS (SELECT 1 FROM post_allowed WHERE user_id = ? AND post_id = posts.id) OR ` + inAudienceList + `) AS allowed,
+		(SELECT 1 FROM group_members WHERE user_id = ? AND group_id = posts.group_id LIMIT 1) AS member,
+		` + blockedWith("posts.user_id") + ` AS blocked
 		FROM posts WHERE posts.id = ?`
 	var authorID int64
 	var groupID sql.NullInt64
 	var visibility string
 	var follows sql.NullInt64
-	var allowed sql.NullInt64
+	var allowed bool
 	var member sql.NullInt64
-	row := db.QueryRowContext(ctx, query, viewerID, viewerID, viewerID, postID)
-	if err := row.Scan(&authorID, &groupID, &visibility, &follows, &allowed, &member); err != nil {
+	var blocked bool
+	row := db.QueryRowContext(ctx, query, viewerID, viewerID, viewerID, viewerID, viewerID, viewerID, postID)
+	if err := row.Scan(&authorID, &groupID, &visibility, &follows, &allowed, &member, &blocked); err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil
 		}
 		return false, err
 	}
 
+	if blocked {
+		return false, nil
+	}
 	if groupID.Valid {
 		return member.Valid, nil
 	}
@@ -148,7 +191,7 @@ func CanViewPost(ctx context.Context, db *sql.DB, viewerID, postID int64) (bool,
 	case "followers":
 		return follows.Valid, nil
 	case "private":
-		return allowed.Valid, nil
+		return allowed, nil
 	default:
 		return false, nil
 	}
@@ -179,14 +222,110 @@ func scanPosts(rows *sql.Rows) ([]Post, error) {
 		var post Post
 		var groupID sql.NullInt64
 		var media sql.NullString
-		if err := rows.Scan(&post.ID, &post.UserID, &groupID, &post.Text, &post.Visibility, &media, &post.CreatedAt); err != nil {
+		var audienceListID sql.NullInt64
+		var editedAt sql.NullString
+		if err := rows.Scan(&post.ID, &post.UserID, &groupID, &post.Text, &post.Visibility, &media, &audienceListID, &editedAt, &post.CreatedAt); err != nil {
 			return nil, err
 		}
 		if groupID.Valid {
 			post.GroupID = &groupID.Int64
 		}
+		if audienceListID.Valid {
+			post.AudienceListID = &audienceListID.Int64
+		}
 		post.MediaPath = nullableStringPtr(media)
+		post.EditedAt = nullableStringPtr(editedAt)
 		posts = append(posts, post)
 	}
 	return posts, rows.Err()
 }
+
+// PostEdit is the new state of an edited post. The visibility, allowed users
+// and audience list are only written when ReplaceAudience is set.
+type PostEdit struct {
+	Text            string
+	MediaPath       *string
+	Visibility      string
+	ReplaceAudience bool
+	AllowedIDs      []int64
+	AudienceListID  *int64
+}
+
+// PostRevision is a version of a post that a later edit replaced.
+type PostRevision struct {
+	ID         int64   `json:"id"`
+	Text       string  `json:"text"`
+	Visibility string  `json:"visibility"`
+	MediaPath  *string `json:"media_path,omitempty"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// UpdatePost stores the post's current version as a revision, then applies
+// the edit and sets edited_at.
+func UpdatePost(ctx context.Context, db *sql.DB, postID int64, edit PostEdit) (Post, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Post{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO post_revisions (post_id, text, visibility, media_path, created_at)
+		SELECT id, text, visibility, media_path, COALESCE(edited_at, created_at) FROM posts WHERE id = ?`, postID); err != nil {
+		return Post{}, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE posts SET text = ?, media_path = ?, edited_at = CURRENT_TIMESTAMP WHERE id = ?",
+		edit.Text, nullableString(edit.MediaPath), postID); err != nil {
+		return Post{}, err
+	}
+	if edit.ReplaceAudience {
+		if _, err := tx.ExecContext(ctx, "UPDATE posts SET visibility = ?, audience_list_id = ? WHERE id = ?", edit.Visibility, edit.AudienceListID, postID); err != nil {
+			return Post{}, err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM post_allowed WHERE post_id = ?", postID); err != nil {
+			return Post{}, err
+		}
+		if edit.Visibility == "private" {
+			for _, id := range edit.AllowedIDs {
+				if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO post_allowed (user_id, post_id) VALUES (?, ?)", id, postID); err != nil {
+					return Post{}, err
+				}
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return Post{}, err
+	}
+	return GetPostByID(ctx, db, postID)
+}
+
+// DeletePost removes a post along with its comments and revisions.
+func DeletePost(ctx context.Context, db *sql.DB, postID int64) (bool, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM posts WHERE id = ?", postID)
+	if err != nil {
+		return false, err
+	}
+	deleted, _ := result.RowsAffected()
+	return deleted > 0, nil
+}
+
+// ListPostRevisions returns the earlier versions of a post, newest first.
+func ListPostRevisions(ctx context.Context, db *sql.DB, postID int64) ([]PostRevision, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, text, visibility, media_path, created_at FROM post_revisions
+		WHERE post_id = ? ORDER BY id DESC`, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []PostRevision{}
+	for rows.Next() {
+		var revision PostRevision
+		var media sql.NullString
+		if err := rows.Scan(&revision.ID, &revision.Text, &revision.Visibility, &media, &revision.CreatedAt); err != nil {
+			return nil, err
+		}
+		revision.MediaPath = nullableStringPtr(media)
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// PresenceRecord is the stored part of a user's presence. Whether they are
+// online is only known to the websocket hub.
+type PresenceRecord struct {
+	UserID          int64
+	Visibility      string
+	NowPlaying      *string
+	NowPlayingSince *string
+	LastSeenAt      *string
+}
+
+// presenceVisibleTo is a condition that holds when the user whose ID is the
+// viewer expression may see the presence of users.id, with user_presence
+// left joined on it. Users always see their own; blocks and private
+// presence hide it; followers presence shows to followers only, and public
+// presence also to group co-members and to anyone when the profile is public.
+func presenceVisibleTo(viewer string) string {
+	follows := `EXISTS (SELECT 1 FROM follows WHERE follower_id = ` + viewer + ` AND followee_id = users.id)`
+	return `(users.id = ` + viewer + ` OR (
+		NOT EXISTS (SELECT 1 FROM user_blocks
+			WHERE (blocker_id = ` + viewer + ` AND blocked_id = users.id) OR (blocker_id = users.id AND blocked_id = ` + viewer + `))
+		AND CASE COALESCE(user_presence.visibility, 'public')
+			WHEN 'public' THEN users.is_public = 1 OR ` + follows + ` OR EXISTS (
+				SELECT 1 FROM group_members AS mine
+				JOIN group_members AS theirs ON theirs.group_id = mine.group_id
+				WHERE mine.user_id = ` + viewer + ` AND theirs.user_id = users.id)
+			WHEN 'followers' THEN ` + follows + `
+			ELSE 0 END))`
+}
+
+// ListPresence returns the presence records of the users in ids that
+// viewerID may see; the others are left out.
+func ListPresence(ctx context.Context, db *sql.DB, viewerID int64, ids []int64) ([]PresenceRecord, error) {
+	records := []PresenceRecord{}
+	if len(ids) == 0 {
+		return records, nil
+	}
+	args := []any{viewerID}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := db.QueryContext(ctx, `WITH viewer(id) AS (SELECT ?)
+		SELECT users.id, COALESCE(user_presence.visibility, 'public'), user_presence.now_playing,
+			user_presence.now_playing_since, user_presence.last_seen_at
+		FROM users
+		CROSS JOIN viewer
+		LEFT JOIN user_presence ON user_presence.user_id = users.id
+		WHERE users.id IN (?`+strings.Repeat(", ?", len(ids)-1)+`)
+			AND users.deletion_scheduled_at IS NULL AND `+presenceVisibleTo("viewer.id")+`
+		ORDER BY users.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var record PresenceRecord
+		var nowPlaying, since, lastSeen sql.NullString
+		if err := rows.Scan(&record.UserID, &record.Visibility, &nowPlaying, &since, &lastSeen); err != nil {
+			return nil, err
+		}
+		record.NowPlaying = nullableStringPtr(nowPlaying)
+		record.NowPlayingSince = nullableStringPtr(since)
+		record.LastSeenAt = nullableStringPtr(lastSeen)
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// GetPresence returns a user's own presence record.
+func GetPresence(ctx context.Context, db *sql.DB, userID int64) (PresenceRecord, error) {
+	records, err := ListPresence(ctx, db, userID, []int64{userID})
+	if err != nil {
+		return PresenceRecord{}, err
+	}
+	if len(records) == 0 {
+		return PresenceRecord{UserID: userID, Visibility: "public"}, nil
+	}
+	return records[0], nil
+}
+
+// ListPresenceAudience returns the followers and group co-members of userID
+// who may see their presence, the people presence changes are pushed to.
+func ListPresenceAudience(ctx context.Context, db *sql.DB, userID int64) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, `WITH viewers(id) AS (
+			SELECT follower_id FROM follows WHERE followee_id = ?
+			UNION
+			SELECT theirs.user_id FROM group_members AS mine
+			JOIN group_members AS theirs ON theirs.group_id = mine.group_id
+			WHERE mine.user_id = ? AND theirs.user_id != mine.user_id
+		)
+		SELECT viewers.id
+		FROM viewers
+		JOIN users ON users.id = ?
+		LEFT JOIN user_presence ON user_presence.user_id = users.id
+		WHERE `+presenceVisibleTo("viewers.id")+`
+		ORDER BY viewers.id`, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func SetPresenceVisibility(ctx context.Context, db *sql.DB, userID int64, visibility string) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO user_presence (user_id, visibility) VALUES (?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET visibility = excluded.visibility`, userID, visibility)
+	return err
+}
+
+// SetNowPlaying records the game the user is playing; an empty game clears
+// it. The start time is kept while the game stays the same.
+func SetNowPlaying(ctx context.Context, db *sql.DB, userID int64, game string) error {
+	now := sqliteTime(time.Now())
+	_, err := db.ExecContext(ctx, `INSERT INTO user_presence (user_id, now_playing, now_playing_since)
+		VALUES (?, NULLIF(?, ''), CASE WHEN ? = '' THEN NULL ELSE ? END)
+		ON CONFLICT (user_id) DO UPDATE SET
+			now_playing_since = CASE
+				WHEN excluded.now_playing IS NULL THEN NULL
+				WHEN user_presence.now_playing IS excluded.now_playing THEN user_presence.now_playing_since
+				ELSE excluded.now_playing_since END,
+			now_playing = excluded.now_playing`, userID, game, game, now)
+	return err
+}
+
+// TouchLastSeen stamps the user as seen now. When offline is set their now
+// playing status is cleared too, as it only lasts while they are connected.
+func TouchLastSeen(ctx context.Context, db *sql.DB, userID int64, offline bool) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO user_presence (user_id, last_seen_at) VALUES (?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			last_seen_at = excluded.last_seen_at,
+			now_playing = CASE WHEN ? THEN NULL ELSE user_presence.now_playing END,
+			now_playing_since = CASE WHEN ? THEN NULL ELSE user_presence.now_playing_since END`,
+		userID, sqliteTime(time.Now()), offline, offline)
+	return err
+}
@@ -0,0 +1,205 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// itemTable names a table of rows attached to one kind of item, such as
+// posts or comments, and its column pointing at the item.
+type itemTable struct {
+	table  string
+	column string
+}
+
+var (
+	postReactions         = itemTable{table: "post_reactions", column: "post_id"}
+	commentReactions      = itemTable{table: "comment_reactions", column: "comment_id"}
+	dmReactions           = itemTable{table: "dm_message_reactions", column: "message_id"}
+	groupMessageReactions = itemTable{table: "group_message_reactions", column: "message_id"}
+)
+
+// Reactor is someone who reacted, with their reaction.
+type Reactor struct {
+	UserCard
+	Emoji     string `json:"emoji"`
+	CreatedAt string `json:"reacted_at"`
+}
+
+func SetPostReaction(ctx context.Context, db *sql.DB, postID, userID int64, emoji string) error {
+	return setReaction(ctx, db, postReactions, postID, userID, emoji)
+}
+
+func RemovePostReaction(ctx context.Context, db *sql.DB, postID, userID int64) error {
+	return removeReaction(ctx, db, postReactions, postID, userID)
+}
+
+func SetCommentReaction(ctx context.Context, db *sql.DB, commentID, userID int64, emoji string) error {
+	return setReaction(ctx, db, commentReactions, commentID, userID, emoji)
+}
+
+func RemoveCommentReaction(ctx context.Context, db *sql.DB, commentID, userID int64) error {
+	return removeReaction(ctx, db, commentReactions, commentID, userID)
+}
+
+// setReaction adds userID's reaction to the item, replacing any earlier one.
+func setReaction(ctx context.Context, db *sql.DB, target itemTable, itemID, userID int64, emoji string) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO `+target.table+` (`+target.column+`, user_id, emoji) VALUES (?, ?, ?)
+		ON CONFLICT (`+target.column+`, user_id) DO UPDATE SET emoji = excluded.emoji, created_at = CURRENT_TIMESTAMP`,
+		itemID, userID, emoji)
+	return err
+}
+
+func removeReaction(ctx context.Context, db *sql.DB, target itemTable, itemID, userID int64) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM "+target.table+" WHERE "+target.column+" = ? AND user_id = ?", itemID, userID)
+	return err
+}
+
+// SetMessageReaction is SetPostReaction for a direct or group message.
+func SetMessageReaction(ctx context.Context, db *sql.DB, kind MessageKind, messageID, userID int64, emoji string) error {
+	return setReaction(ctx, db, kind.reactions(), messageID, userID, emoji)
+}
+
+func RemoveMessageReaction(ctx context.Context, db *sql.DB, kind MessageKind, messageID, userID int64) error {
+	return removeReaction(ctx, db, kind.reactions(), messageID, userID)
+}
+
+// LoadMessageReactions returns the reaction counts of one message and the
+// viewer's own reaction to it.
+func LoadMessageReactions(ctx context.Context, db *sql.DB, kind MessageKind, viewerID, messageID int64) (map[string]int, *string, error) {
+	counts, mine, err := loadReactions(ctx, db, kind.reactions(), viewerID, []int64{messageID})
+	if err != nil {
+		return nil, nil, err
+	}
+	return counts[messageID], mine[messageID], nil
+}
+
+// LoadPostReactions fills in the reaction counts of posts and the viewer's
+// own reaction to each.
+func LoadPostReactions(ctx context.Context, db *sql.DB, viewerID int64, posts []Post) error {
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	counts, mine, err := loadReactions(ctx, db, postReactions, viewerID, ids)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Reactions = counts[posts[i].ID]
+		posts[i].MyReaction = mine[posts[i].ID]
+	}
+	return nil
+}
+
+// LoadCommentReactions is LoadPostReactions for comments.
+func LoadCommentReactions(ctx context.Context, db *sql.DB, viewerID int64, comments []Comment) error {
+	ids := make([]int64, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+	counts, mine, err := loadReactions(ctx, db, commentReactions, viewerID, ids)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].Reactions = counts[comments[i].ID]
+		comments[i].MyReaction = mine[comments[i].ID]
+	}
+	return nil
+}
+
+// loadReactions counts the reactions to each item by emoji and finds the
+// viewer's own. Every item gets a counts map, empty when nobody reacted.
+func loadReactions(ctx context.Context, db *sql.DB, target itemTable, viewerID int64, ids []int64) (map[int64]map[string]int, map[int64]*string, error) {
+	counts := make(map[int64]map[string]int, len(ids))
+	mine := make(map[int64]*string)
+	if len(ids) == 0 {
+		return counts, mine, nil
+	}
+	placeholders := make([]string, len(ids))
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, viewerID)
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args = append(args, id)
+		counts[id] = map[string]int{}
+	}
+	rows, err := db.QueryContext(ctx, `SELECT `+target.column+`, emoji, COUNT(*), MAX(user_id = ?)
+		FROM `+target.table+`
+		WHERE `+target.column+` IN (`+strings.Join(placeholders, ",")+`)
+		GROUP BY `+target.column+`, emoji`, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var itemID int64
+		var emoji string
+		var count int
+		var viewer bool
+		if err := rows.Scan(&itemID, &emoji, &count, &viewer); err != nil {
+			return nil, nil, err
+		}
+		counts[itemID][emoji] = count
+		if viewer {
+			mine[itemID] = &emoji
+		}
+	}
+	return counts, mine, rows.Err()
+}
+
+// ListPostReactions lists who reacted to a post, newest first, optionally
+// only with emoji. Users the viewer blocked or was blocked by are left out.
+func ListPostReactions(ctx context.Context, db *sql.DB, viewerID, postID int64, emoji string, limit, offset int) ([]Reactor, error) {
+	return listReactions(ctx, db, postReactions, viewerID, postID, emoji, limit, offset)
+}
+
+func ListCommentReactions(ctx context.Context, db *sql.DB, viewerID, commentID int64, emoji string, limit, offset int) ([]Reactor, error) {
+	return listReactions(ctx, db, commentReactions, viewerID, commentID, emoji, limit, offset)
+}
+
+func ListMessageReactions(ctx context.Context, db *sql.DB, kind MessageKind, viewerID, messageID int64, emoji string, limit, offset int) ([]Reactor, error) {
+	return listReactions(ctx, db, kind.reactions(), viewerID, messageID, emoji, limit, offset)
+}
+
+func listReactions(ctx context.Context, db *sql.DB, target itemTable, viewerID, itemID int64, emoji string, limit, offset int) ([]Reactor, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+userCardColumns+`, reactions.emoji, reactions.created_at
+		FROM `+target.table+` AS reactions
+		JOIN users ON users.id = reactions.user_id
+		WHERE reactions.`+target.column+` = ? AND (? = '' OR reactions.emoji = ?)
+			AND users.deletion_scheduled_at IS NULL
+			AND NOT `+blockedWith("users.id")+`
+		ORDER BY reactions.created_at DESC, users.id ASC
+		LIMIT ? OFFSET ?`, viewerID, viewerID, itemID, emoji, emoji, viewerID, viewerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactors := []Reactor{}
+	for rows.Next() {
+		var reactor Reactor
+		card, err := scanUserCard(rows, &reactor.Emoji, &reactor.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		reactor.UserCard = card
+		reactors = append(reactors, reactor)
+	}
+	return reactors, rows.Err()
+}
+
+// NotifyPostReaction tells a post's author about a reaction, unless they
+// already got a reaction notification for that post since since.
+func NotifyPostReaction(ctx context.Context, db *sql.DB, authorID, postID int64, payload string, since time.Time) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO notifications (user_id, type, payload_json, is_read)
+		SELECT ?, 'post_reaction', ?, 0
+		WHERE NOT EXISTS (SELECT 1 FROM notifications
+			WHERE user_id = ? AND type = 'post_reaction' AND created_at > ?
+				AND json_valid(payload_json) AND json_extract(payload_json, '$.post_id') = ?)`,
+		authorID, payload, authorID, sqliteTime(since), postID)
+	return err
+}
@@ -0,0 +1,166 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode"
+)
+
+// UserCard is the compact profile shown in search results. Limited cards
+// belong to private profiles the viewer does not follow and only carry
+// what is needed to send a follow request.
+type UserCard struct {
+	ID        int64   `json:"id"`
+	Handle    string  `json:"handle"`
+	Nickname  *string `json:"nickname"`
+	FirstName string  `json:"first_name,omitempty"`
+	LastName  string  `json:"last_name,omitempty"`
+	Avatar    *string `json:"avatar_path"`
+	About     *string `json:"about,omitempty"`
+	IsPublic  bool    `json:"is_public"`
+	Limited   bool    `json:"limited"`
+}
+
+// maxSearchTerms caps how many words of a query reach the FTS index.
+const maxSearchTerms = 8
+
+// UserSearchFilter narrows a search to players of a game, by slug, or with
+// an account on a platform.
+type UserSearchFilter struct {
+	Game     string
+	Platform string
+}
+
+// SearchUsers ranks users whose handle, nickname or names start with the words in
+// query, best match first. Names only match on profiles the viewer may see. An email address only matches exactly, so the
+// directory cannot be used to enumerate addresses. With a filter the query
+// may be empty, and every matching user is listed by ID. Games only match on
+// profiles the viewer may see, and platforms only on accounts they may see.
+func SearchUsers(ctx context.Context, db *sql.DB, viewerID int64, query string, filter UserSearchFilter, limit, offset int) ([]UserCard, error) {
+	var ctes, branches []string
+	var cteArgs, branchArgs []any
+	if strings.TrimSpace(query) == "" && filter != (UserSearchFilter{}) {
+		branches = append(branches, "SELECT id, 0 AS rank FROM users")
+	}
+	if match := ftsQuery(query); match != "" {
+		// bm25 only works where the FTS table is queried directly, so the
+		// match is materialized before it is combined with the email branch.
+		// Names are hidden on limited cards, so those profiles only match
+		// on handle and nickname.
+		ctes = append(ctes,
+			"fts AS MATERIALIZED (SELECT rowid AS id, bm25(users_fts, 10.0, 10.0, 4.0, 4.0) AS rank FROM users_fts WHERE users_fts MATCH ?)",
+			"fts_limited AS MATERIALIZED (SELECT rowid AS id, bm25(users_fts, 10.0, 10.0, 4.0, 4.0) AS rank FROM users_fts WHERE users_fts MATCH ?)")
+		branches = append(branches,
+			"SELECT fts.id, fts.rank FROM fts JOIN users ON users.id = fts.id WHERE "+profileVisible,
+			"SELECT id, rank FROM fts_limited")
+		cteArgs = append(cteArgs, match, limitedFTSQuery(match))
+		branchArgs = append(branchArgs, viewerID, viewerID)
+	}
+	if email := strings.TrimSpace(query); strings.Contains(email, "@") {
+		// bm25 scores are negative; an exact email hit outranks them all.
+		branches = append(branches, "SELECT id, -1e9 AS rank FROM users WHERE lower(email) = lower(?)")
+		branchArgs = append(branchArgs, email)
+	}
+	if len(branches) == 0 {
+		return []UserCard{}, nil
+	}
+	with := ""
+	if len(ctes) > 0 {
+		with = "WITH " + strings.Join(ctes, ", ") + " "
+	}
+
+	where := "users.deletion_scheduled_at IS NULL AND NOT " + blockedWith("users.id")
+	whereArgs := []any{viewerID, viewerID}
+	if filter.Game != "" {
+		where += ` AND EXISTS (SELECT 1 FROM user_games WHERE user_games.user_id = users.id AND user_games.game_slug = ?)
+			AND ` + profileVisible
+		whereArgs = append(whereArgs, filter.Game, viewerID, viewerID)
+	}
+	if filter.Platform != "" {
+		where += ` AND EXISTS (SELECT 1 FROM user_platform_accounts
+			WHERE user_platform_accounts.user_id = users.id AND user_platform_accounts.platform = ? AND ` + platformVisible + `)`
+		whereArgs = append(whereArgs, filter.Platform, viewerID, viewerID)
+	}
+
+	args := append(append(append(append(cteArgs, viewerID, viewerID), branchArgs...), whereArgs...), limit, offset)
+	rows, err := db.QueryContext(ctx, with+`SELECT `+userCardColumns+`
+		FROM (SELECT id, MIN(rank) AS rank FROM (`+strings.Join(branches, " UNION ALL ")+`) GROUP BY id) AS matches
+		JOIN users ON users.id = matches.id
+		WHERE `+where+`
+		ORDER BY matches.rank ASC, users.id ASC
+		LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := []UserCard{}
+	for rows.Next() {
+		card, err := scanUserCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+// userCardColumns are read by scanUserCard. They take the viewer ID twice,
+// for the visibility check.
+const userCardColumns = `users.id, users.handle, users.nickname, users.first_name, users.last_name, users.avatar_path,
+	users.about, users.is_public,
+	users.id = ? OR EXISTS (SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = users.id)`
+
+// profileVisible holds when the viewer may see the whole profile of
+// users.id. It takes the viewer ID twice.
+const profileVisible = `(users.is_public = 1 OR users.id = ? OR EXISTS (SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = users.id))`
+
+// scanUserCard reads userCardColumns followed by any extra columns, and
+// limits the card when the viewer may not see the profile.
+func scanUserCard(row rowScanner, extra ...any) (UserCard, error) {
+	var card UserCard
+	var handle, nickname, avatar, about sql.NullString
+	var isPublic int
+	var visible bool
+	dest := append([]any{&card.ID, &handle, &nickname, &card.FirstName, &card.LastName, &avatar, &about, &isPublic, &visible}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return UserCard{}, err
+	}
+	card.Handle = handle.String
+	card.Nickname = nullableStringPtr(nickname)
+	card.Avatar = nullableStringPtr(avatar)
+	card.IsPublic = isPublic == 1
+	if card.IsPublic || visible {
+		card.About = nullableStringPtr(about)
+	} else {
+		card.FirstName, card.LastName = "", ""
+		card.Limited = true
+	}
+	return card, nil
+}
+
+// ftsQuery turns free text into an FTS5 query that prefix-matches every
+// word. Each word is quoted so FTS5 operators typed by the user are taken
+// literally; words without letters or digits would tokenize to nothing and
+// are dropped.
+func ftsQuery(query string) string {
+	words := strings.Fields(query)
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
+	}
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if strings.IndexFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+			continue
+		}
+		word = strings.ReplaceAll(word, `"`, `""`)
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// limitedFTSQuery narrows an ftsQuery to the columns shown on limited cards.
+func limitedFTSQuery(match string) string {
+	return "{handle nickname} : (" + match + ")"
+}
@@ -5,21 +5,48 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
+	"strings"
 	"time"
 )
 
-func CreateSession(ctx context.Context, db *sql.DB, userID int64, ttl time.Duration) (string, time.Time, error) {
+type Session struct {
+	ID         string  `json:"id"`
+	UserAgent  *string `json:"user_agent"`
+	IP         *string `json:"ip"`
+	CreatedAt  string  `json:"created_at"`
+	LastSeenAt string  `json:"last_seen_at"`
+	ExpiresAt  string  `json:"expires_at"`
+	Current    bool    `json:"current"`
+}
+
+// CreateSession issues a session that expires after idleTTL of inactivity
+// and never outlives absoluteTTL, however often it is refreshed.
+func CreateSession(ctx context.Context, db *sql.DB, userID int64, idleTTL, absoluteTTL time.Duration, userAgent, ip string) (string, time.Time, error) {
 	token, err := randomToken()
 	if err != nil {
 		return "", time.Time{}, err
 	}
-	expiresAt := time.Now().Add(ttl).UTC()
+	publicID, err := randomID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now().UTC()
+	absoluteAt := now.Add(absoluteTTL)
+	expiresAt := now.Add(idleTTL)
+	if absoluteAt.Before(expiresAt) {
+		expiresAt = absoluteAt
+	}
 	_, err = db.ExecContext(
 		ctx,
-		"INSERT INTO sessions (token, user_id, expires_at) VALUES (?, ?, ?)",
+		`INSERT INTO sessions (token, public_id, user_id, user_agent, ip, expires_at, absolute_expires_at, last_seen_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
 		token,
+		publicID,
 		userID,
-		expiresAt,
+		nullableString(&userAgent),
+		nullableString(&ip),
+		sqliteTime(expiresAt),
+		sqliteTime(absoluteAt),
 	)
 	if err != nil {
 		return "", time.Time{}, err
@@ -28,45 +55,205 @@ func CreateSession(ctx context.Context, db *sql.DB, userID int64, ttl time.Durat
 }
 
 func DeleteSession(ctx context.Context, db *sql.DB, token string) error {
-	_, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE token = ?", token)
+	_, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE token = ? OR previous_token = ?", token, token)
 	return err
 }
 
-func GetSessionUser(ctx context.Context, db *sql.DB, token string) (UserProfile, bool, error) {
+func DeleteExpiredSessions(ctx context.Context, db *sql.DB) (int64, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
+
+func GetSessionUser(ctx context.Context, db *sql.DB, token string) (UserProfile, string, bool, error) {
 	var profile UserProfile
+	var sessionID string
+	var handle sql.NullString
+	var dob sql.NullString
 	var avatar sql.NullString
 	var nickname sql.NullString
 	var about sql.NullString
 	var isPublic int
 
-	row := db.QueryRowContext(ctx, `SELECT users.id, users.email, users.first_name, users.last_name, users.dob,
-		users.avatar_path, users.nickname, users.about, users.is_public, users.created_at
+	row := db.QueryRowContext(ctx, `SELECT sessions.public_id, users.id, users.email, users.first_name, users.last_name, users.handle, users.dob,
+		users.avatar_path, users.nickname, users.about, users.is_public, users.email_verified_at IS NOT NULL, users.created_at
 		FROM sessions
 		JOIN users ON users.id = sessions.user_id
-		WHERE sessions.token = ? AND sessions.expires_at > CURRENT_TIMESTAMP`, token)
+		WHERE (sessions.token = ? OR (sessions.previous_token = ? AND sessions.rotated_at > datetime('now', ?)))
+			AND sessions.expires_at > CURRENT_TIMESTAMP`, token, token, rotationGraceModifier)
 	if err := row.Scan(
+		&sessionID,
 		&profile.ID,
 		&profile.Email,
 		&profile.FirstName,
 		&profile.LastName,
-		&profile.DOB,
+		&handle,
+		&dob,
 		&avatar,
 		&nickname,
 		&about,
 		&isPublic,
+		&profile.EmailVerified,
 		&profile.CreatedAt,
 	); err != nil {
 		if err == sql.ErrNoRows {
-			return UserProfile{}, false, nil
+			return UserProfile{}, "", false, nil
 		}
-		return UserProfile{}, false, err
+		return UserProfile{}, "", false, err
 	}
 
+	profile.Handle = handle.String
+	profile.DOB = strings.TrimSpace(dob.String)
 	profile.Avatar = nullableStringPtr(avatar)
 	profile.Nickname = nullableStringPtr(nickname)
 	profile.About = nullableStringPtr(about)
 	profile.IsPublic = isPublic == 1
-	return profile, true, nil
+	return profile, sessionID, true, nil
+}
+
+// rotationGraceModifier keeps a rotated-out token valid briefly so requests
+// already in flight with the old cookie are not logged out.
+const rotationGraceModifier = "-1 minute"
+
+// RefreshSession slides the session expiry forward by idleTTL (capped at the
+// absolute lifetime), records the latest client details and, once the token
+// is older than rotateEvery, swaps in a fresh token. Writes are throttled to
+// one per minute; changed reports whether the caller must reissue the cookie.
+func RefreshSession(ctx context.Context, db *sql.DB, token, userAgent, ip string, idleTTL, rotateEvery time.Duration) (string, time.Time, bool, error) {
+	var absolute sql.NullString
+	var lastSeen string
+	var issuedAt string
+	row := db.QueryRowContext(ctx, `SELECT absolute_expires_at, COALESCE(last_seen_at, created_at), COALESCE(rotated_at, created_at)
+		FROM sessions WHERE token = ?`, token)
+	if err := row.Scan(&absolute, &lastSeen, &issuedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return token, time.Time{}, false, nil
+		}
+		return token, time.Time{}, false, err
+	}
+
+	now := time.Now().UTC()
+	rotate := rotateEvery > 0 && parseSQLiteTime(issuedAt).Add(rotateEvery).Before(now)
+	if !rotate && parseSQLiteTime(lastSeen).Add(time.Minute).After(now) {
+		return token, time.Time{}, false, nil
+	}
+
+	expiresAt := now.Add(idleTTL)
+	if absolute.Valid {
+		if absoluteAt := parseSQLiteTime(absolute.String); !absoluteAt.IsZero() && absoluteAt.Before(expiresAt) {
+			expiresAt = absoluteAt
+		}
+	}
+
+	newToken := token
+	query := `UPDATE sessions SET expires_at = ?, last_seen_at = CURRENT_TIMESTAMP, user_agent = ?, ip = ? WHERE token = ?`
+	args := []any{sqliteTime(expiresAt), nullableString(&userAgent), nullableString(&ip), token}
+	if rotate {
+		var err error
+		newToken, err = randomToken()
+		if err != nil {
+			return token, time.Time{}, false, err
+		}
+		query = `UPDATE sessions SET token = ?, previous_token = token, rotated_at = CURRENT_TIMESTAMP,
+			expires_at = ?, last_seen_at = CURRENT_TIMESTAMP, user_agent = ?, ip = ? WHERE token = ?`
+		args = append([]any{newToken}, args...)
+	}
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return token, time.Time{}, false, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		// Another request rotated the token first.
+		return token, time.Time{}, false, nil
+	}
+	return newToken, expiresAt, true, nil
+}
+
+func ListSessions(ctx context.Context, db *sql.DB, userID int64) ([]Session, error) {
+	rows, err := db.QueryContext(ctx, `SELECT public_id, user_agent, ip, created_at, COALESCE(last_seen_at, created_at), expires_at
+		FROM sessions
+		WHERE user_id = ? AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY COALESCE(last_seen_at, created_at) DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, session)
+	}
+	return list, rows.Err()
+}
+
+func GetSession(ctx context.Context, db *sql.DB, userID int64, sessionID string) (Session, bool, error) {
+	row := db.QueryRowContext(ctx, `SELECT public_id, user_agent, ip, created_at, COALESCE(last_seen_at, created_at), expires_at
+		FROM sessions
+		WHERE user_id = ? AND public_id = ? AND expires_at > CURRENT_TIMESTAMP`, userID, sessionID)
+	session, err := scanSession(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Session{}, false, nil
+		}
+		return Session{}, false, err
+	}
+	return session, true, nil
+}
+
+// IsRecentSession reports whether the session was created within the last
+// window, i.e. the user has just signed in.
+func IsRecentSession(ctx context.Context, db *sql.DB, userID int64, sessionID string, window time.Duration) (bool, error) {
+	var exists int
+	row := db.QueryRowContext(ctx, `SELECT 1 FROM sessions
+		WHERE user_id = ? AND public_id = ? AND created_at > ? AND expires_at > CURRENT_TIMESTAMP`,
+		userID, sessionID, sqliteTime(time.Now().Add(-window)))
+	if err := row.Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func DeleteSessionByID(ctx context.Context, db *sql.DB, userID int64, sessionID string) (bool, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ? AND public_id = ?", userID, sessionID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func DeleteOtherSessions(ctx context.Context, db *sql.DB, userID int64, keepSessionID string) (int64, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ? AND public_id <> ?", userID, keepSessionID)
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSession(row rowScanner) (Session, error) {
+	var session Session
+	var userAgent sql.NullString
+	var ip sql.NullString
+	if err := row.Scan(&session.ID, &userAgent, &ip, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+		return Session{}, err
+	}
+	session.UserAgent = nullableStringPtr(userAgent)
+	session.IP = nullableStringPtr(ip)
+	return session, nil
 }
 
 func randomToken() (string, error) {
@@ -76,3 +263,11 @@ func randomToken() (string, error) {
 	}
 	return hex.EncodeToString(buf), nil
 }
+
+func randomID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"os"
 	"path/filepath"
 	"time"
@@ -15,15 +16,13 @@ func Open(path string) (*sql.DB, error) {
 		return nil, err
 	}
 
-	db, err := sql.Open("sqlite3", path)
+	// Foreign keys are enabled through the DSN so every pooled connection
+	// gets them; a PRAGMA would only reach the first one and ON DELETE
+	// CASCADE would silently not run elsewhere.
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on")
 	if err != nil {
 		return nil, err
 	}
-
-	if _, err := db.Exec("PRAGMA foreign_keys = ON;"); err != nil {
-		_ = db.Close()
-		return nil, err
-	}
 	if _, err := db.Exec("PRAGMA journal_mode = WAL;"); err != nil {
 		_ = db.Close()
 		return nil, err
@@ -33,10 +32,29 @@ func Open(path string) (*sql.DB, error) {
 		_ = db.Close()
 		return nil, err
 	}
+	if err := requireFTS5(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 
 	return db, nil
 }
 
+var errNoFTS5 = errors.New("sqlite was built without FTS5, which the search index needs: build and test with -tags sqlite_fts5")
+
+// requireFTS5 fails fast when the binary was built without the sqlite_fts5
+// tag, rather than with "no such module: fts5" halfway through migrating.
+func requireFTS5(db *sql.DB) error {
+	var enabled bool
+	if err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled); err != nil {
+		return err
+	}
+	if !enabled {
+		return errNoFTS5
+	}
+	return nil
+}
+
 func ensureDir(path string) error {
 	if path == "." || path == "/" || path == "" {
 		return nil
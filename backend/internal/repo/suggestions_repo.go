@@ -0,0 +1,169 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Suggestion is a user card with the reasons it was suggested.
+type Suggestion struct {
+	UserCard
+	MutualFollows int `json:"mutual_follows"`
+	SharedGroups  int `json:"shared_groups"`
+	SharedEvents  int `json:"shared_events"`
+	SharedGames   int `json:"shared_games"`
+}
+
+// notConnected is a condition that holds when the user in column is
+// neither followed by, requested by nor blocked with the user in owner.
+func notConnected(owner, column string) string {
+	return `NOT EXISTS (SELECT 1 FROM follows WHERE follower_id = ` + owner + ` AND followee_id = ` + column + `)
+		AND NOT EXISTS (SELECT 1 FROM follow_requests WHERE status = 'pending'
+			AND ((from_user_id = ` + owner + ` AND to_user_id = ` + column + `) OR (from_user_id = ` + column + ` AND to_user_id = ` + owner + `)))
+		AND NOT EXISTS (SELECT 1 FROM user_blocks
+			WHERE (blocker_id = ` + owner + ` AND blocked_id = ` + column + `) OR (blocker_id = ` + column + ` AND blocked_id = ` + owner + `))`
+}
+
+// suggestionsBatch is how many users RefreshSuggestions handles per
+// transaction, so the write lock is never held for the whole rebuild.
+const suggestionsBatch = 100
+
+// RefreshSuggestions recomputes everyone's follow suggestions, keeping the
+// best perUser of each. Candidates are scored by the people the user follows
+// who follow them, then by shared groups, events both are going to, and
+// favorite games in common; games only count where the user may see the
+// candidate's profile. Users are refreshed a batch at a time; it returns how
+// many suggestions were stored.
+func RefreshSuggestions(ctx context.Context, db *sql.DB, perUser int) (int64, error) {
+	var stored int64
+	var after int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return stored, err
+		}
+		var last sql.NullInt64
+		if err := db.QueryRowContext(ctx, `SELECT MAX(id) FROM (SELECT id FROM users WHERE id > ? ORDER BY id LIMIT ?)`,
+			after, suggestionsBatch).Scan(&last); err != nil {
+			return stored, err
+		}
+		if !last.Valid {
+			return stored, nil
+		}
+		count, err := refreshSuggestionsBetween(ctx, db, after, last.Int64, perUser)
+		if err != nil {
+			return stored, err
+		}
+		stored += count
+		after = last.Int64
+	}
+}
+
+// refreshSuggestionsBetween replaces the suggestions of the users with
+// after < id <= last.
+func refreshSuggestionsBetween(ctx context.Context, db *sql.DB, after, last int64, perUser int) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_suggestions WHERE user_id > ? AND user_id <= ?", after, last); err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, `INSERT INTO user_suggestions
+			(user_id, suggested_id, score, mutual_follows, shared_groups, shared_events, shared_games)
+		WITH batch (after_id, last_id) AS (SELECT ?, ?),
+		signals (user_id, candidate_id, kind) AS (
+			SELECT mine.follower_id, theirs.followee_id, 'follow'
+			FROM batch, follows AS mine JOIN follows AS theirs ON theirs.follower_id = mine.followee_id
+			WHERE mine.follower_id > batch.after_id AND mine.follower_id <= batch.last_id
+			UNION ALL
+			SELECT mine.user_id, theirs.user_id, 'group'
+			FROM batch, group_members AS mine JOIN group_members AS theirs ON theirs.group_id = mine.group_id
+			WHERE mine.user_id > batch.after_id AND mine.user_id <= batch.last_id
+			UNION ALL
+			SELECT mine.user_id, theirs.user_id, 'event'
+			FROM batch, event_responses AS mine JOIN event_responses AS theirs ON theirs.event_id = mine.event_id
+			WHERE mine.user_id > batch.after_id AND mine.user_id <= batch.last_id
+				AND mine.status = 'going' AND theirs.status = 'going'
+			UNION ALL
+			SELECT mine.user_id, theirs.user_id, 'game'
+			FROM batch, user_games AS mine JOIN user_games AS theirs ON theirs.game_slug = mine.game_slug
+			JOIN users AS player ON player.id = theirs.user_id
+			WHERE mine.user_id > batch.after_id AND mine.user_id <= batch.last_id
+				AND (player.is_public = 1 OR EXISTS (SELECT 1 FROM follows WHERE follower_id = mine.user_id AND followee_id = theirs.user_id))
+		), totals AS (
+			SELECT user_id, candidate_id,
+				SUM(kind = 'follow') AS mutual_follows,
+				SUM(kind = 'group') AS shared_groups,
+				SUM(kind = 'event') AS shared_events,
+				SUM(kind = 'game') AS shared_games
+			FROM signals
+			WHERE user_id != candidate_id
+			GROUP BY user_id, candidate_id
+		), scored AS (
+			SELECT totals.*, 3.0 * mutual_follows + 2.0 * shared_groups + shared_events + shared_games AS score
+			FROM totals
+			JOIN users AS owner ON owner.id = totals.user_id AND owner.deletion_scheduled_at IS NULL
+			JOIN users AS candidate ON candidate.id = totals.candidate_id AND candidate.deletion_scheduled_at IS NULL
+			WHERE `+notConnected("totals.user_id", "totals.candidate_id")+`
+		), ranked AS (
+			SELECT scored.*, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY score DESC, candidate_id) AS position
+			FROM scored
+		)
+		SELECT user_id, candidate_id, score, mutual_follows, shared_groups, shared_events, shared_games
+		FROM ranked
+		WHERE position <= ?`, after, last, perUser)
+	if err != nil {
+		return 0, err
+	}
+	stored, _ := result.RowsAffected()
+	return stored, tx.Commit()
+}
+
+// ListSuggestions returns userID's stored suggestions, best first, leaving
+// out anyone they followed, requested or blocked since they were computed.
+// When none are left, as for new members, the most followed public profiles
+// are suggested instead.
+func ListSuggestions(ctx context.Context, db *sql.DB, userID int64, limit int) ([]Suggestion, error) {
+	suggestions, err := querySuggestions(ctx, db, `SELECT `+userCardColumns+`,
+			user_suggestions.mutual_follows, user_suggestions.shared_groups,
+			user_suggestions.shared_events, user_suggestions.shared_games
+		FROM user_suggestions
+		JOIN users ON users.id = user_suggestions.suggested_id
+		WHERE user_suggestions.user_id = ? AND users.deletion_scheduled_at IS NULL
+			AND `+notConnected("user_suggestions.user_id", "users.id")+`
+		ORDER BY user_suggestions.score DESC, users.id ASC
+		LIMIT ?`, userID, userID, userID, limit)
+	if err != nil || len(suggestions) > 0 {
+		return suggestions, err
+	}
+	return querySuggestions(ctx, db, `WITH viewer(id) AS (SELECT ?)
+		SELECT `+userCardColumns+`, 0, 0, 0, 0
+		FROM users
+		CROSS JOIN viewer
+		WHERE users.id != viewer.id AND users.is_public = 1 AND users.deletion_scheduled_at IS NULL
+			AND `+notConnected("viewer.id", "users.id")+`
+		ORDER BY (SELECT COUNT(*) FROM follows WHERE followee_id = users.id) DESC, users.id ASC
+		LIMIT ?`, userID, userID, userID, limit)
+}
+
+func querySuggestions(ctx context.Context, db *sql.DB, query string, args ...any) ([]Suggestion, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []Suggestion{}
+	for rows.Next() {
+		var suggestion Suggestion
+		card, err := scanUserCard(rows, &suggestion.MutualFollows, &suggestion.SharedGroups, &suggestion.SharedEvents, &suggestion.SharedGames)
+		if err != nil {
+			return nil, err
+		}
+		suggestion.UserCard = card
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, rows.Err()
+}
@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+var (
+	postTags        = itemTable{table: "post_tags", column: "post_id"}
+	commentTags     = itemTable{table: "comment_tags", column: "comment_id"}
+	postMentions    = itemTable{table: "post_mentions", column: "post_id"}
+	commentMentions = itemTable{table: "comment_mentions", column: "comment_id"}
+)
+
+// TagCount is a tag with how often it was used.
+type TagCount struct {
+	Tag  string `json:"tag"`
+	Uses int    `json:"uses"`
+}
+
+// IndexPost replaces the tags of a post and the users it mentions. It
+// returns the users who were not mentioned by the post before.
+func IndexPost(ctx context.Context, db *sql.DB, postID int64, tags []string, mentionedIDs []int64) ([]int64, error) {
+	return indexItem(ctx, db, postTags, postMentions, postID, tags, mentionedIDs)
+}
+
+// IndexComment is IndexPost for comments.
+func IndexComment(ctx context.Context, db *sql.DB, commentID int64, tags []string, mentionedIDs []int64) ([]int64, error) {
+	return indexItem(ctx, db, commentTags, commentMentions, commentID, tags, mentionedIDs)
+}
+
+func indexItem(ctx context.Context, db *sql.DB, tagTable, mentionTable itemTable, itemID int64, tags []string, userIDs []int64) ([]int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+tagTable.table+" WHERE "+tagTable.column+" = ?", itemID); err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO "+tagTable.table+" ("+tagTable.column+", tag) VALUES (?, ?)", itemID, tag); err != nil {
+			return nil, err
+		}
+	}
+
+	query := "DELETE FROM " + mentionTable.table + " WHERE " + mentionTable.column + " = ?"
+	args := []any{itemID}
+	if len(userIDs) > 0 {
+		placeholders := make([]string, len(userIDs))
+		for i, id := range userIDs {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		query += " AND user_id NOT IN (" + strings.Join(placeholders, ",") + ")"
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, err
+	}
+
+	var added []int64
+	for _, id := range userIDs {
+		result, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO "+mentionTable.table+" ("+mentionTable.column+", user_id) VALUES (?, ?)", itemID, id)
+		if err != nil {
+			return nil, err
+		}
+		if inserted, _ := result.RowsAffected(); inserted > 0 {
+			added = append(added, id)
+		}
+	}
+	return added, tx.Commit()
+}
+
+// UsersByHandle finds the users holding handles, other than excludeID.
+func UsersByHandle(ctx context.Context, db *sql.DB, excludeID int64, handles []string) ([]int64, error) {
+	if len(handles) == 0 {
+		return nil, nil
+	}
+	placeholders := make([]string, len(handles))
+	args := make([]any, 0, len(handles)+1)
+	args = append(args, excludeID)
+	for i, handle := range handles {
+		placeholders[i] = "?"
+		args = append(args, handle)
+	}
+	rows, err := db.QueryContext(ctx, `SELECT id FROM users
+		WHERE id != ? AND deletion_scheduled_at IS NULL AND handle IN (`+strings.Join(placeholders, ",")+`)
+		ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// TagPosts lists the posts tagged with tag that the viewer may see, newest
+// first, leaving out authors they blocked, muted or were blocked by.
+func TagPosts(ctx context.Context, db *sql.DB, viewerID int64, tag string, limit, offset int) ([]Post, error) {
+	query := `SELECT posts.id, posts.user_id, posts.group_id, posts.text, posts.visibility, posts.media_path, posts.audience_list_id, posts.edited_at, posts.created_at
+		` + visiblePosts + `
+		AND posts.id IN (SELECT post_id FROM post_tags WHERE tag = ?)
+		AND NOT ` + blockedWith("posts.user_id") + `
+		AND NOT ` + mutedBy("posts.user_id") + `
+		ORDER BY posts.created_at DESC, posts.id DESC
+		LIMIT ? OFFSET ?`
+	rows, err := db.QueryContext(ctx, query, viewerID, viewerID, viewerID, viewerID, viewerID, tag, viewerID, viewerID, viewerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return posts, LoadPostReactions(ctx, db, viewerID, posts)
+}
+
+// SearchTags suggests tags starting with prefix, most used first. Only
+// public posts and comments on them count, so tags used in private never
+// show up.
+func SearchTags(ctx context.Context, db *sql.DB, prefix string, limit int) ([]TagCount, error) {
+	rows, err := db.QueryContext(ctx, `SELECT tag, COUNT(*) AS uses FROM (
+			SELECT post_tags.tag FROM post_tags
+			JOIN posts ON posts.id = post_tags.post_id
+			WHERE posts.visibility = 'public' AND posts.group_id IS NULL
+			UNION ALL
+			SELECT comment_tags.tag FROM comment_tags
+			JOIN comments ON comments.id = comment_tags.comment_id
+			JOIN posts ON posts.id = comments.post_id
+			WHERE posts.visibility = 'public' AND posts.group_id IS NULL
+		)
+		WHERE substr(tag, 1, length(?)) = ?
+		GROUP BY tag
+		ORDER BY uses DESC, tag ASC
+		LIMIT ?`, prefix, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TagCount{}
+	for rows.Next() {
+		var tag TagCount
+		if err := rows.Scan(&tag.Tag, &tag.Uses); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
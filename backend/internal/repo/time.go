@@ -0,0 +1,21 @@
+package repo
+
+import "time"
+
+// sqliteTimeLayout matches CURRENT_TIMESTAMP so values written from Go
+// compare correctly against SQLite's own timestamps.
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+func parseSQLiteTime(value string) time.Time {
+	layouts := []string{sqliteTimeLayout, "2006-01-02 15:04:05.999999999-07:00", time.RFC3339Nano}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
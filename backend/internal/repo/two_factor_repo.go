@@ -0,0 +1,196 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"time"
+)
+
+type TOTPState struct {
+	Secret       string
+	Confirmed    bool
+	LastUsedStep int64
+}
+
+// SaveTOTPSecret stores a pending secret for enrollment. A confirmed secret
+// is never replaced; 2FA has to be disabled first.
+func SaveTOTPSecret(ctx context.Context, db *sql.DB, userID int64, secret string) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO user_totp (user_id, secret) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET secret = excluded.secret, last_used_step = 0, created_at = CURRENT_TIMESTAMP
+		WHERE user_totp.confirmed_at IS NULL`, userID, secret)
+	return err
+}
+
+func GetTOTP(ctx context.Context, db *sql.DB, userID int64) (TOTPState, bool, error) {
+	var state TOTPState
+	var confirmedAt sql.NullString
+	row := db.QueryRowContext(ctx, "SELECT secret, confirmed_at, last_used_step FROM user_totp WHERE user_id = ?", userID)
+	if err := row.Scan(&state.Secret, &confirmedAt, &state.LastUsedStep); err != nil {
+		if err == sql.ErrNoRows {
+			return TOTPState{}, false, nil
+		}
+		return TOTPState{}, false, err
+	}
+	state.Confirmed = confirmedAt.Valid
+	return state, true, nil
+}
+
+func IsTwoFactorEnabled(ctx context.Context, db *sql.DB, userID int64) (bool, error) {
+	state, found, err := GetTOTP(ctx, db, userID)
+	if err != nil {
+		return false, err
+	}
+	return found && state.Confirmed, nil
+}
+
+func ConfirmTOTP(ctx context.Context, db *sql.DB, userID int64) error {
+	_, err := db.ExecContext(ctx, "UPDATE user_totp SET confirmed_at = CURRENT_TIMESTAMP WHERE user_id = ?", userID)
+	return err
+}
+
+// MarkTOTPStepUsed records step as consumed. It reports false when the step
+// (or a later one) was already used, which rejects replayed codes.
+func MarkTOTPStepUsed(ctx context.Context, db *sql.DB, userID, step int64) (bool, error) {
+	result, err := db.ExecContext(ctx, "UPDATE user_totp SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?", step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func DeleteTwoFactor(ctx context.Context, db *sql.DB, userID int64) error {
+	if _, err := db.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, "DELETE FROM user_totp WHERE user_id = ?", userID)
+	return err
+}
+
+// ReplaceRecoveryCodes discards any previous recovery codes and stores the
+// hashes of the new ones.
+func ReplaceRecoveryCodes(ctx context.Context, db *sql.DB, userID int64, codes []string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	for _, code := range codes {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO user_recovery_codes (user_id, code_hash) VALUES (?, ?)", userID, hashToken(code)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func UseRecoveryCode(ctx context.Context, db *sql.DB, userID int64, code string) (bool, error) {
+	result, err := db.ExecContext(ctx, `UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`, userID, hashToken(code))
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func CountRecoveryCodes(ctx context.Context, db *sql.DB, userID int64) (int, error) {
+	var count int
+	row := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = ? AND used_at IS NULL", userID)
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// maxLiveLoginChallenges bounds the challenges one account can hold at a
+// time; minting another drops the oldest, so repeating the password step
+// does not multiply the guesses available at the second step.
+const maxLiveLoginChallenges = 3
+
+// CreateLoginChallenge issues the short-lived token that links the password
+// step of a login to its second-factor step.
+func CreateLoginChallenge(ctx context.Context, db *sql.DB, userID int64, ttl time.Duration) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO login_challenges (token_hash, user_id, expires_at) VALUES (?, ?, ?)",
+		hashToken(token), userID, sqliteTime(time.Now().Add(ttl)))
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM login_challenges
+		WHERE user_id = ? AND rowid NOT IN (
+			SELECT rowid FROM login_challenges WHERE user_id = ?
+			ORDER BY created_at DESC, rowid DESC LIMIT ?
+		)`, userID, userID, maxLiveLoginChallenges)
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ClaimLoginChallengeAttempt spends one of the challenge's maxAttempts
+// tries and returns the account it belongs to. The attempt is counted before
+// any code is checked, so concurrent requests cannot share a try; ok is false
+// once the challenge is unknown, expired or out of tries.
+func ClaimLoginChallengeAttempt(ctx context.Context, db *sql.DB, token string, maxAttempts int) (int64, bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE login_challenges SET attempts = attempts + 1
+		WHERE token_hash = ? AND attempts < ? AND expires_at > CURRENT_TIMESTAMP`, hashToken(token), maxAttempts)
+	if err != nil {
+		return 0, false, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return 0, false, nil
+	}
+	var userID int64
+	if err := tx.QueryRowContext(ctx, "SELECT user_id FROM login_challenges WHERE token_hash = ?", hashToken(token)).Scan(&userID); err != nil {
+		return 0, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return userID, true, nil
+}
+
+func DeleteExpiredLoginChallenges(ctx context.Context, db *sql.DB) (int64, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM login_challenges WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
+
+func DeleteLoginChallenge(ctx context.Context, db *sql.DB, token string) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM login_challenges WHERE token_hash = ?", hashToken(token))
+	return err
+}
+
+// hashToken is used for secrets that are only ever compared, never shown
+// again, so a database leak does not expose usable tokens.
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -27,11 +27,18 @@ func CreateUser(ctx context.Context, db *sql.DB, email, passwordHash, firstName,
 	return id, nil
 }
 
+// DiscardNewUser removes an account whose registration could not be
+// finished. Nothing refers to it yet, so a plain delete is enough.
+func DiscardNewUser(ctx context.Context, db *sql.DB, userID int64) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", userID)
+	return err
+}
+
 func CreateOAuthUser(ctx context.Context, db *sql.DB, email, firstName, lastName string, avatar *string) (int64, error) {
 	result, err := db.ExecContext(
 		ctx,
-		`INSERT INTO users (email, password_hash, first_name, last_name, dob, avatar_path, nickname, about, is_public)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1)`,
+		`INSERT INTO users (email, password_hash, first_name, last_name, dob, avatar_path, nickname, about, is_public, email_verified_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1, CURRENT_TIMESTAMP)`,
 		email,
 		nil,
 		firstName,
@@ -51,7 +58,7 @@ func CreateOAuthUser(ctx context.Context, db *sql.DB, email, firstName, lastName
 func GetUserByEmail(ctx context.Context, db *sql.DB, email string) (int64, *string, error) {
 	var id int64
 	var hash sql.NullString
-	row := db.QueryRowContext(ctx, "SELECT id, password_hash FROM users WHERE email = ?", email)
+	row := db.QueryRowContext(ctx, "SELECT id, password_hash FROM users WHERE lower(email) = lower(?) ORDER BY id LIMIT 1", email)
 	if err := row.Scan(&id, &hash); err != nil {
 		return 0, nil, err
 	}
@@ -63,7 +70,7 @@ func GetUserByEmail(ctx context.Context, db *sql.DB, email string) (int64, *stri
 
 func GetUserIDByEmail(ctx context.Context, db *sql.DB, email string) (int64, bool, error) {
 	var id int64
-	row := db.QueryRowContext(ctx, "SELECT id FROM users WHERE email = ?", email)
+	row := db.QueryRowContext(ctx, "SELECT id FROM users WHERE lower(email) = lower(?) ORDER BY id LIMIT 1", email)
 	if err := row.Scan(&id); err != nil {
 		if err == sql.ErrNoRows {
 			return 0, false, nil
@@ -75,24 +82,28 @@ func GetUserIDByEmail(ctx context.Context, db *sql.DB, email string) (int64, boo
 
 func GetUserByID(ctx context.Context, db *sql.DB, id int64) (UserProfile, bool, error) {
 	var profile UserProfile
+	var handle sql.NullString
 	var dob sql.NullString
 	var avatar sql.NullString
 	var nickname sql.NullString
 	var about sql.NullString
 	var isPublic int
 
-	row := db.QueryRowContext(ctx, `SELECT id, email, first_name, last_name, dob, avatar_path, nickname, about, is_public, created_at
+	row := db.QueryRowContext(ctx, `SELECT id, email, first_name, last_name, handle, dob, avatar_path, nickname, about, is_public,
+		email_verified_at IS NOT NULL, created_at
 		FROM users WHERE id = ?`, id)
 	if err := row.Scan(
 		&profile.ID,
 		&profile.Email,
 		&profile.FirstName,
 		&profile.LastName,
+		&handle,
 		&dob,
 		&avatar,
 		&nickname,
 		&about,
 		&isPublic,
+		&profile.EmailVerified,
 		&profile.CreatedAt,
 	); err != nil {
 		if err == sql.ErrNoRows {
@@ -101,6 +112,7 @@ func GetUserByID(ctx context.Context, db *sql.DB, id int64) (UserProfile, bool,
 		return UserProfile{}, false, err
 	}
 
+	profile.Handle = handle.String
 	profile.Avatar = nullableStringPtr(avatar)
 	profile.Nickname = nullableStringPtr(nickname)
 	profile.About = nullableStringPtr(about)
@@ -177,3 +189,67 @@ func nullableStringPtr(value sql.NullString) *string {
 	}
 	return &val
 }
+
+func HasPassword(ctx context.Context, db *sql.DB, userID int64) (bool, error) {
+	var hasPassword bool
+	row := db.QueryRowContext(ctx, "SELECT password_hash IS NOT NULL FROM users WHERE id = ?", userID)
+	if err := row.Scan(&hasPassword); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return hasPassword, nil
+}
+
+// SetInitialPassword stores passwordHash for an account that signed up
+// through OAuth. It reports false when the account already has a password.
+func SetInitialPassword(ctx context.Context, db *sql.DB, userID int64, passwordHash string) (bool, error) {
+	result, err := db.ExecContext(ctx, "UPDATE users SET password_hash = ? WHERE id = ? AND password_hash IS NULL", passwordHash, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// ChangePassword replaces the password of a signed-in user and signs out
+// every other session. Access tokens, pending resets and login challenges
+// issued under the old password go too.
+func ChangePassword(ctx context.Context, db *sql.DB, userID int64, passwordHash, keepSessionID string) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, userID); err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ? AND public_id <> ?", userID, keepSessionID)
+	if err != nil {
+		return 0, err
+	}
+	revoked, _ := result.RowsAffected()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM access_tokens WHERE user_id = ?", userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM password_resets WHERE user_id = ?", userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM login_challenges WHERE user_id = ?", userID); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return revoked, nil
+}
+
+// RehashPassword swaps in an upgraded hash of the same password. It only
+// applies if the stored hash is still oldHash, so it never overwrites a
+// password changed in the meantime.
+func RehashPassword(ctx context.Context, db *sql.DB, userID int64, oldHash, newHash string) error {
+	_, err := db.ExecContext(ctx, "UPDATE users SET password_hash = ? WHERE id = ? AND password_hash = ?", newHash, userID, oldHash)
+	return err
+}
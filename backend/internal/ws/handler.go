@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -16,9 +17,10 @@ import (
 )
 
 type Client struct {
-	UserID int64
-	Conn   *websocket.Conn
-	Send   chan []byte
+	UserID        int64
+	EmailVerified bool
+	Conn          *websocket.Conn
+	Send          chan []byte
 }
 
 type incomingMessage struct {
@@ -26,10 +28,12 @@ type incomingMessage struct {
 	ToUser  int64  `json:"to_user_id,omitempty"`
 	GroupID int64  `json:"group_id,omitempty"`
 	Text    string `json:"text"`
+	PresenceUpdate
 }
 
 type outgoingMessage struct {
 	Type      string `json:"type"`
+	MessageID int64  `json:"message_id,omitempty"`
 	FromUser  int64  `json:"from_user_id,omitempty"`
 	ToUser    int64  `json:"to_user_id,omitempty"`
 	GroupID   int64  `json:"group_id,omitempty"`
@@ -40,11 +44,10 @@ type outgoingMessage struct {
 
 var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
 
-func NewHandler(cfg config.Config, db *sql.DB) http.HandlerFunc {
-	hub := NewHub()
-
+// NewHandler serves the websocket on hub, which the router shares with the
+// presence endpoints.
+func NewHandler(cfg config.Config, db *sql.DB, hub *Hub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		_ = cfg
 		current, ok := middleware.CurrentUser(r)
 		if !ok {
 			w.WriteHeader(http.StatusUnauthorized)
@@ -54,17 +57,21 @@ func NewHandler(cfg config.Config, db *sql.DB) http.HandlerFunc {
 		if err != nil {
 			return
 		}
-		client := &Client{UserID: current.ID, Conn: conn, Send: make(chan []byte, 32)}
-		hub.Register(client)
+		client := &Client{UserID: current.ID, EmailVerified: current.EmailVerified, Conn: conn, Send: make(chan []byte, 32)}
+		if hub.Register(client) {
+			announcePresence(db, hub, client.UserID, false)
+		}
 
 		go writePump(client)
-		readPump(db, hub, client)
+		readPump(cfg, db, hub, client)
 	}
 }
 
-func readPump(db *sql.DB, hub *Hub, client *Client) {
+func readPump(cfg config.Config, db *sql.DB, hub *Hub, client *Client) {
 	defer func() {
-		hub.Unregister(client)
+		if hub.Unregister(client) {
+			announcePresence(db, hub, client.UserID, true)
+		}
 		_ = client.Conn.Close()
 	}()
 
@@ -84,6 +91,10 @@ func readPump(db *sql.DB, hub *Hub, client *Client) {
 			sendError(client, "invalid message")
 			continue
 		}
+		if msg.Type == "presence_set" {
+			handlePresence(db, hub, client, msg.PresenceUpdate)
+			continue
+		}
 		msg.Text = strings.TrimSpace(msg.Text)
 		if msg.Text == "" {
 			sendError(client, "text required")
@@ -92,9 +103,13 @@ func readPump(db *sql.DB, hub *Hub, client *Client) {
 
 		switch msg.Type {
 		case "dm_send":
-			handleDM(db, hub, client, msg)
+			if checkVerifiedEmail(cfg, db, client, "dm") {
+				handleDM(db, hub, client, msg)
+			}
 		case "group_send":
-			handleGroup(db, hub, client, msg)
+			if checkVerifiedEmail(cfg, db, client, "group") {
+				handleGroup(db, hub, client, msg)
+			}
 		default:
 			sendError(client, "unsupported type")
 		}
@@ -131,6 +146,7 @@ func handleDM(db *sql.DB, hub *Hub, client *Client, msg incomingMessage) {
 
 	payload := outgoingMessage{
 		Type:      "dm_new",
+		MessageID: created.ID,
 		FromUser:  client.UserID,
 		ToUser:    msg.ToUser,
 		Text:      msg.Text,
@@ -161,6 +177,7 @@ func handleGroup(db *sql.DB, hub *Hub, client *Client, msg incomingMessage) {
 	}
 	payload := outgoingMessage{
 		Type:      "group_new",
+		MessageID: created.ID,
 		FromUser:  client.UserID,
 		GroupID:   msg.GroupID,
 		Text:      msg.Text,
@@ -178,6 +195,46 @@ func handleGroup(db *sql.DB, hub *Hub, client *Client, msg incomingMessage) {
 	}
 }
 
+func handlePresence(db *sql.DB, hub *Hub, client *Client, update PresenceUpdate) {
+	if fields := update.Normalize(); len(fields) > 0 {
+		for _, message := range fields {
+			sendError(client, message)
+		}
+		return
+	}
+	if _, err := UpdatePresence(contextBackground(), db, hub, client.UserID, update); err != nil {
+		sendError(client, "presence failed")
+	}
+}
+
+// announcePresence runs when a user's first connection opens or their last
+// one closes: it records when they were last seen, clears what they were
+// playing once offline, and tells their audience.
+func announcePresence(db *sql.DB, hub *Hub, userID int64, offline bool) {
+	if err := repo.TouchLastSeen(contextBackground(), db, userID, offline); err != nil {
+		log.Printf("presence %d: %v", userID, err)
+	}
+	if err := PublishPresence(contextBackground(), db, hub, userID); err != nil {
+		log.Printf("presence %d: %v", userID, err)
+	}
+}
+
+// checkVerifiedEmail mirrors middleware.RequireVerifiedEmail for websocket
+// messages. The flag captured at connect time is rechecked so verifying the
+// email takes effect without reconnecting.
+func checkVerifiedEmail(cfg config.Config, db *sql.DB, client *Client, action string) bool {
+	if client.EmailVerified || !cfg.RequiresVerifiedEmail(action) {
+		return true
+	}
+	verified, err := repo.IsEmailVerified(contextBackground(), db, client.UserID)
+	if err != nil || !verified {
+		sendError(client, "email not verified")
+		return false
+	}
+	client.EmailVerified = true
+	return true
+}
+
 func sendError(client *Client, message string) {
 	payload := outgoingMessage{Type: "error", Message: message}
 	data, _ := json.Marshal(payload)
@@ -188,6 +245,10 @@ func sendError(client *Client, message string) {
 }
 
 func canDM(db *sql.DB, fromID, toID int64) (bool, error) {
+	blocked, err := repo.IsBlocked(contextBackground(), db, fromID, toID)
+	if err != nil || blocked {
+		return false, err
+	}
 	f1, err := repo.IsFollowing(contextBackground(), db, fromID, toID)
 	if err != nil {
 		return false, err
@@ -200,6 +261,10 @@ func canDM(db *sql.DB, fromID, toID int64) (bool, error) {
 }
 
 func shouldDeliverDM(db *sql.DB, fromID, toID int64) bool {
+	blocked, err := repo.IsBlocked(contextBackground(), db, fromID, toID)
+	if err != nil || blocked {
+		return false
+	}
 	follows, err := repo.IsFollowing(contextBackground(), db, toID, fromID)
 	if err != nil {
 		return false
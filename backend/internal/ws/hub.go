@@ -4,34 +4,75 @@ import (
 	"sync"
 )
 
+// Hub tracks the live connections of each user, and whether a connected
+// user marked themselves away.
 type Hub struct {
 	mu      sync.RWMutex
 	clients map[int64]map[*Client]struct{}
+	away    map[int64]bool
 }
 
 func NewHub() *Hub {
-	return &Hub{clients: make(map[int64]map[*Client]struct{})}
+	return &Hub{clients: make(map[int64]map[*Client]struct{}), away: make(map[int64]bool)}
 }
 
-func (h *Hub) Register(client *Client) {
+// Register adds a connection and reports whether it is the user's first,
+// that is whether they just came online.
+func (h *Hub) Register(client *Client) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	if h.clients[client.UserID] == nil {
+	first := h.clients[client.UserID] == nil
+	if first {
 		h.clients[client.UserID] = make(map[*Client]struct{})
 	}
 	h.clients[client.UserID][client] = struct{}{}
+	return first
 }
 
-func (h *Hub) Unregister(client *Client) {
+// Unregister removes a connection and reports whether it was the user's
+// last, that is whether they just went offline.
+func (h *Hub) Unregister(client *Client) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	clients := h.clients[client.UserID]
 	if clients == nil {
-		return
+		return false
 	}
 	delete(clients, client)
 	if len(clients) == 0 {
 		delete(h.clients, client.UserID)
+		delete(h.away, client.UserID)
+		return true
+	}
+	return false
+}
+
+// Status is online, away or offline.
+func (h *Hub) Status(userID int64) string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	switch {
+	case h.clients[userID] == nil:
+		return StatusOffline
+	case h.away[userID]:
+		return StatusAway
+	default:
+		return StatusOnline
+	}
+}
+
+// SetAway marks a connected user away or back online. It does nothing for
+// users without a connection, who are offline either way.
+func (h *Hub) SetAway(userID int64, away bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[userID] == nil {
+		return
+	}
+	if away {
+		h.away[userID] = true
+	} else {
+		delete(h.away, userID)
 	}
 }
 
@@ -0,0 +1,145 @@
+package ws
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"backend/internal/domain/profile"
+	"backend/internal/repo"
+)
+
+const (
+	StatusOnline  = "online"
+	StatusAway    = "away"
+	StatusOffline = "offline"
+)
+
+// Presence is a user's status as others see it. Now playing only shows
+// while the user is connected.
+type Presence struct {
+	Type            string  `json:"type,omitempty"`
+	UserID          int64   `json:"user_id"`
+	Status          string  `json:"status"`
+	LastSeenAt      *string `json:"last_seen_at"`
+	NowPlaying      *string `json:"now_playing"`
+	NowPlayingSince *string `json:"now_playing_since"`
+}
+
+// Presence combines a stored record with the hub's live state.
+func (h *Hub) Presence(record repo.PresenceRecord) Presence {
+	presence := Presence{UserID: record.UserID, Status: h.Status(record.UserID), LastSeenAt: record.LastSeenAt}
+	if presence.Status != StatusOffline {
+		presence.NowPlaying = record.NowPlaying
+		presence.NowPlayingSince = record.NowPlayingSince
+	}
+	return presence
+}
+
+// PresenceUpdate is a change users make to their own presence, over REST
+// or the websocket. Nil fields are left as they are; an empty now playing
+// clears it.
+type PresenceUpdate struct {
+	Status     *string `json:"status"`
+	NowPlaying *string `json:"now_playing"`
+	Visibility *string `json:"visibility"`
+}
+
+// Normalize trims the update and reports what is wrong with it, by field.
+func (u *PresenceUpdate) Normalize() profile.FieldErrors {
+	fields := profile.FieldErrors{}
+	if u.Status != nil {
+		status := strings.ToLower(strings.TrimSpace(*u.Status))
+		u.Status = &status
+		if status != StatusOnline && status != StatusAway {
+			fields["status"] = "status must be online or away"
+		}
+	}
+	if u.NowPlaying != nil {
+		game := strings.TrimSpace(*u.NowPlaying)
+		u.NowPlaying = &game
+		if utf8.RuneCountInString(game) > profile.GameNameMaxLength {
+			fields["now_playing"] = "now_playing must be at most " + strconv.Itoa(profile.GameNameMaxLength) + " characters"
+		}
+	}
+	if u.Visibility != nil {
+		visibility := strings.ToLower(strings.TrimSpace(*u.Visibility))
+		u.Visibility = &visibility
+		if !profile.IsVisibility(visibility) {
+			fields["visibility"] = "visibility must be one of " + strings.Join(profile.Visibilities, ", ")
+		}
+	}
+	return fields
+}
+
+// UpdatePresence applies a normalized update and pushes the result. Users
+// who can no longer see the presence after a visibility change are told
+// the user went offline.
+func UpdatePresence(ctx context.Context, db *sql.DB, hub *Hub, userID int64, update PresenceUpdate) (Presence, error) {
+	var before []int64
+	if update.Visibility != nil {
+		var err error
+		if before, err = repo.ListPresenceAudience(ctx, db, userID); err != nil {
+			return Presence{}, err
+		}
+		if err := repo.SetPresenceVisibility(ctx, db, userID, *update.Visibility); err != nil {
+			return Presence{}, err
+		}
+	}
+	if update.NowPlaying != nil {
+		if err := repo.SetNowPlaying(ctx, db, userID, *update.NowPlaying); err != nil {
+			return Presence{}, err
+		}
+	}
+	if update.Status != nil {
+		hub.SetAway(userID, *update.Status == StatusAway)
+	}
+
+	presence, audience, err := publishPresence(ctx, db, hub, userID)
+	if err != nil {
+		return Presence{}, err
+	}
+	if len(before) > 0 {
+		still := make(map[int64]bool, len(audience))
+		for _, id := range audience {
+			still[id] = true
+		}
+		data, _ := json.Marshal(Presence{Type: "presence", UserID: userID, Status: StatusOffline})
+		for _, id := range before {
+			if !still[id] {
+				hub.SendToUser(id, data)
+			}
+		}
+	}
+	return presence, nil
+}
+
+// PublishPresence pushes a user's current presence to their own connections
+// and to the followers and group co-members allowed to see it.
+func PublishPresence(ctx context.Context, db *sql.DB, hub *Hub, userID int64) error {
+	_, _, err := publishPresence(ctx, db, hub, userID)
+	return err
+}
+
+func publishPresence(ctx context.Context, db *sql.DB, hub *Hub, userID int64) (Presence, []int64, error) {
+	record, err := repo.GetPresence(ctx, db, userID)
+	if err != nil {
+		return Presence{}, nil, err
+	}
+	audience, err := repo.ListPresenceAudience(ctx, db, userID)
+	if err != nil {
+		return Presence{}, nil, err
+	}
+	presence := hub.Presence(record)
+	event := presence
+	event.Type = "presence"
+	data, _ := json.Marshal(event)
+	hub.SendToUser(userID, data)
+	for _, id := range audience {
+		hub.SendToUser(id, data)
+	}
+	return presence, audience, nil
+}
@@ -0,0 +1,22 @@
+package ws
+
+import "encoding/json"
+
+// MessageReaction tells a conversation that someone reacted to one of its
+// messages, or took their reaction back when Emoji is nil. Reactions holds
+// the new counts.
+type MessageReaction struct {
+	Type      string         `json:"type"`
+	MessageID int64          `json:"message_id"`
+	UserID    int64          `json:"user_id"`
+	Emoji     *string        `json:"emoji"`
+	Reactions map[string]int `json:"reactions"`
+}
+
+// PublishMessageReaction sends event to every user in audience.
+func PublishMessageReaction(hub *Hub, audience []int64, event MessageReaction) {
+	data, _ := json.Marshal(event)
+	for _, userID := range audience {
+		hub.SendToUser(userID, data)
+	}
+}
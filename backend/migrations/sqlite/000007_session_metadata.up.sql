@@ -0,0 +1,9 @@
+ALTER TABLE sessions ADD COLUMN public_id TEXT;
+ALTER TABLE sessions ADD COLUMN user_agent TEXT;
+ALTER TABLE sessions ADD COLUMN ip TEXT;
+ALTER TABLE sessions ADD COLUMN last_seen_at TEXT;
+
+UPDATE sessions SET public_id = lower(hex(randomblob(16))) WHERE public_id IS NULL;
+UPDATE sessions SET last_seen_at = created_at WHERE last_seen_at IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_public_id ON sessions(public_id);
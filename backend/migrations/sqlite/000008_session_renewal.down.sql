@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_sessions_expires_at;
+DROP INDEX IF EXISTS idx_sessions_previous_token;
+
+ALTER TABLE sessions DROP COLUMN previous_token;
+ALTER TABLE sessions DROP COLUMN rotated_at;
+ALTER TABLE sessions DROP COLUMN absolute_expires_at;
@@ -0,0 +1,8 @@
+ALTER TABLE sessions ADD COLUMN absolute_expires_at TEXT;
+ALTER TABLE sessions ADD COLUMN rotated_at TEXT;
+ALTER TABLE sessions ADD COLUMN previous_token TEXT;
+
+UPDATE sessions SET absolute_expires_at = expires_at WHERE absolute_expires_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_token ON sessions(previous_token);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
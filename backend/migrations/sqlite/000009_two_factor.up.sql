@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS user_totp (
+	user_id INTEGER PRIMARY KEY,
+	secret TEXT NOT NULL,
+	confirmed_at TEXT,
+	last_used_step INTEGER NOT NULL DEFAULT 0,
+	created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	code_hash TEXT NOT NULL,
+	used_at TEXT,
+	created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS login_challenges (
+	token_hash TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	expires_at TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user_id ON user_recovery_codes(user_id);
+CREATE INDEX IF NOT EXISTS idx_login_challenges_user_id ON login_challenges(user_id);
@@ -0,0 +1,15 @@
+ALTER TABLE users ADD COLUMN email_verified_at TEXT;
+
+-- Accounts created before verification existed are trusted as-is.
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+	token_hash TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	email TEXT NOT NULL,
+	expires_at TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications(user_id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS login_throttle (
+	scope TEXT NOT NULL,
+	key TEXT NOT NULL,
+	failures INTEGER NOT NULL DEFAULT 0,
+	lockouts INTEGER NOT NULL DEFAULT 0,
+	window_started_at TEXT NOT NULL,
+	locked_until TEXT,
+	updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_throttle_updated_at ON login_throttle(updated_at);
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN deletion_scheduled_at TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at ON users(deletion_scheduled_at);
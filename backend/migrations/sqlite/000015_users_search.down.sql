@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS users_fts_update;
+DROP TRIGGER IF EXISTS users_fts_delete;
+DROP TRIGGER IF EXISTS users_fts_insert;
+
+DROP TABLE IF EXISTS users_fts;
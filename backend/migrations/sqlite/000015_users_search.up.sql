@@ -0,0 +1,28 @@
+-- Requires SQLite built with FTS5 (go build -tags sqlite_fts5).
+CREATE VIRTUAL TABLE IF NOT EXISTS users_fts USING fts5(
+	nickname,
+	first_name,
+	last_name,
+	content = 'users',
+	content_rowid = 'id',
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS users_fts_insert AFTER INSERT ON users BEGIN
+	INSERT INTO users_fts (rowid, nickname, first_name, last_name)
+	VALUES (new.id, new.nickname, new.first_name, new.last_name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_delete AFTER DELETE ON users BEGIN
+	INSERT INTO users_fts (users_fts, rowid, nickname, first_name, last_name)
+	VALUES ('delete', old.id, old.nickname, old.first_name, old.last_name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_update AFTER UPDATE OF nickname, first_name, last_name ON users BEGIN
+	INSERT INTO users_fts (users_fts, rowid, nickname, first_name, last_name)
+	VALUES ('delete', old.id, old.nickname, old.first_name, old.last_name);
+	INSERT INTO users_fts (rowid, nickname, first_name, last_name)
+	VALUES (new.id, new.nickname, new.first_name, new.last_name);
+END;
+
+INSERT INTO users_fts (users_fts) VALUES ('rebuild');
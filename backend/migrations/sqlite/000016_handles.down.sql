@@ -0,0 +1,38 @@
+DROP TRIGGER IF EXISTS users_fts_update;
+DROP TRIGGER IF EXISTS users_fts_delete;
+DROP TRIGGER IF EXISTS users_fts_insert;
+DROP TABLE IF EXISTS users_fts;
+
+CREATE VIRTUAL TABLE users_fts USING fts5(
+	nickname,
+	first_name,
+	last_name,
+	content = 'users',
+	content_rowid = 'id',
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER users_fts_insert AFTER INSERT ON users BEGIN
+	INSERT INTO users_fts (rowid, nickname, first_name, last_name)
+	VALUES (new.id, new.nickname, new.first_name, new.last_name);
+END;
+
+CREATE TRIGGER users_fts_delete AFTER DELETE ON users BEGIN
+	INSERT INTO users_fts (users_fts, rowid, nickname, first_name, last_name)
+	VALUES ('delete', old.id, old.nickname, old.first_name, old.last_name);
+END;
+
+CREATE TRIGGER users_fts_update AFTER UPDATE OF nickname, first_name, last_name ON users BEGIN
+	INSERT INTO users_fts (users_fts, rowid, nickname, first_name, last_name)
+	VALUES ('delete', old.id, old.nickname, old.first_name, old.last_name);
+	INSERT INTO users_fts (rowid, nickname, first_name, last_name)
+	VALUES (new.id, new.nickname, new.first_name, new.last_name);
+END;
+
+INSERT INTO users_fts (users_fts) VALUES ('rebuild');
+
+DROP TABLE IF EXISTS handle_history;
+DROP INDEX IF EXISTS idx_users_handle;
+
+ALTER TABLE users DROP COLUMN handle_changed_at;
+ALTER TABLE users DROP COLUMN handle;
@@ -0,0 +1,68 @@
+ALTER TABLE users ADD COLUMN handle TEXT;
+ALTER TABLE users ADD COLUMN handle_changed_at TEXT;
+
+-- Existing users keep their nickname as handle when it is already a valid,
+-- unclaimed handle; everyone else gets user<id> and can pick a better one
+-- right away (handle_changed_at stays NULL, so no cooldown applies).
+UPDATE users SET handle = lower(nickname)
+WHERE nickname IS NOT NULL
+	AND length(nickname) BETWEEN 3 AND 30
+	AND lower(nickname) NOT GLOB '*[^a-z0-9_]*'
+	AND lower(nickname) GLOB '*[a-z]*'
+	AND lower(nickname) NOT GLOB 'user[0-9]*'
+	AND lower(nickname) NOT IN ('admin', 'administrator', 'all', 'api', 'auth', 'everyone', 'gaming', 'gamingnetwork',
+		'help', 'here', 'login', 'logout', 'me', 'mod', 'moderator', 'network', 'null', 'official', 'register', 'root',
+		'search', 'security', 'settings', 'staff', 'support', 'system', 'undefined')
+	AND NOT EXISTS (
+		SELECT 1 FROM users AS earlier
+		WHERE lower(earlier.nickname) = lower(users.nickname) AND earlier.id < users.id
+	);
+UPDATE users SET handle = 'user' || id WHERE handle IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_handle ON users(handle);
+
+-- Handles a user moved away from. They redirect to the user's current
+-- handle and stay reserved for them for a while.
+CREATE TABLE IF NOT EXISTS handle_history (
+	handle TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	released_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_handle_history_user_id ON handle_history(user_id);
+
+-- Rebuild the search index with the handle as its first column.
+DROP TRIGGER IF EXISTS users_fts_update;
+DROP TRIGGER IF EXISTS users_fts_delete;
+DROP TRIGGER IF EXISTS users_fts_insert;
+DROP TABLE IF EXISTS users_fts;
+
+CREATE VIRTUAL TABLE users_fts USING fts5(
+	handle,
+	nickname,
+	first_name,
+	last_name,
+	content = 'users',
+	content_rowid = 'id',
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER users_fts_insert AFTER INSERT ON users BEGIN
+	INSERT INTO users_fts (rowid, handle, nickname, first_name, last_name)
+	VALUES (new.id, new.handle, new.nickname, new.first_name, new.last_name);
+END;
+
+CREATE TRIGGER users_fts_delete AFTER DELETE ON users BEGIN
+	INSERT INTO users_fts (users_fts, rowid, handle, nickname, first_name, last_name)
+	VALUES ('delete', old.id, old.handle, old.nickname, old.first_name, old.last_name);
+END;
+
+CREATE TRIGGER users_fts_update AFTER UPDATE OF handle, nickname, first_name, last_name ON users BEGIN
+	INSERT INTO users_fts (users_fts, rowid, handle, nickname, first_name, last_name)
+	VALUES ('delete', old.id, old.handle, old.nickname, old.first_name, old.last_name);
+	INSERT INTO users_fts (rowid, handle, nickname, first_name, last_name)
+	VALUES (new.id, new.handle, new.nickname, new.first_name, new.last_name);
+END;
+
+INSERT INTO users_fts (users_fts) VALUES ('rebuild');
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS users_email_unique_update;
+DROP TRIGGER IF EXISTS users_email_unique_insert;
+DROP INDEX IF EXISTS idx_users_email_lower;
+DROP TABLE IF EXISTS email_conflicts;
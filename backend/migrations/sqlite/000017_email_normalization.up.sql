@@ -0,0 +1,35 @@
+-- Accounts whose emails only differ by case or surrounding spaces. They are
+-- left as they are for an operator to merge or rename, and the server logs
+-- them at startup until they are resolved.
+CREATE TABLE IF NOT EXISTS email_conflicts (
+	user_id INTEGER PRIMARY KEY,
+	email TEXT NOT NULL,
+	normalized_email TEXT NOT NULL,
+	detected_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT OR IGNORE INTO email_conflicts (user_id, email, normalized_email)
+SELECT id, email, lower(trim(email)) FROM users
+WHERE lower(trim(email)) IN (
+	SELECT lower(trim(email)) FROM users GROUP BY lower(trim(email)) HAVING COUNT(*) > 1
+);
+
+UPDATE users SET email = lower(trim(email))
+WHERE email != lower(trim(email)) AND id NOT IN (SELECT user_id FROM email_conflicts);
+
+CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users(lower(email));
+
+-- A unique index on lower(email) would fail while conflicts remain, so new
+-- writes are checked by triggers instead.
+CREATE TRIGGER IF NOT EXISTS users_email_unique_insert BEFORE INSERT ON users
+WHEN EXISTS (SELECT 1 FROM users WHERE lower(email) = lower(NEW.email))
+BEGIN
+	SELECT RAISE(ABORT, 'email already exists');
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_email_unique_update BEFORE UPDATE OF email ON users
+WHEN EXISTS (SELECT 1 FROM users WHERE lower(email) = lower(NEW.email) AND id != NEW.id)
+BEGIN
+	SELECT RAISE(ABORT, 'email already exists');
+END;
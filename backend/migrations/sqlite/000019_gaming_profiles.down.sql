@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_games;
+DROP TABLE IF EXISTS user_platform_accounts;
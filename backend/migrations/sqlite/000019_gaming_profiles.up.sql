@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS user_platform_accounts (
+	user_id INTEGER NOT NULL,
+	platform TEXT NOT NULL CHECK (platform IN ('steam', 'psn', 'xbox', 'nintendo', 'riot', 'battlenet')),
+	account_id TEXT NOT NULL,
+	visibility TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'followers', 'private')),
+	PRIMARY KEY (user_id, platform),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_platform_accounts_platform ON user_platform_accounts(platform);
+
+CREATE TABLE IF NOT EXISTS user_games (
+	user_id INTEGER NOT NULL,
+	game_slug TEXT NOT NULL,
+	game_name TEXT NOT NULL,
+	rank TEXT,
+	roles_json TEXT NOT NULL DEFAULT '[]',
+	position INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, game_slug),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_games_game_slug ON user_games(game_slug);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_presence (
+	user_id INTEGER PRIMARY KEY,
+	visibility TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'followers', 'private')),
+	now_playing TEXT,
+	now_playing_since TEXT,
+	last_seen_at TEXT,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS user_suggestions (
+	user_id INTEGER NOT NULL,
+	suggested_id INTEGER NOT NULL,
+	score REAL NOT NULL,
+	mutual_follows INTEGER NOT NULL DEFAULT 0,
+	shared_groups INTEGER NOT NULL DEFAULT 0,
+	shared_events INTEGER NOT NULL DEFAULT 0,
+	shared_games INTEGER NOT NULL DEFAULT 0,
+	computed_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, suggested_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (suggested_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_suggestions_rank ON user_suggestions(user_id, score DESC);
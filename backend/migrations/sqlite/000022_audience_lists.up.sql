@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS audience_lists (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	owner_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audience_lists_owner_name ON audience_lists(owner_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS audience_list_members (
+	list_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (list_id, user_id),
+	FOREIGN KEY (list_id) REFERENCES audience_lists(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audience_list_members_user ON audience_list_members(user_id);
+
+-- A private post can target a list; its audience follows the list's
+-- current members. Deleting the list leaves the post to its author and any
+-- post_allowed entries.
+ALTER TABLE posts ADD COLUMN audience_list_id INTEGER REFERENCES audience_lists(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_audience_list ON posts(audience_list_id);
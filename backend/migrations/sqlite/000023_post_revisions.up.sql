@@ -0,0 +1,15 @@
+ALTER TABLE posts ADD COLUMN edited_at TEXT;
+
+-- Each edit keeps the version it replaced; created_at is when that version
+-- was written, so the post itself plus its revisions make up the history.
+CREATE TABLE IF NOT EXISTS post_revisions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id INTEGER NOT NULL,
+	text TEXT NOT NULL,
+	visibility TEXT NOT NULL,
+	media_path TEXT,
+	created_at TEXT NOT NULL,
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_revisions_post ON post_revisions(post_id, id);
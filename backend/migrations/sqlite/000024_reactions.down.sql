@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS group_message_reactions;
+DROP TABLE IF EXISTS dm_message_reactions;
+DROP TABLE IF EXISTS comment_reactions;
+DROP TABLE IF EXISTS post_reactions;
@@ -0,0 +1,49 @@
+-- One reaction per user per post, comment or chat message; reacting again
+-- replaces it.
+CREATE TABLE IF NOT EXISTS post_reactions (
+	post_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	emoji TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (post_id, user_id),
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_reactions_user ON post_reactions(user_id);
+
+CREATE TABLE IF NOT EXISTS comment_reactions (
+	comment_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	emoji TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (comment_id, user_id),
+	FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_reactions_user ON comment_reactions(user_id);
+
+CREATE TABLE IF NOT EXISTS dm_message_reactions (
+	message_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	emoji TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (message_id, user_id),
+	FOREIGN KEY (message_id) REFERENCES dm_messages(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_dm_message_reactions_user ON dm_message_reactions(user_id);
+
+CREATE TABLE IF NOT EXISTS group_message_reactions (
+	message_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	emoji TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (message_id, user_id),
+	FOREIGN KEY (message_id) REFERENCES group_messages(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_message_reactions_user ON group_message_reactions(user_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS comment_mentions;
+DROP TABLE IF EXISTS post_mentions;
+DROP TABLE IF EXISTS comment_tags;
+DROP TABLE IF EXISTS post_tags;
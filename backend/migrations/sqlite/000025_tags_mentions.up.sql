@@ -0,0 +1,38 @@
+-- Tags are stored normalized (lowercase, without the #).
+CREATE TABLE IF NOT EXISTS post_tags (
+	post_id INTEGER NOT NULL,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (post_id, tag),
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_tags_tag ON post_tags(tag, post_id);
+
+CREATE TABLE IF NOT EXISTS comment_tags (
+	comment_id INTEGER NOT NULL,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (comment_id, tag),
+	FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_tags_tag ON comment_tags(tag, comment_id);
+
+CREATE TABLE IF NOT EXISTS post_mentions (
+	post_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	PRIMARY KEY (post_id, user_id),
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_mentions_user ON post_mentions(user_id);
+
+CREATE TABLE IF NOT EXISTS comment_mentions (
+	comment_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	PRIMARY KEY (comment_id, user_id),
+	FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_mentions_user ON comment_mentions(user_id);
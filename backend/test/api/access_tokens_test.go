@@ -0,0 +1,114 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func bearerJSON(t *testing.T, method, url, token string, body any) (*http.Response, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, _ := http.NewRequest(method, url, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	buf, _ := io.ReadAll(resp.Body)
+	return resp, buf
+}
+
+func createAccessToken(t *testing.T, baseURL string, cookies []*http.Cookie, scopes ...string) (int64, string) {
+	t.Helper()
+	resp, body := postJSON(t, baseURL+"/api/me/tokens", map[string]any{"name": "bot", "scopes": scopes}, cookies)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create token status: %d %s", resp.StatusCode, body)
+	}
+	var created struct {
+		Token       string `json:"token"`
+		AccessToken struct {
+			ID int64 `json:"id"`
+		} `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil || created.Token == "" {
+		t.Fatalf("create token payload: %s", body)
+	}
+	return created.AccessToken.ID, created.Token
+}
+
+func TestAccessTokens(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	cookies := loginUser(t, srv.URL, "alice@example.com")
+
+	resp, _ := postJSON(t, srv.URL+"/api/me/tokens", map[string]any{"name": "bot", "scopes": []string{"admin"}}, cookies)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unknown scope status: %d", resp.StatusCode)
+	}
+
+	readID, readToken := createAccessToken(t, srv.URL, cookies, "read")
+	_, writeToken := createAccessToken(t, srv.URL, cookies, "read", "write")
+
+	resp, body := bearerJSON(t, http.MethodGet, srv.URL+"/api/me", readToken, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("bearer me status: %d %s", resp.StatusCode, body)
+	}
+
+	post := map[string]any{"text": "from a bot", "visibility": "public"}
+	resp, _ = bearerJSON(t, http.MethodPost, srv.URL+"/api/posts", readToken, post)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("read token posting: %d", resp.StatusCode)
+	}
+	resp, _ = bearerJSON(t, http.MethodPost, srv.URL+"/api/posts", writeToken, post)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("write token posting: %d", resp.StatusCode)
+	}
+
+	resp, _ = bearerJSON(t, http.MethodGet, srv.URL+"/api/sessions", writeToken, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("token reaching session-only route: %d", resp.StatusCode)
+	}
+	resp, _ = bearerJSON(t, http.MethodPost, srv.URL+"/api/me/tokens", writeToken, map[string]any{"name": "x", "scopes": []string{"read"}})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("token minting tokens: %d", resp.StatusCode)
+	}
+
+	_, body = doJSON(t, http.MethodGet, srv.URL+"/api/me/tokens", nil, cookies)
+	var list struct {
+		Tokens []struct {
+			ID         int64   `json:"id"`
+			Prefix     string  `json:"prefix"`
+			LastUsedAt *string `json:"last_used_at"`
+		} `json:"tokens"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil || len(list.Tokens) != 2 {
+		t.Fatalf("list tokens: %s", body)
+	}
+	for _, token := range list.Tokens {
+		if token.ID == readID && (token.LastUsedAt == nil || token.Prefix == "" || token.Prefix == readToken) {
+			t.Fatalf("read token listing: %s", body)
+		}
+	}
+
+	resp, _ = doJSON(t, http.MethodDelete, srv.URL+"/api/me/tokens/"+strconv.FormatInt(readID, 10), nil, cookies)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("revoke status: %d", resp.StatusCode)
+	}
+	resp, _ = bearerJSON(t, http.MethodGet, srv.URL+"/api/me", readToken, nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("revoked token still works: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,249 @@
+package api_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"backend/internal/jobs"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func uploadMedia(t *testing.T, baseURL string, cookies []*http.Cookie) string {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "avatar.png")
+	_, _ = part.Write(pngHeader)
+	_ = form.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, baseURL+"/api/media/upload", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	withCSRF(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	defer resp.Body.Close()
+	var payload struct {
+		Path string `json:"path"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&payload)
+	if resp.StatusCode != http.StatusOK || payload.Path == "" {
+		t.Fatalf("upload status: %d", resp.StatusCode)
+	}
+	return payload.Path
+}
+
+func userID(t *testing.T, db *sql.DB, email string) int64 {
+	t.Helper()
+	var id int64
+	if err := db.QueryRow("SELECT id FROM users WHERE email = ?", email).Scan(&id); err != nil {
+		t.Fatalf("lookup %s: %v", email, err)
+	}
+	return id
+}
+
+func TestExportAccount(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	registerUser(t, srv.URL, "bob@example.com")
+	cookies := loginUser(t, srv.URL, "alice@example.com")
+	alice := userID(t, db, "alice@example.com")
+	bob := userID(t, db, "bob@example.com")
+
+	mediaPath := uploadMedia(t, srv.URL, cookies)
+	resp, _ := postJSON(t, srv.URL+"/api/posts", map[string]any{"text": "hello", "visibility": "public", "media_path": mediaPath}, cookies)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create post: %d", resp.StatusCode)
+	}
+	if _, err := db.Exec("INSERT INTO dm_messages (from_user_id, to_user_id, text) VALUES (?, ?, 'hi alice')", bob, alice); err != nil {
+		t.Fatalf("insert dm: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/me/export", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/zip" {
+		t.Fatalf("export status: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	if files["media/"+filepath.Base(mediaPath)] == nil {
+		t.Fatalf("uploaded file missing from export: %v", files)
+	}
+	data, err := files["data.json"].Open()
+	if err != nil {
+		t.Fatalf("open data.json: %v", err)
+	}
+	var export struct {
+		Account struct {
+			Profile struct {
+				Email string `json:"email"`
+			} `json:"profile"`
+			Posts          []map[string]any `json:"posts"`
+			DirectMessages []map[string]any `json:"direct_messages"`
+			Media          []map[string]any `json:"media"`
+		} `json:"account"`
+	}
+	_ = json.NewDecoder(data).Decode(&export)
+	data.Close()
+	if export.Account.Profile.Email != "alice@example.com" || len(export.Account.Posts) != 1 ||
+		len(export.Account.DirectMessages) != 1 || len(export.Account.Media) != 1 {
+		t.Fatalf("unexpected export: %+v", export.Account)
+	}
+
+	_, token := createAccessToken(t, srv.URL, cookies, "read")
+	resp, _ = bearerJSON(t, http.MethodGet, srv.URL+"/api/me/export", token, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("export with access token: %d", resp.StatusCode)
+	}
+}
+
+func TestDeleteAccountGracePeriod(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	cookies := loginUser(t, srv.URL, "alice@example.com")
+
+	resp, _ := doJSON(t, http.MethodDelete, srv.URL+"/api/me", map[string]string{"password": "wrong"}, cookies)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("delete with wrong password: %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, http.MethodDelete, srv.URL+"/api/me", map[string]string{"password": "password123"}, cookies)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("delete status: %d", resp.StatusCode)
+	}
+
+	resp, _ = doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, cookies)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("session survived deletion request: %d", resp.StatusCode)
+	}
+
+	loginUser(t, srv.URL, "alice@example.com")
+	var scheduled sql.NullString
+	_ = db.QueryRow("SELECT deletion_scheduled_at FROM users WHERE email = ?", "alice@example.com").Scan(&scheduled)
+	if scheduled.Valid {
+		t.Fatalf("signing in did not cancel deletion")
+	}
+}
+
+func TestPurgeDeletedAccount(t *testing.T) {
+	srv, cfg, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	registerUser(t, srv.URL, "bob@example.com")
+	cookies := loginUser(t, srv.URL, "alice@example.com")
+	alice := userID(t, db, "alice@example.com")
+	bob := userID(t, db, "bob@example.com")
+
+	mediaPath := uploadMedia(t, srv.URL, cookies)
+	postJSON(t, srv.URL+"/api/posts", map[string]any{"text": "bye", "visibility": "public"}, cookies)
+	resp, body := postJSON(t, srv.URL+"/api/groups", map[string]any{"title": "Raiders", "description": "weekly"}, cookies)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create group: %d", resp.StatusCode)
+	}
+	var group struct{ ID int64 }
+	_ = json.Unmarshal(body, &group)
+	if _, err := db.Exec("INSERT INTO group_members (group_id, user_id, role) VALUES (?, ?, 'member')", group.ID, bob); err != nil {
+		t.Fatalf("add member: %v", err)
+	}
+
+	resp, _ = doJSON(t, http.MethodDelete, srv.URL+"/api/me", map[string]string{"password": "password123"}, cookies)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("delete status: %d", resp.StatusCode)
+	}
+	if _, err := db.Exec("UPDATE users SET deletion_scheduled_at = datetime('now', '-1 minute') WHERE id = ?", alice); err != nil {
+		t.Fatalf("expire grace period: %v", err)
+	}
+	jobs.PurgeDeletedAccounts(context.Background(), cfg, db)
+
+	var count int
+	_ = db.QueryRow("SELECT COUNT(*) FROM users WHERE id = ?", alice).Scan(&count)
+	if count != 0 {
+		t.Fatalf("user not purged")
+	}
+	_ = db.QueryRow("SELECT COUNT(*) FROM posts WHERE user_id = ?", alice).Scan(&count)
+	if count != 0 {
+		t.Fatalf("posts not cascaded: %d", count)
+	}
+	var creator int64
+	var role string
+	_ = db.QueryRow("SELECT creator_id FROM groups WHERE id = ?", group.ID).Scan(&creator)
+	_ = db.QueryRow("SELECT role FROM group_members WHERE group_id = ? AND user_id = ?", group.ID, bob).Scan(&role)
+	if creator != bob || role != "creator" {
+		t.Fatalf("group not handed over: creator=%d role=%q", creator, role)
+	}
+	if _, err := os.Stat(cfg.MediaFilePath(mediaPath)); !os.IsNotExist(err) {
+		t.Fatalf("media file not removed: %v", err)
+	}
+}
+
+func TestReportEmailConflictsForgetsResolvedOnes(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "dup@example.com")
+	registerUser(t, srv.URL, "renamed@example.com")
+	// Stored before emails were trimmed, this one clashes with dup@.
+	result, err := db.Exec("INSERT INTO users (email, password_hash, first_name, last_name, dob) VALUES (' dup@example.com', 'x', 'Old', 'Account', '1990-01-01')")
+	if err != nil {
+		t.Fatalf("insert clashing user: %v", err)
+	}
+	clashID, _ := result.LastInsertId()
+	for _, id := range []int64{userID(t, db, "dup@example.com"), clashID, userID(t, db, "renamed@example.com")} {
+		if _, err := db.Exec("INSERT INTO email_conflicts (user_id, email, normalized_email) SELECT id, email, lower(trim(email)) FROM users WHERE id = ?", id); err != nil {
+			t.Fatalf("record conflict: %v", err)
+		}
+	}
+
+	jobs.ReportEmailConflicts(context.Background(), db)
+	var open int
+	_ = db.QueryRow("SELECT COUNT(*) FROM email_conflicts").Scan(&open)
+	if open != 2 {
+		t.Fatalf("open conflicts after the resolved one was pruned: %d", open)
+	}
+
+	if _, err := db.Exec("UPDATE users SET email = 'old@example.com' WHERE id = ?", clashID); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	jobs.ReportEmailConflicts(context.Background(), db)
+	_ = db.QueryRow("SELECT COUNT(*) FROM email_conflicts").Scan(&open)
+	if open != 0 {
+		t.Fatalf("conflicts left after resolving: %d", open)
+	}
+}
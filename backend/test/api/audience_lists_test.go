@@ -0,0 +1,127 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func feedTexts(t *testing.T, baseURL string, cookies []*http.Cookie) map[string]bool {
+	t.Helper()
+	resp, body := doJSON(t, http.MethodGet, baseURL+"/api/feed", nil, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("feed: %d %s", resp.StatusCode, body)
+	}
+	var payload struct {
+		Posts []struct {
+			Text string `json:"text"`
+		} `json:"posts"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	texts := map[string]bool{}
+	for _, post := range payload.Posts {
+		texts[post.Text] = true
+	}
+	return texts
+}
+
+func TestAudienceLists(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	cookies, ids := registerUsers(t, srv.URL, db, "alice", "bob", "carol", "dave")
+	for _, name := range []string{"bob", "carol"} {
+		postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": ids["alice"]}, cookies[name])
+	}
+
+	resp, body := postJSON(t, srv.URL+"/api/audience-lists", map[string]any{"name": "Squad", "member_ids": []int64{ids["dave"]}}, cookies["alice"])
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("non-follower member: %d %s", resp.StatusCode, body)
+	}
+	resp, body = postJSON(t, srv.URL+"/api/audience-lists", map[string]any{"name": "Squad", "member_ids": []int64{ids["bob"]}}, cookies["alice"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create list: %d %s", resp.StatusCode, body)
+	}
+	var list struct {
+		ID        int64   `json:"id"`
+		MemberIDs []int64 `json:"member_ids"`
+	}
+	_ = json.Unmarshal(body, &list)
+	if len(list.MemberIDs) != 1 || list.MemberIDs[0] != ids["bob"] {
+		t.Fatalf("members: %s", body)
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/audience-lists", map[string]any{"name": "squad"}, cookies["alice"])
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("duplicate name: %d", resp.StatusCode)
+	}
+	listURL := srv.URL + "/api/audience-lists/" + strconv.FormatInt(list.ID, 10)
+	if resp, _ := doJSON(t, http.MethodGet, listURL, nil, cookies["bob"]); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("other user's list: %d", resp.StatusCode)
+	}
+	if resp, _ := postJSON(t, srv.URL+"/api/posts", map[string]any{"text": "hi", "visibility": "private", "audience_list_id": list.ID}, cookies["bob"]); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("post to other user's list: %d", resp.StatusCode)
+	}
+
+	resp, body = postJSON(t, srv.URL+"/api/posts", map[string]any{"text": "squad only", "visibility": "private", "audience_list_id": list.ID}, cookies["alice"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create post: %d %s", resp.StatusCode, body)
+	}
+	var post struct{ ID int64 }
+	_ = json.Unmarshal(body, &post)
+	commentsURL := srv.URL + "/api/posts/" + strconv.FormatInt(post.ID, 10) + "/comments"
+
+	if !feedTexts(t, srv.URL, cookies["bob"])["squad only"] {
+		t.Fatalf("member cannot see post")
+	}
+	if feedTexts(t, srv.URL, cookies["carol"])["squad only"] {
+		t.Fatalf("non-member sees post")
+	}
+	if resp, _ := doJSON(t, http.MethodGet, commentsURL, nil, cookies["carol"]); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("non-member comments: %d", resp.StatusCode)
+	}
+
+	resp, body = patchJSON(t, listURL, map[string]any{"member_ids": []int64{ids["carol"]}}, cookies["alice"])
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update list: %d %s", resp.StatusCode, body)
+	}
+	if feedTexts(t, srv.URL, cookies["bob"])["squad only"] || !feedTexts(t, srv.URL, cookies["carol"])["squad only"] {
+		t.Fatalf("visibility did not follow members")
+	}
+	if resp, _ := doJSON(t, http.MethodGet, commentsURL, nil, cookies["carol"]); resp.StatusCode != http.StatusOK {
+		t.Fatalf("member comments: %d", resp.StatusCode)
+	}
+
+	if resp, _ := doJSON(t, http.MethodDelete, listURL, nil, cookies["alice"]); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete list: %d", resp.StatusCode)
+	}
+	if feedTexts(t, srv.URL, cookies["carol"])["squad only"] {
+		t.Fatalf("post visible after list deleted")
+	}
+
+	resp, body = postJSON(t, srv.URL+"/api/audience-lists", map[string]any{"name": "Duo", "member_ids": []int64{ids["bob"], ids["carol"]}}, cookies["alice"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create list: %d %s", resp.StatusCode, body)
+	}
+	_ = json.Unmarshal(body, &list)
+	postJSON(t, srv.URL+"/api/posts", map[string]any{"text": "duo only", "visibility": "private", "audience_list_id": list.ID}, cookies["alice"])
+	if resp, _ := doJSON(t, http.MethodDelete, srv.URL+"/api/follows/"+strconv.FormatInt(ids["alice"], 10), nil, cookies["bob"]); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unfollow: %d", resp.StatusCode)
+	}
+	if resp, _ := doJSON(t, http.MethodDelete, srv.URL+"/api/followers/"+strconv.FormatInt(ids["carol"], 10), nil, cookies["alice"]); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("remove follower: %d", resp.StatusCode)
+	}
+	for _, name := range []string{"bob", "carol"} {
+		postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": ids["alice"]}, cookies[name])
+		if feedTexts(t, srv.URL, cookies[name])["duo only"] {
+			t.Fatalf("%s kept list membership after the follow ended", name)
+		}
+	}
+	_, body = doJSON(t, http.MethodGet, srv.URL+"/api/audience-lists/"+strconv.FormatInt(list.ID, 10), nil, cookies["alice"])
+	list.MemberIDs = nil
+	_ = json.Unmarshal(body, &list)
+	if len(list.MemberIDs) != 0 {
+		t.Fatalf("members after follows ended: %s", body)
+	}
+}
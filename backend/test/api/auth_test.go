@@ -29,6 +29,7 @@ func TestAuthFlow(t *testing.T) {
 	for _, c := range cookies {
 		req.AddCookie(c)
 	}
+	withCSRF(req)
 	resp, err = http.DefaultClient.Do(req)
 	if err != nil {
 		t.Fatalf("logout failed: %v", err)
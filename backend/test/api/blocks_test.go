@@ -0,0 +1,176 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func feedPostIDs(t *testing.T, baseURL string, cookies []*http.Cookie) map[int64]bool {
+	t.Helper()
+	resp, body := doJSON(t, http.MethodGet, baseURL+"/api/feed", nil, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("feed: %d", resp.StatusCode)
+	}
+	var payload struct {
+		Posts []struct {
+			ID int64 `json:"id"`
+		} `json:"posts"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	ids := map[int64]bool{}
+	for _, post := range payload.Posts {
+		ids[post.ID] = true
+	}
+	return ids
+}
+
+func createPost(t *testing.T, baseURL, text string, cookies []*http.Cookie) int64 {
+	t.Helper()
+	resp, body := postJSON(t, baseURL+"/api/posts", map[string]any{"text": text, "visibility": "public"}, cookies)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create post: %d", resp.StatusCode)
+	}
+	var post struct{ ID int64 }
+	_ = json.Unmarshal(body, &post)
+	return post.ID
+}
+
+func TestBlockHidesUsersFromEachOther(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerNamed(t, srv.URL, "alice@example.com", "Alice", "Martin", "alice")
+	registerNamed(t, srv.URL, "bob@example.com", "Bob", "Smith", "bob")
+	alice := loginUser(t, srv.URL, "alice@example.com")
+	bob := loginUser(t, srv.URL, "bob@example.com")
+	aliceID := userID(t, db, "alice@example.com")
+	bobID := userID(t, db, "bob@example.com")
+
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": aliceID}, bob)
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": bobID}, alice)
+	postID := createPost(t, srv.URL, "gg", alice)
+	resp, _ := postJSON(t, srv.URL+"/api/posts/"+strconv.FormatInt(postID, 10)+"/comments", map[string]any{"text": "ez"}, bob)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("comment before block: %d", resp.StatusCode)
+	}
+
+	if resp, _ := postJSON(t, srv.URL+"/api/blocks/"+strconv.FormatInt(bobID, 10), nil, alice); resp.StatusCode != http.StatusOK {
+		t.Fatalf("block: %d", resp.StatusCode)
+	}
+	var follows int
+	_ = db.QueryRow("SELECT COUNT(*) FROM follows WHERE follower_id IN (?, ?)", aliceID, bobID).Scan(&follows)
+	if follows != 0 {
+		t.Fatalf("follows survived block: %d", follows)
+	}
+
+	if feedPostIDs(t, srv.URL, bob)[postID] {
+		t.Fatalf("blocked user sees post in feed")
+	}
+	if resp, _ := doJSON(t, http.MethodGet, srv.URL+"/api/users/"+strconv.FormatInt(aliceID, 10), nil, bob); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("blocked user sees profile: %d", resp.StatusCode)
+	}
+	resp, body := doJSON(t, http.MethodGet, srv.URL+"/api/users/"+strconv.FormatInt(aliceID, 10)+"/posts", nil, bob)
+	var posts struct{ Posts []any }
+	_ = json.Unmarshal(body, &posts)
+	if resp.StatusCode != http.StatusOK || len(posts.Posts) != 0 {
+		t.Fatalf("blocked user sees posts: %d %s", resp.StatusCode, body)
+	}
+	if resp, _ := postJSON(t, srv.URL+"/api/posts/"+strconv.FormatInt(postID, 10)+"/comments", map[string]any{"text": "again"}, bob); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("blocked user can comment: %d", resp.StatusCode)
+	}
+	if resp, _ := postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": aliceID}, bob); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("blocked user can follow: %d", resp.StatusCode)
+	}
+	if cards := searchUsers(t, srv.URL, "alice", bob); len(cards) != 0 {
+		t.Fatalf("blocked user finds blocker: %+v", cards)
+	}
+	resp, body = doJSON(t, http.MethodGet, srv.URL+"/api/posts/"+strconv.FormatInt(postID, 10)+"/comments", nil, alice)
+	var comments struct{ Comments []any }
+	_ = json.Unmarshal(body, &comments)
+	if resp.StatusCode != http.StatusOK || len(comments.Comments) != 0 {
+		t.Fatalf("blocker sees blocked user's comments: %s", body)
+	}
+
+	resp, body = doJSON(t, http.MethodGet, srv.URL+"/api/blocks", nil, alice)
+	var blocks struct {
+		Users []map[string]any `json:"users"`
+	}
+	_ = json.Unmarshal(body, &blocks)
+	if len(blocks.Users) != 1 || blocks.Users[0]["id"] != float64(bobID) {
+		t.Fatalf("list blocks: %s", body)
+	}
+	if _, leaked := blocks.Users[0]["email"]; leaked {
+		t.Fatalf("block list leaks email: %s", body)
+	}
+	if _, leaked := blocks.Users[0]["dob"]; leaked {
+		t.Fatalf("block list leaks dob: %s", body)
+	}
+
+	if resp, _ := doJSON(t, http.MethodDelete, srv.URL+"/api/blocks/"+strconv.FormatInt(bobID, 10), nil, alice); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unblock: %d", resp.StatusCode)
+	}
+	if !feedPostIDs(t, srv.URL, bob)[postID] {
+		t.Fatalf("post still hidden after unblock")
+	}
+}
+
+func TestMuteHidesFeedAndNotifications(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "carol@example.com")
+	registerUser(t, srv.URL, "dave@example.com")
+	carol := loginUser(t, srv.URL, "carol@example.com")
+	dave := loginUser(t, srv.URL, "dave@example.com")
+	carolID := userID(t, db, "carol@example.com")
+	daveID := userID(t, db, "dave@example.com")
+	patchJSON(t, srv.URL+"/api/users/me", map[string]any{"is_public": false}, carol)
+
+	postID := createPost(t, srv.URL, "lfg", dave)
+	if resp, _ := postJSON(t, srv.URL+"/api/mutes/"+strconv.FormatInt(daveID, 10), nil, carol); resp.StatusCode != http.StatusOK {
+		t.Fatalf("mute: %d", resp.StatusCode)
+	}
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": carolID}, dave)
+
+	if feedPostIDs(t, srv.URL, carol)[postID] {
+		t.Fatalf("muted post in feed")
+	}
+	resp, body := doJSON(t, http.MethodGet, srv.URL+"/api/users/"+strconv.FormatInt(daveID, 10)+"/posts", nil, carol)
+	var posts struct{ Posts []any }
+	_ = json.Unmarshal(body, &posts)
+	if resp.StatusCode != http.StatusOK || len(posts.Posts) != 1 {
+		t.Fatalf("mute should not hide the profile's posts: %s", body)
+	}
+	_, body = doJSON(t, http.MethodGet, srv.URL+"/api/notifications", nil, carol)
+	var notifications struct{ Notifications []any }
+	_ = json.Unmarshal(body, &notifications)
+	if len(notifications.Notifications) != 0 {
+		t.Fatalf("muted user's notification shown: %s", body)
+	}
+
+	patchJSON(t, srv.URL+"/api/users/me", map[string]any{"is_public": false}, dave)
+	_, body = doJSON(t, http.MethodGet, srv.URL+"/api/mutes", nil, carol)
+	var mutes struct {
+		Users []map[string]any `json:"users"`
+	}
+	_ = json.Unmarshal(body, &mutes)
+	if len(mutes.Users) != 1 || mutes.Users[0]["limited"] != true {
+		t.Fatalf("list mutes: %s", body)
+	}
+	for _, field := range []string{"email", "dob", "first_name"} {
+		if _, leaked := mutes.Users[0][field]; leaked {
+			t.Fatalf("mute list leaks %s of a private account: %s", field, body)
+		}
+	}
+
+	doJSON(t, http.MethodDelete, srv.URL+"/api/mutes/"+strconv.FormatInt(daveID, 10), nil, carol)
+	_, body = doJSON(t, http.MethodGet, srv.URL+"/api/notifications", nil, carol)
+	_ = json.Unmarshal(body, &notifications)
+	if len(notifications.Notifications) != 1 {
+		t.Fatalf("notification not back after unmute: %s", body)
+	}
+}
@@ -0,0 +1,100 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCSRFProtection(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	cookies := loginUser(t, srv.URL, "alice@example.com")
+
+	post := `{"text":"hello","visibility":"public"}`
+	send := func(origin, csrfCookie, csrfHeader string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/posts", strings.NewReader(post))
+		req.Header.Set("Content-Type", "application/json")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if csrfCookie != "" {
+			req.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrfCookie})
+		}
+		if csrfHeader != "" {
+			req.Header.Set("X-CSRF-Token", csrfHeader)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := send("", "", ""); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("missing token status: %d", resp.StatusCode)
+	}
+	if resp := send("", "a", "b"); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("mismatched token status: %d", resp.StatusCode)
+	}
+	if resp := send("https://evil.example", "a", "a"); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("foreign origin status: %d", resp.StatusCode)
+	}
+
+	// Bootstrap the token the way the frontend does.
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/auth/csrf", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("csrf bootstrap: %v", err)
+	}
+	var payload struct {
+		Token string `json:"csrf_token"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&payload)
+	resp.Body.Close()
+	if payload.Token == "" || resp.Header.Get("Access-Control-Allow-Origin") != "http://localhost:3000" {
+		t.Fatalf("csrf bootstrap response: %+v %v", payload, resp.Header)
+	}
+	var issued string
+	for _, c := range resp.Cookies() {
+		if c.Name == "csrf_token" {
+			issued = c.Value
+		}
+	}
+	if issued != payload.Token {
+		t.Fatalf("cookie and body token differ")
+	}
+
+	if resp := send("http://localhost:3000", issued, payload.Token); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("valid csrf status: %d", resp.StatusCode)
+	}
+}
+
+func TestCORSOnlyReflectsAllowedOrigins(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	req, _ := http.NewRequest(http.MethodOptions, srv.URL+"/api/posts", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("preflight: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("foreign origin reflected: %v", resp.Header)
+	}
+	if !strings.Contains(resp.Header.Get("Vary"), "Origin") {
+		t.Fatalf("missing Vary: Origin")
+	}
+}
@@ -0,0 +1,56 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"backend/internal/config"
+)
+
+var verifyTokenPattern = regexp.MustCompile(`verify-email\?token=([0-9a-f]+)`)
+
+func TestEmailVerification(t *testing.T) {
+	srv, cfg, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.RequireVerifiedEmail = []string{"post"}
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	cookies := loginUser(t, srv.URL, "alice@example.com")
+	token := waitForMailToken(t, cfg, verifyTokenPattern)
+
+	post := map[string]any{"text": "hello", "visibility": "public"}
+	resp, _ := postJSON(t, srv.URL+"/api/posts", post, cookies)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("unverified post status: %d", resp.StatusCode)
+	}
+
+	resp, _ = postJSON(t, srv.URL+"/api/auth/verify-email/resend", map[string]any{}, cookies)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("resend should be throttled: %d", resp.StatusCode)
+	}
+
+	resp, _ = postJSON(t, srv.URL+"/api/auth/verify-email", map[string]any{"token": token}, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("verify status: %d", resp.StatusCode)
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/auth/verify-email", map[string]any{"token": token}, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("token reuse status: %d", resp.StatusCode)
+	}
+
+	_, body := doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, cookies)
+	var me struct {
+		EmailVerified bool `json:"email_verified"`
+	}
+	if err := json.Unmarshal(body, &me); err != nil || !me.EmailVerified {
+		t.Fatalf("me after verify: %s", body)
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/posts", post, cookies)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("verified post status: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,113 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type followListPage struct {
+	Users []struct {
+		ID               int64  `json:"id"`
+		Email            string `json:"email"`
+		DOB              string `json:"dob"`
+		FirstName        string `json:"first_name"`
+		Limited          bool   `json:"limited"`
+		FollowedByViewer bool   `json:"followed_by_viewer"`
+		FollowsViewer    bool   `json:"follows_viewer"`
+		Mutual           bool   `json:"mutual"`
+	} `json:"users"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+func followList(t *testing.T, baseURL string, userID int64, list string, query url.Values, cookies []*http.Cookie) (int, followListPage) {
+	t.Helper()
+	resp, body := doJSON(t, http.MethodGet, baseURL+"/api/users/"+strconv.FormatInt(userID, 10)+"/"+list+"?"+query.Encode(), nil, cookies)
+	var page followListPage
+	_ = json.Unmarshal(body, &page)
+	if strings.Contains(string(body), `"email"`) {
+		t.Fatalf("follow list leaks email: %s", body)
+	}
+	return resp.StatusCode, page
+}
+
+func TestFollowListsArePrivateAndPaginated(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	names := []string{"alice", "bob", "carol", "dave", "eve"}
+	for _, name := range names {
+		registerNamed(t, srv.URL, name+"@example.com", strings.ToUpper(name[:1])+name[1:], "Player", name)
+	}
+	cookies, ids := loginUsers(t, srv.URL, db, names...)
+	for _, name := range []string{"bob", "carol", "dave", "eve"} {
+		postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": ids["alice"]}, cookies[name])
+	}
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": ids["bob"]}, cookies["alice"])
+	patchJSON(t, srv.URL+"/api/users/me", map[string]any{"is_public": false}, cookies["eve"])
+
+	seen := map[int64]bool{}
+	query := url.Values{"limit": {"3"}}
+	status, page := followList(t, srv.URL, ids["alice"], "followers", query, cookies["carol"])
+	if status != http.StatusOK || len(page.Users) != 3 || page.NextCursor == nil {
+		t.Fatalf("first page: %d %+v", status, page)
+	}
+	for _, user := range page.Users {
+		seen[user.ID] = true
+	}
+	query.Set("cursor", *page.NextCursor)
+	status, page = followList(t, srv.URL, ids["alice"], "followers", query, cookies["carol"])
+	if status != http.StatusOK || len(page.Users) != 1 || page.NextCursor != nil || seen[page.Users[0].ID] {
+		t.Fatalf("second page: %d %+v", status, page)
+	}
+
+	status, page = followList(t, srv.URL, ids["alice"], "followers", nil, cookies["carol"])
+	for _, user := range page.Users {
+		if user.ID == ids["eve"] && (!user.Limited || user.FirstName != "") {
+			t.Fatalf("private follower not limited: %+v", user)
+		}
+		if user.ID == ids["carol"] && user.FollowedByViewer {
+			t.Fatalf("viewer flagged as following themselves: %+v", user)
+		}
+	}
+
+	status, page = followList(t, srv.URL, ids["alice"], "followers", nil, cookies["alice"])
+	for _, user := range page.Users {
+		if mutual := user.ID == ids["bob"]; user.Mutual != mutual || !user.FollowsViewer {
+			t.Fatalf("mutual flags for %d: %+v", user.ID, user)
+		}
+	}
+
+	status, page = followList(t, srv.URL, ids["alice"], "followers", url.Values{"q": {"car"}}, cookies["bob"])
+	if status != http.StatusOK || len(page.Users) != 1 || page.Users[0].ID != ids["carol"] {
+		t.Fatalf("search within list: %d %+v", status, page)
+	}
+	status, page = followList(t, srv.URL, ids["alice"], "followers", url.Values{"q": {"player"}}, cookies["bob"])
+	for _, user := range page.Users {
+		if user.ID == ids["eve"] {
+			t.Fatalf("private follower matched on a hidden name: %+v", user)
+		}
+	}
+	if status != http.StatusOK || len(page.Users) != 3 {
+		t.Fatalf("name search within list: %d %+v", status, page)
+	}
+	status, page = followList(t, srv.URL, ids["alice"], "followers", url.Values{"q": {"eve"}}, cookies["bob"])
+	if status != http.StatusOK || len(page.Users) != 1 || !page.Users[0].Limited {
+		t.Fatalf("nickname search within list: %d %+v", status, page)
+	}
+	status, page = followList(t, srv.URL, ids["bob"], "following", nil, cookies["carol"])
+	if status != http.StatusOK || len(page.Users) != 1 || page.Users[0].ID != ids["alice"] {
+		t.Fatalf("following: %d %+v", status, page)
+	}
+
+	if status, _ := followList(t, srv.URL, ids["eve"], "following", nil, cookies["bob"]); status != http.StatusForbidden {
+		t.Fatalf("private user's list: %d", status)
+	}
+	if status, _ := followList(t, srv.URL, ids["alice"], "followers", url.Values{"cursor": {"nope"}}, cookies["bob"]); status != http.StatusBadRequest {
+		t.Fatalf("bad cursor: %d", status)
+	}
+}
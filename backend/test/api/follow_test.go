@@ -1,7 +1,9 @@
 package api_test
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
 	"testing"
 )
 
@@ -41,3 +43,80 @@ func TestFollowRequestFlow(t *testing.T) {
 		t.Fatalf("incoming status: %d", resp.StatusCode)
 	}
 }
+
+func outgoingRequestIDs(t *testing.T, baseURL string, cookies []*http.Cookie) []int64 {
+	t.Helper()
+	_, body := doJSON(t, http.MethodGet, baseURL+"/api/follows/requests/outgoing", nil, cookies)
+	var payload struct {
+		Requests []struct{ ID int64 } `json:"requests"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	ids := []int64{}
+	for _, request := range payload.Requests {
+		ids = append(ids, request.ID)
+	}
+	return ids
+}
+
+func TestCancelRequestsAndRemoveFollowers(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	registerUser(t, srv.URL, "bob@example.com")
+	registerUser(t, srv.URL, "carol@example.com")
+	alice := loginUser(t, srv.URL, "alice@example.com")
+	bob := loginUser(t, srv.URL, "bob@example.com")
+	carol := loginUser(t, srv.URL, "carol@example.com")
+	aliceID := userID(t, db, "alice@example.com")
+	bobID := userID(t, db, "bob@example.com")
+	carolID := userID(t, db, "carol@example.com")
+	patchJSON(t, srv.URL+"/api/users/me", map[string]any{"is_public": false}, alice)
+
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": aliceID}, bob)
+	requests := outgoingRequestIDs(t, srv.URL, bob)
+	if len(requests) != 1 {
+		t.Fatalf("outgoing requests: %v", requests)
+	}
+	cancelURL := srv.URL + "/api/follows/request/" + strconv.FormatInt(requests[0], 10)
+	if resp, _ := doJSON(t, http.MethodDelete, cancelURL, nil, alice); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("recipient cancelled the request: %d", resp.StatusCode)
+	}
+	if resp, _ := doJSON(t, http.MethodDelete, cancelURL, nil, bob); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("cancel: %d", resp.StatusCode)
+	}
+	if requests := outgoingRequestIDs(t, srv.URL, bob); len(requests) != 0 {
+		t.Fatalf("request still pending: %v", requests)
+	}
+	var notifications int
+	_ = db.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = ? AND type = 'follow_request'", aliceID).Scan(&notifications)
+	if notifications != 0 {
+		t.Fatalf("notification left behind: %d", notifications)
+	}
+
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": aliceID}, bob)
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": aliceID}, carol)
+	if resp, _ := patchJSON(t, srv.URL+"/api/users/me", map[string]any{"accept_pending_requests": true}, alice); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bulk accept without going public: %d", resp.StatusCode)
+	}
+	if resp, _ := patchJSON(t, srv.URL+"/api/users/me", map[string]any{"is_public": true, "accept_pending_requests": true}, alice); resp.StatusCode != http.StatusOK {
+		t.Fatalf("go public: %d", resp.StatusCode)
+	}
+	for _, id := range []int64{bobID, carolID} {
+		var follows int
+		_ = db.QueryRow("SELECT COUNT(*) FROM follows WHERE follower_id = ? AND followee_id = ?", id, aliceID).Scan(&follows)
+		if follows != 1 {
+			t.Fatalf("request from %d not accepted", id)
+		}
+	}
+
+	if resp, _ := doJSON(t, http.MethodDelete, srv.URL+"/api/followers/"+strconv.FormatInt(bobID, 10), nil, alice); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("remove follower: %d", resp.StatusCode)
+	}
+	var remaining int
+	_ = db.QueryRow("SELECT COUNT(*) FROM follows WHERE followee_id = ?", aliceID).Scan(&remaining)
+	if remaining != 1 {
+		t.Fatalf("followers after removal: %d", remaining)
+	}
+}
@@ -0,0 +1,148 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+type gamingProfile struct {
+	Platforms []struct {
+		Platform   string `json:"platform"`
+		AccountID  string `json:"account_id"`
+		Visibility string `json:"visibility"`
+	} `json:"platforms"`
+	Games []struct {
+		Slug  string   `json:"slug"`
+		Name  string   `json:"name"`
+		Rank  *string  `json:"rank"`
+		Roles []string `json:"roles"`
+	} `json:"games"`
+}
+
+func viewGaming(t *testing.T, baseURL string, id int64, cookies []*http.Cookie) gamingProfile {
+	t.Helper()
+	resp, body := doJSON(t, http.MethodGet, baseURL+"/api/users/"+strconv.FormatInt(id, 10), nil, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("profile %d: %d", id, resp.StatusCode)
+	}
+	var payload struct {
+		Gaming gamingProfile `json:"gaming"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	return payload.Gaming
+}
+
+func TestGamingProfile(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerNamed(t, srv.URL, "alice@example.com", "Alice", "Martin", "alice")
+	registerNamed(t, srv.URL, "bob@example.com", "Bob", "Smith", "bob")
+	registerNamed(t, srv.URL, "carol@example.com", "Carol", "Lee", "carol")
+	alice := loginUser(t, srv.URL, "alice@example.com")
+	bob := loginUser(t, srv.URL, "bob@example.com")
+	carol := loginUser(t, srv.URL, "carol@example.com")
+	aliceID := userID(t, db, "alice@example.com")
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": aliceID}, bob)
+
+	resp, body := patchJSON(t, srv.URL+"/api/users/me/gaming", map[string]any{
+		"platforms": []map[string]any{
+			{"platform": "Steam", "account_id": "alice_gg"},
+			{"platform": "riot", "account_id": "Alice#EUW", "visibility": "followers"},
+			{"platform": "psn", "account_id": "alice-psn", "visibility": "private"},
+		},
+		"games": []map[string]any{
+			{"name": "League of Legends", "rank": "Gold II", "roles": []string{"support", "jungle"}},
+			{"name": "Valorant"},
+		},
+	}, alice)
+	var own gamingProfile
+	_ = json.Unmarshal(body, &own)
+	if resp.StatusCode != http.StatusOK || len(own.Platforms) != 3 || len(own.Games) != 2 {
+		t.Fatalf("update gaming: %d %s", resp.StatusCode, body)
+	}
+	if own.Games[0].Slug != "league-of-legends" || own.Games[0].Rank == nil || len(own.Games[0].Roles) != 2 || own.Games[1].Rank != nil {
+		t.Fatalf("games: %+v", own.Games)
+	}
+
+	if gaming := viewGaming(t, srv.URL, aliceID, carol); len(gaming.Platforms) != 1 || gaming.Platforms[0].Platform != "steam" || len(gaming.Games) != 2 {
+		t.Fatalf("stranger view: %+v", gaming)
+	}
+	if gaming := viewGaming(t, srv.URL, aliceID, bob); len(gaming.Platforms) != 2 {
+		t.Fatalf("follower view: %+v", gaming)
+	}
+	if gaming := viewGaming(t, srv.URL, aliceID, alice); len(gaming.Platforms) != 3 {
+		t.Fatalf("own view: %+v", gaming)
+	}
+
+	resp, body = patchJSON(t, srv.URL+"/api/users/me/gaming", map[string]any{"games": []map[string]any{{"name": "Apex Legends"}}}, alice)
+	_ = json.Unmarshal(body, &own)
+	if resp.StatusCode != http.StatusOK || len(own.Platforms) != 3 || len(own.Games) != 1 {
+		t.Fatalf("partial update: %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = patchJSON(t, srv.URL+"/api/users/me/gaming", map[string]any{
+		"platforms": []map[string]any{{"platform": "origin", "account_id": "x"}, {"platform": "xbox", "account_id": ""}},
+		"games":     []map[string]any{{"name": "Dota 2"}, {"name": "dota-2"}},
+	}, alice)
+	var invalid struct {
+		Fields map[string]string `json:"fields"`
+	}
+	_ = json.Unmarshal(body, &invalid)
+	if resp.StatusCode != http.StatusBadRequest || len(invalid.Fields) != 3 || invalid.Fields["games[1]"] == "" {
+		t.Fatalf("invalid gaming: %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestSearchUsersByGameAndPlatform(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerNamed(t, srv.URL, "alice@example.com", "Alice", "Martin", "alice")
+	registerNamed(t, srv.URL, "bob@example.com", "Bob", "Smith", "bob")
+	registerNamed(t, srv.URL, "carol@example.com", "Carol", "Lee", "carol")
+	alice := loginUser(t, srv.URL, "alice@example.com")
+	bob := loginUser(t, srv.URL, "bob@example.com")
+	carol := loginUser(t, srv.URL, "carol@example.com")
+	patchJSON(t, srv.URL+"/api/users/me/gaming", map[string]any{
+		"platforms": []map[string]any{{"platform": "steam", "account_id": "alice_gg"}},
+		"games":     []map[string]any{{"name": "Valorant"}},
+	}, alice)
+	patchJSON(t, srv.URL+"/api/users/me/gaming", map[string]any{
+		"platforms": []map[string]any{{"platform": "steam", "account_id": "bob_gg", "visibility": "private"}},
+		"games":     []map[string]any{{"name": "Valorant"}},
+	}, bob)
+
+	search := func(query url.Values) []searchCard {
+		t.Helper()
+		resp, body := doJSON(t, http.MethodGet, srv.URL+"/api/users/search?"+query.Encode(), nil, carol)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("search %v: %d %s", query, resp.StatusCode, body)
+		}
+		var payload struct {
+			Users []searchCard `json:"users"`
+		}
+		_ = json.Unmarshal(body, &payload)
+		return payload.Users
+	}
+	if users := search(url.Values{"game": {"VALORANT"}}); len(users) != 2 {
+		t.Fatalf("game filter: %+v", users)
+	}
+	if users := search(url.Values{"platform": {"steam"}}); len(users) != 1 || users[0].Handle != "alice" {
+		t.Fatalf("platform filter shows hidden accounts: %+v", users)
+	}
+	if users := search(url.Values{"q": {"bob"}, "game": {"valorant"}}); len(users) != 1 || users[0].Handle != "bob" {
+		t.Fatalf("query with filter: %+v", users)
+	}
+	if users := search(url.Values{"game": {"fortnite"}}); len(users) != 0 {
+		t.Fatalf("unknown game: %+v", users)
+	}
+	if resp, _ := doJSON(t, http.MethodGet, srv.URL+"/api/users/search?platform=origin", nil, carol); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad platform: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,177 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+)
+
+func getByHandle(t *testing.T, baseURL, handle string, cookies []*http.Cookie) (*http.Response, string, int64) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, baseURL+"/api/users/by-handle/"+handle, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("by-handle: %v", err)
+	}
+	defer resp.Body.Close()
+	var payload struct {
+		ID     int64  `json:"id"`
+		Handle string `json:"handle"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&payload)
+	return resp, payload.Handle, payload.ID
+}
+
+func TestRegisterAssignsHandles(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerNamed(t, srv.URL, "alice@example.com", "Alice", "Martin", "ShadowHunter")
+	registerUser(t, srv.URL, "bob@example.com")
+	registerUser(t, srv.URL, "carol@example.com")
+	cookies := loginUser(t, srv.URL, "bob@example.com")
+
+	for _, tc := range []struct {
+		email string
+		want  string
+	}{
+		{"alice@example.com", "shadowhunter"},
+		{"bob@example.com", "test_user"},
+		{"carol@example.com", "test_user2"},
+	} {
+		resp, handle, id := getByHandle(t, srv.URL, tc.want, cookies)
+		if resp.StatusCode != http.StatusOK || handle != tc.want || id != userID(t, db, tc.email) {
+			t.Fatalf("handle %s: status=%d handle=%q id=%d", tc.want, resp.StatusCode, handle, id)
+		}
+	}
+	// Numbered variants of "user" would look like generated handles.
+	registerNamed(t, srv.URL, "erin@example.com", "Erin", "Doe", "User")
+	registerNamed(t, srv.URL, "frank@example.com", "Frank", "Doe", "User")
+	for _, tc := range []struct {
+		email string
+		want  string
+	}{
+		{"erin@example.com", "user"},
+		{"frank@example.com", "user" + strconv.FormatInt(userID(t, db, "frank@example.com"), 10)},
+	} {
+		resp, handle, id := getByHandle(t, srv.URL, tc.want, cookies)
+		if resp.StatusCode != http.StatusOK || handle != tc.want || id != userID(t, db, tc.email) {
+			t.Fatalf("handle %s: status=%d handle=%q id=%d", tc.want, resp.StatusCode, handle, id)
+		}
+	}
+	if resp, _, _ := getByHandle(t, srv.URL, "@SHADOWHunter", cookies); resp.StatusCode != http.StatusOK {
+		t.Fatalf("lookup is case-sensitive: %d", resp.StatusCode)
+	}
+	if resp, _, _ := getByHandle(t, srv.URL, "nobody_here", cookies); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown handle: %d", resp.StatusCode)
+	}
+
+	// Ordered: the second case relies on the first having taken the handle.
+	for i, tc := range []struct {
+		handle string
+		status int
+	}{
+		{"Raid_Boss", http.StatusCreated},
+		{"raid_boss", http.StatusConflict},
+		{"admin", http.StatusBadRequest},
+		{"user42", http.StatusBadRequest},
+		{"no", http.StatusBadRequest},
+		{"bad-name", http.StatusBadRequest},
+	} {
+		resp, _ := postJSON(t, srv.URL+"/api/auth/register", map[string]any{
+			"email": "dan" + strconv.Itoa(i) + "@example.com", "password": "password123", "first_name": "Dan",
+			"last_name": "Doe", "dob": "1990-01-01", "handle": tc.handle,
+		}, nil)
+		if resp.StatusCode != tc.status {
+			t.Fatalf("register with handle %q: got %d want %d", tc.handle, resp.StatusCode, tc.status)
+		}
+	}
+}
+
+func TestChangeHandle(t *testing.T) {
+	srv, _, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.HandleChangeCooldown = 24 * time.Hour
+		cfg.HandleHoldPeriod = 7 * 24 * time.Hour
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	registerNamed(t, srv.URL, "alice@example.com", "Alice", "Martin", "alice")
+	registerNamed(t, srv.URL, "bob@example.com", "Bob", "Smith", "bob")
+	alice := loginUser(t, srv.URL, "alice@example.com")
+	bob := loginUser(t, srv.URL, "bob@example.com")
+
+	if resp, _ := patchJSON(t, srv.URL+"/api/users/me/handle", map[string]string{"handle": "support"}, alice); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("reserved handle: %d", resp.StatusCode)
+	}
+	if resp, _ := patchJSON(t, srv.URL+"/api/users/me/handle", map[string]string{"handle": "BOB"}, alice); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("taken handle: %d", resp.StatusCode)
+	}
+	resp, body := patchJSON(t, srv.URL+"/api/users/me/handle", map[string]string{"handle": "Alice_Plays"}, alice)
+	var updated struct {
+		Handle string `json:"handle"`
+	}
+	_ = json.Unmarshal(body, &updated)
+	if resp.StatusCode != http.StatusOK || updated.Handle != "alice_plays" {
+		t.Fatalf("change handle: %d %s", resp.StatusCode, body)
+	}
+
+	resp, handle, _ := getByHandle(t, srv.URL, "alice", bob)
+	if resp.StatusCode != http.StatusFound || handle != "alice_plays" || resp.Header.Get("Location") != "/api/users/by-handle/alice_plays" {
+		t.Fatalf("old handle redirect: %d %q %q", resp.StatusCode, handle, resp.Header.Get("Location"))
+	}
+
+	resp, _ = patchJSON(t, srv.URL+"/api/users/me/handle", map[string]string{"handle": "alice_again"}, alice)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("cooldown: %d", resp.StatusCode)
+	}
+	if resp, _ := patchJSON(t, srv.URL+"/api/users/me/handle", map[string]string{"handle": "alice"}, bob); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("held handle: %d", resp.StatusCode)
+	}
+
+	if _, err := db.Exec("UPDATE handle_history SET released_at = datetime('now', '-8 days') WHERE handle = 'alice'"); err != nil {
+		t.Fatalf("expire hold: %v", err)
+	}
+	if resp, _ := patchJSON(t, srv.URL+"/api/users/me/handle", map[string]string{"handle": "alice"}, bob); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expired hold: %d", resp.StatusCode)
+	}
+	resp, _, id := getByHandle(t, srv.URL, "alice", alice)
+	if resp.StatusCode != http.StatusOK || id != userID(t, db, "bob@example.com") {
+		t.Fatalf("released handle: %d id=%d", resp.StatusCode, id)
+	}
+
+	// bob gave up "bob" to take "alice": it is held for new accounts too.
+	resp, _ = postJSON(t, srv.URL+"/api/auth/register", map[string]any{
+		"email": "dan@example.com", "password": "password123", "first_name": "Dan",
+		"last_name": "Doe", "dob": "1990-01-01", "handle": "bob",
+	}, nil)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("register with held handle: %d", resp.StatusCode)
+	}
+	registerNamed(t, srv.URL, "dave@example.com", "Dave", "Doe", "bob")
+	if resp, handle, _ := getByHandle(t, srv.URL, "bob2", bob); resp.StatusCode != http.StatusOK || handle != "bob2" {
+		t.Fatalf("derived handle skips held one: %d %q", resp.StatusCode, handle)
+	}
+	if _, err := db.Exec("UPDATE handle_history SET released_at = datetime('now', '-8 days') WHERE handle = 'bob'"); err != nil {
+		t.Fatalf("expire hold: %v", err)
+	}
+	registerNamed(t, srv.URL, "erin@example.com", "Erin", "Doe", "bob")
+	resp, _, id = getByHandle(t, srv.URL, "bob", bob)
+	if resp.StatusCode != http.StatusOK || id != userID(t, db, "erin@example.com") {
+		t.Fatalf("derived handle after hold: %d id=%d", resp.StatusCode, id)
+	}
+
+	cards := searchUsers(t, srv.URL, "alice_pl", bob)
+	if len(cards) != 1 || cards[0].Handle != "alice_plays" {
+		t.Fatalf("search by handle: %+v", cards)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"backend/internal/config"
 	apphttp "backend/internal/http"
@@ -17,6 +18,13 @@ import (
 )
 
 func newTestServer(t *testing.T) (*httptest.Server, config.Config, *sql.DB) {
+	t.Helper()
+	return newTestServerWithConfig(t, nil)
+}
+
+// newTestServerWithConfig is newTestServer with a hook to adjust the config
+// before the router is built.
+func newTestServerWithConfig(t *testing.T, configure func(*config.Config)) (*httptest.Server, config.Config, *sql.DB) {
 	t.Helper()
 	tmp := t.TempDir()
 	cfg := config.Config{
@@ -27,6 +35,17 @@ func newTestServer(t *testing.T) (*httptest.Server, config.Config, *sql.DB) {
 		CookieSecure: false,
 		CORSOrigin:  "http://localhost:3000",
 		Env:         "test",
+		SessionIdleTTL:        7 * 24 * time.Hour,
+		SessionAbsoluteTTL:    30 * 24 * time.Hour,
+		SessionRotateInterval: 24 * time.Hour,
+		MailDriver:            "file",
+		MailDir:               filepath.Join(tmp, "mail"),
+		MailFrom:              "no-reply@test.local",
+		PasswordResetTTL:      time.Hour,
+		EmailVerificationTTL:  time.Hour,
+	}
+	if configure != nil {
+		configure(&cfg)
 	}
 	db, err := sqlite.Open(cfg.DBPath)
 	if err != nil {
@@ -39,6 +58,15 @@ func newTestServer(t *testing.T) (*httptest.Server, config.Config, *sql.DB) {
 	return srv, cfg, db
 }
 
+// testCSRFToken is sent as both the CSRF cookie and header, which is all
+// the double-submit check compares.
+const testCSRFToken = "test-csrf-token"
+
+func withCSRF(req *http.Request) {
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: testCSRFToken})
+	req.Header.Set("X-CSRF-Token", testCSRFToken)
+}
+
 func postJSON(t *testing.T, url string, body any, cookies []*http.Cookie) (*http.Response, []byte) {
 	t.Helper()
 	data, _ := json.Marshal(body)
@@ -47,6 +75,7 @@ func postJSON(t *testing.T, url string, body any, cookies []*http.Cookie) (*http
 	for _, c := range cookies {
 		req.AddCookie(c)
 	}
+	withCSRF(req)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		t.Fatalf("request failed: %v", err)
@@ -64,6 +93,31 @@ func patchJSON(t *testing.T, url string, body any, cookies []*http.Cookie) (*htt
 	for _, c := range cookies {
 		req.AddCookie(c)
 	}
+	withCSRF(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	buf, _ := io.ReadAll(resp.Body)
+	return resp, buf
+}
+
+func doJSON(t *testing.T, method, url string, body any, cookies []*http.Cookie) (*http.Response, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, _ := http.NewRequest(method, url, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	withCSRF(req)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		t.Fatalf("request failed: %v", err)
@@ -96,3 +150,25 @@ func loginUser(t *testing.T, baseURL, email string) []*http.Cookie {
 	}
 	return resp.Cookies()
 }
+
+// registerUsers registers name@example.com for every name and signs them in,
+// returning their session cookies and IDs by name.
+func registerUsers(t *testing.T, baseURL string, db *sql.DB, names ...string) (map[string][]*http.Cookie, map[string]int64) {
+	t.Helper()
+	for _, name := range names {
+		registerUser(t, baseURL, name+"@example.com")
+	}
+	return loginUsers(t, baseURL, db, names...)
+}
+
+// loginUsers is registerUsers for accounts that already exist.
+func loginUsers(t *testing.T, baseURL string, db *sql.DB, names ...string) (map[string][]*http.Cookie, map[string]int64) {
+	t.Helper()
+	cookies := map[string][]*http.Cookie{}
+	ids := map[string]int64{}
+	for _, name := range names {
+		cookies[name] = loginUser(t, baseURL, name+"@example.com")
+		ids[name] = userID(t, db, name+"@example.com")
+	}
+	return cookies, ids
+}
@@ -0,0 +1,128 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"backend/internal/config"
+)
+
+// linkProvider runs the link flow for a signed-in user and returns the
+// callback response.
+func linkProvider(t *testing.T, baseURL, provider string, cookies []*http.Cookie) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, baseURL+"/api/me/identities/"+provider+"/link", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		t.Fatalf("link start: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("link start status: %d", resp.StatusCode)
+	}
+	location, _ := url.Parse(resp.Header.Get("Location"))
+
+	req, _ = http.NewRequest(http.MethodGet, baseURL+"/api/auth/"+provider+"/callback?code=good-code&state="+url.QueryEscape(location.Query().Get("state")), nil)
+	for _, c := range append(resp.Cookies(), cookies...) {
+		req.AddCookie(c)
+	}
+	resp, err = noRedirect.Do(req)
+	if err != nil {
+		t.Fatalf("link callback: %v", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestLinkAndUnlinkIdentity(t *testing.T) {
+	idp := newMockOIDC(t, map[string]any{"sub": "mock-1", "email": "alice@example.com", "email_verified": true})
+	defer idp.Close()
+
+	srv, _, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.OAuthProviders = []config.OAuthProvider{mockProvider(idp)}
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+
+	resp := oauthLogin(t, srv.URL, "mock", "good-code")
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("login with unlinked matching email: %d", resp.StatusCode)
+	}
+
+	cookies := loginUser(t, srv.URL, "alice@example.com")
+	resp = linkProvider(t, srv.URL, "mock", cookies)
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("link status: %d", resp.StatusCode)
+	}
+
+	_, body := doJSON(t, http.MethodGet, srv.URL+"/api/me/identities", nil, cookies)
+	var list struct {
+		Identities []struct {
+			Provider string `json:"provider"`
+		} `json:"identities"`
+		HasPassword bool `json:"has_password"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil || len(list.Identities) != 1 || list.Identities[0].Provider != "mock" || !list.HasPassword {
+		t.Fatalf("identities: %s", body)
+	}
+
+	resp = oauthLogin(t, srv.URL, "mock", "good-code")
+	if resp.StatusCode != http.StatusFound || len(sessionCookies(resp)) == 0 {
+		t.Fatalf("login after link: %d", resp.StatusCode)
+	}
+
+	resp, _ = doJSON(t, http.MethodDelete, srv.URL+"/api/me/identities/mock", nil, cookies)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unlink status: %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, http.MethodDelete, srv.URL+"/api/me/identities/mock", nil, cookies)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("second unlink status: %d", resp.StatusCode)
+	}
+}
+
+func TestOAuthOnlyAccountKeepsLoginMethod(t *testing.T) {
+	idp := newMockOIDC(t, map[string]any{"sub": "mock-2", "email": "gamer@example.com", "email_verified": true})
+	defer idp.Close()
+
+	srv, _, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.OAuthProviders = []config.OAuthProvider{mockProvider(idp)}
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	cookies := sessionCookies(oauthLogin(t, srv.URL, "mock", "good-code"))
+	if len(cookies) == 0 {
+		t.Fatalf("oauth login failed")
+	}
+
+	resp, _ := doJSON(t, http.MethodDelete, srv.URL+"/api/me/identities/mock", nil, cookies)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("unlink last method: %d", resp.StatusCode)
+	}
+
+	resp, _ = postJSON(t, srv.URL+"/api/me/password/set", map[string]any{"password": "newpass456"}, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set password: %d", resp.StatusCode)
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/me/password/set", map[string]any{"password": "other789"}, cookies)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("set password twice: %d", resp.StatusCode)
+	}
+
+	resp, _ = doJSON(t, http.MethodDelete, srv.URL+"/api/me/identities/mock", nil, cookies)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unlink with password: %d", resp.StatusCode)
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/auth/login", map[string]any{"email": "gamer@example.com", "password": "newpass456"}, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("password login: %d", resp.StatusCode)
+	}
+}
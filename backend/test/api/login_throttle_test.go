@@ -0,0 +1,50 @@
+package api_test
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+)
+
+func TestLoginLockout(t *testing.T) {
+	srv, cfg, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.LoginMaxFailuresEmail = 3
+		cfg.LoginFailureWindow = 15 * time.Minute
+		cfg.LoginLockoutBase = time.Minute
+		cfg.LoginLockoutMax = time.Hour
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+
+	wrong := map[string]any{"email": "alice@example.com", "password": "nope"}
+	for i := 0; i < 2; i++ {
+		resp, _ := postJSON(t, srv.URL+"/api/auth/login", wrong, nil)
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("attempt %d status: %d", i+1, resp.StatusCode)
+		}
+	}
+	resp, _ := postJSON(t, srv.URL+"/api/auth/login", wrong, nil)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("lockout status: %d", resp.StatusCode)
+	}
+
+	resp, _ = postJSON(t, srv.URL+"/api/auth/login", map[string]any{"email": "alice@example.com", "password": "password123"}, nil)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("correct password during lockout: %d", resp.StatusCode)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM notifications WHERE type = 'login_lockout'").Scan(&count); err != nil || count != 1 {
+		t.Fatalf("lockout notifications: %d %v", count, err)
+	}
+	waitForMailToken(t, cfg, regexp.MustCompile(`(Sign-in temporarily locked)`))
+
+	// Other accounts are unaffected by a per-email lock.
+	registerUser(t, srv.URL, "bob@example.com")
+	loginUser(t, srv.URL, "bob@example.com")
+}
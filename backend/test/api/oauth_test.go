@@ -0,0 +1,260 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/platform/totp"
+)
+
+// newMockOIDC serves the token and userinfo endpoints of a provider that
+// always signs in the same account.
+func newMockOIDC(t *testing.T, claims map[string]any) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "mock-token", "token_type": "Bearer", "expires_in": 3600})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer mock-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(claims)
+	})
+	return httptest.NewServer(mux)
+}
+
+func mockProvider(idp *httptest.Server) config.OAuthProvider {
+	return config.OAuthProvider{
+		Name:         "mock",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/api/auth/mock/callback",
+		AuthURL:      idp.URL + "/authorize",
+		TokenURL:     idp.URL + "/token",
+		UserInfoURL:  idp.URL + "/userinfo",
+		Claims:       map[string]string{"name": "username"},
+	}
+}
+
+var noRedirect = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+// oauthLogin runs the start and callback steps and returns the response of
+// the callback.
+func oauthLogin(t *testing.T, baseURL, provider, code string) *http.Response {
+	t.Helper()
+	resp, err := noRedirect.Get(baseURL + "/api/auth/" + provider + "/start")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("start status: %d", resp.StatusCode)
+	}
+	location, _ := url.Parse(resp.Header.Get("Location"))
+	state := location.Query().Get("state")
+
+	req, _ := http.NewRequest(http.MethodGet, baseURL+"/api/auth/"+provider+"/callback?state="+url.QueryEscape(state)+"&code="+code, nil)
+	for _, c := range resp.Cookies() {
+		req.AddCookie(c)
+	}
+	resp, err = noRedirect.Do(req)
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func sessionCookies(resp *http.Response) []*http.Cookie {
+	var cookies []*http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "sid" && c.Value != "" {
+			cookies = append(cookies, c)
+		}
+	}
+	return cookies
+}
+
+func TestOAuthProviderLogin(t *testing.T) {
+	idp := newMockOIDC(t, map[string]any{
+		"sub":            "mock-123",
+		"email":          "gamer@example.com",
+		"email_verified": true,
+		"username":       "ProGamer",
+	})
+	defer idp.Close()
+
+	srv, _, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.OAuthProviders = []config.OAuthProvider{mockProvider(idp)}
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	resp, err := noRedirect.Get(srv.URL + "/api/auth/unknown/start")
+	if err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown provider: %v %v", resp.StatusCode, err)
+	}
+
+	resp = oauthLogin(t, srv.URL, "mock", "bad-code")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad code status: %d", resp.StatusCode)
+	}
+
+	resp = oauthLogin(t, srv.URL, "mock", "good-code")
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("callback status: %d", resp.StatusCode)
+	}
+	cookies := sessionCookies(resp)
+	if len(cookies) == 0 {
+		t.Fatalf("no session cookie")
+	}
+	_, body := doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, cookies)
+	var me struct {
+		ID            int64  `json:"id"`
+		Email         string `json:"email"`
+		FirstName     string `json:"first_name"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err := json.Unmarshal(body, &me); err != nil || me.Email != "gamer@example.com" || me.FirstName != "ProGamer" || !me.EmailVerified {
+		t.Fatalf("me: %s", body)
+	}
+
+	resp = oauthLogin(t, srv.URL, "mock", "good-code")
+	_, body = doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, sessionCookies(resp))
+	var again struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(body, &again); err != nil || again.ID != me.ID {
+		t.Fatalf("second login created another user: %s", body)
+	}
+}
+
+func TestOAuthSignupDiscardsHalfCreatedUser(t *testing.T) {
+	idp := newMockOIDC(t, map[string]any{
+		"sub":            "mock-123",
+		"email":          "gamer@example.com",
+		"email_verified": true,
+		"username":       "ProGamer",
+	})
+	defer idp.Close()
+
+	srv, _, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.OAuthProviders = []config.OAuthProvider{mockProvider(idp)}
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TRIGGER fail_oauth_link BEFORE INSERT ON oauth_accounts
+		BEGIN SELECT RAISE(ABORT, 'link failed'); END`); err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+	resp := oauthLogin(t, srv.URL, "mock", "good-code")
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("failed link status: %d", resp.StatusCode)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE email = 'gamer@example.com'").Scan(&count); err != nil || count != 0 {
+		t.Fatalf("half-created users: %d %v", count, err)
+	}
+
+	if _, err := db.Exec("DROP TRIGGER fail_oauth_link"); err != nil {
+		t.Fatalf("drop trigger: %v", err)
+	}
+	resp = oauthLogin(t, srv.URL, "mock", "good-code")
+	if resp.StatusCode != http.StatusFound || len(sessionCookies(resp)) == 0 {
+		t.Fatalf("retry status: %d", resp.StatusCode)
+	}
+}
+
+func TestOAuthStateBoundToProvider(t *testing.T) {
+	idp := newMockOIDC(t, map[string]any{"sub": "x", "email": "x@example.com", "email_verified": true})
+	defer idp.Close()
+	other := mockProvider(idp)
+	other.Name = "other"
+
+	srv, _, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.OAuthProviders = []config.OAuthProvider{mockProvider(idp), other}
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	resp, err := noRedirect.Get(srv.URL + "/api/auth/mock/start")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	resp.Body.Close()
+	location, _ := url.Parse(resp.Header.Get("Location"))
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/auth/other/callback?code=good-code&state="+url.QueryEscape(location.Query().Get("state")), nil)
+	for _, c := range resp.Cookies() {
+		req.AddCookie(c)
+	}
+	resp, err = noRedirect.Do(req)
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("cross-provider callback status: %d", resp.StatusCode)
+	}
+}
+
+func TestOAuthLoginRequiresTwoFactor(t *testing.T) {
+	idp := newMockOIDC(t, map[string]any{"sub": "mock-2fa", "email": "secure@example.com", "email_verified": true})
+	defer idp.Close()
+
+	srv, _, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.OAuthProviders = []config.OAuthProvider{mockProvider(idp)}
+		cfg.FrontendURL = "http://frontend.test"
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	cookies := sessionCookies(oauthLogin(t, srv.URL, "mock", "good-code"))
+	_, body := postJSON(t, srv.URL+"/api/me/2fa/setup", map[string]any{}, cookies)
+	var setup struct {
+		Secret string `json:"secret"`
+	}
+	_ = json.Unmarshal(body, &setup)
+	code, _ := totp.Code(setup.Secret, time.Now())
+	resp, body := postJSON(t, srv.URL+"/api/me/2fa/confirm", map[string]any{"code": code}, cookies)
+	var confirm struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if err := json.Unmarshal(body, &confirm); err != nil || resp.StatusCode != http.StatusOK || len(confirm.RecoveryCodes) == 0 {
+		t.Fatalf("enable 2fa: %d %s", resp.StatusCode, body)
+	}
+
+	resp = oauthLogin(t, srv.URL, "mock", "good-code")
+	if resp.StatusCode != http.StatusFound || len(sessionCookies(resp)) != 0 {
+		t.Fatalf("provider sign-in skipped 2fa: %d", resp.StatusCode)
+	}
+	location, _ := url.Parse(resp.Header.Get("Location"))
+	fragment, _ := url.ParseQuery(location.Fragment)
+	if location.Host != "frontend.test" || location.Path != "/login" || fragment.Get("challenge_token") == "" {
+		t.Fatalf("challenge redirect: %s", resp.Header.Get("Location"))
+	}
+
+	resp, _ = postJSON(t, srv.URL+"/api/auth/login/2fa", map[string]any{
+		"challenge_token": fragment.Get("challenge_token"),
+		"code":            confirm.RecoveryCodes[0],
+	}, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("finish 2fa: %d", resp.StatusCode)
+	}
+	if resp, _ := doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, resp.Cookies()); resp.StatusCode != http.StatusOK {
+		t.Fatalf("me after 2fa: %d", resp.StatusCode)
+	}
+}
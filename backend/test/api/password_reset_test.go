@@ -0,0 +1,108 @@
+package api_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/platform/mailer"
+)
+
+// waitForMailToken scans the file mailer's directory until a message
+// matches pattern and returns its first capture group.
+func waitForMailToken(t *testing.T, cfg config.Config, pattern *regexp.Regexp) string {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		files, _ := filepath.Glob(filepath.Join(cfg.MailDir, "*.eml"))
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			if match := pattern.FindSubmatch(data); match != nil {
+				return string(match[1])
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("no mail matching %s in %s", pattern, cfg.MailDir)
+	return ""
+}
+
+var resetTokenPattern = regexp.MustCompile(`reset-password\?token=([0-9a-f]+)`)
+
+func TestPasswordReset(t *testing.T) {
+	srv, cfg, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	oldCookies := loginUser(t, srv.URL, "alice@example.com")
+	_, oldToken := createAccessToken(t, srv.URL, oldCookies, "read")
+
+	resp, _ := postJSON(t, srv.URL+"/api/auth/password/forgot", map[string]any{"email": "nobody@example.com"}, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("forgot unknown status: %d", resp.StatusCode)
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/auth/password/forgot", map[string]any{"email": "alice@example.com"}, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("forgot status: %d", resp.StatusCode)
+	}
+	token := waitForMailToken(t, cfg, resetTokenPattern)
+
+	resp, _ = postJSON(t, srv.URL+"/api/auth/password/reset", map[string]any{"token": "bogus", "password": "newpass456"}, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bogus token status: %d", resp.StatusCode)
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/auth/password/reset", map[string]any{"token": token, "password": "newpass456"}, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("reset status: %d", resp.StatusCode)
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/auth/password/reset", map[string]any{"token": token, "password": "again789"}, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("token reuse status: %d", resp.StatusCode)
+	}
+
+	resp, _ = doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, oldCookies)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("old session survived reset: %d", resp.StatusCode)
+	}
+	resp, _ = bearerJSON(t, http.MethodGet, srv.URL+"/api/me", oldToken, nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("access token survived reset: %d", resp.StatusCode)
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/auth/login", map[string]any{"email": "alice@example.com", "password": "password123"}, nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("old password still works: %d", resp.StatusCode)
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/auth/login", map[string]any{"email": "alice@example.com", "password": "newpass456"}, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login with new password: %d", resp.StatusCode)
+	}
+}
+
+func TestMailDriverRequiredOutsideDev(t *testing.T) {
+	cases := []struct {
+		env, driver string
+		ok          bool
+	}{
+		{"dev", "log", true},
+		{"test", "bogus", true},
+		{"production", "smtp", true},
+		{"production", "file", true},
+		{"production", "log", false},
+		{"production", "", false},
+		{"staging", "smpt", false},
+	}
+	for _, tc := range cases {
+		err := mailer.CheckDriver(config.Config{Env: tc.env, MailDriver: tc.driver})
+		if (err == nil) != tc.ok {
+			t.Errorf("env %q driver %q: %v", tc.env, tc.driver, err)
+		}
+	}
+}
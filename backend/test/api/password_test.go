@@ -0,0 +1,104 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"backend/internal/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestChangePassword(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	cookies := loginUser(t, srv.URL, "alice@example.com")
+	other := loginUser(t, srv.URL, "alice@example.com")
+	_, token := createAccessToken(t, srv.URL, cookies, "read")
+
+	resp, _ := postJSON(t, srv.URL+"/api/me/password", map[string]any{"current_password": "wrong", "new_password": "newpass456"}, cookies)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong current password: %d", resp.StatusCode)
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/me/password", map[string]any{"current_password": "password123", "new_password": "short"}, cookies)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("weak password: %d", resp.StatusCode)
+	}
+	resp, body := postJSON(t, srv.URL+"/api/me/password", map[string]any{"current_password": "password123", "new_password": "newpass456"}, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("change status: %d %s", resp.StatusCode, body)
+	}
+	var payload struct {
+		Revoked int `json:"revoked_sessions"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	if payload.Revoked != 1 {
+		t.Fatalf("revoked sessions: %d", payload.Revoked)
+	}
+
+	if resp, _ := doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, cookies); resp.StatusCode != http.StatusOK {
+		t.Fatalf("current session lost: %d", resp.StatusCode)
+	}
+	if resp, _ := doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, other); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("other session kept: %d", resp.StatusCode)
+	}
+	if resp, _ := bearerJSON(t, http.MethodGet, srv.URL+"/api/me", token, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("access token kept: %d", resp.StatusCode)
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/auth/login", map[string]any{"email": "alice@example.com", "password": "newpass456"}, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login with new password: %d", resp.StatusCode)
+	}
+}
+
+func TestRegisterEnforcesPasswordPolicy(t *testing.T) {
+	srv, _, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.PasswordMinClasses = 3
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	register := func(password string) int {
+		resp, _ := postJSON(t, srv.URL+"/api/auth/register", map[string]any{
+			"email": "alice@example.com", "password": password,
+			"first_name": "Alice", "last_name": "Doe", "dob": "1990-01-01",
+		}, nil)
+		return resp.StatusCode
+	}
+	if status := register("password123"); status != http.StatusBadRequest {
+		t.Fatalf("two character classes accepted: %d", status)
+	}
+	if status := register("Password123"); status != http.StatusCreated {
+		t.Fatalf("valid password rejected: %d", status)
+	}
+}
+
+func TestLoginRehashesOutdatedPassword(t *testing.T) {
+	srv, _, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.PasswordHash = "argon2id"
+		cfg.Argon2MemoryKiB = 1024
+		cfg.Argon2Time = 1
+		cfg.Argon2Threads = 1
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if _, err := db.Exec("UPDATE users SET password_hash = ? WHERE email = ?", string(legacy), "alice@example.com"); err != nil {
+		t.Fatalf("store legacy hash: %v", err)
+	}
+
+	loginUser(t, srv.URL, "alice@example.com")
+	var stored string
+	_ = db.QueryRow("SELECT password_hash FROM users WHERE email = ?", "alice@example.com").Scan(&stored)
+	if !strings.HasPrefix(stored, "$argon2id$") {
+		t.Fatalf("hash not upgraded: %s", stored)
+	}
+	loginUser(t, srv.URL, "alice@example.com")
+}
@@ -0,0 +1,112 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestEditAndDeletePosts(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	cookies, ids := registerUsers(t, srv.URL, db, "alice", "bob", "carol")
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": ids["alice"]}, cookies["bob"])
+
+	resp, body := postJSON(t, srv.URL+"/api/posts", map[string]any{"text": "hello", "visibility": "public"}, cookies["alice"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create post: %d %s", resp.StatusCode, body)
+	}
+	var post struct {
+		ID       int64   `json:"id"`
+		Text     string  `json:"text"`
+		EditedAt *string `json:"edited_at"`
+	}
+	_ = json.Unmarshal(body, &post)
+	if post.EditedAt != nil {
+		t.Fatalf("new post has edited_at: %s", body)
+	}
+	postURL := srv.URL + "/api/posts/" + strconv.FormatInt(post.ID, 10)
+
+	if resp, _ := patchJSON(t, postURL, map[string]any{"text": "hijacked"}, cookies["bob"]); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("edit by other user: %d", resp.StatusCode)
+	}
+	resp, body = patchJSON(t, postURL, map[string]any{"text": "hello again"}, cookies["alice"])
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("edit text: %d %s", resp.StatusCode, body)
+	}
+	_ = json.Unmarshal(body, &post)
+	if post.Text != "hello again" || post.EditedAt == nil {
+		t.Fatalf("edited post: %s", body)
+	}
+
+	resp, _ = patchJSON(t, postURL, map[string]any{"visibility": "private", "allowed_follower_ids": []int64{ids["carol"]}}, cookies["alice"])
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("non-follower audience: %d", resp.StatusCode)
+	}
+	resp, body = patchJSON(t, postURL, map[string]any{"visibility": "private", "allowed_follower_ids": []int64{ids["bob"]}}, cookies["alice"])
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("edit visibility: %d %s", resp.StatusCode, body)
+	}
+	if !feedTexts(t, srv.URL, cookies["bob"])["hello again"] || feedTexts(t, srv.URL, cookies["carol"])["hello again"] {
+		t.Fatalf("visibility edit not applied")
+	}
+
+	resp, body = doJSON(t, http.MethodGet, postURL+"/revisions", nil, cookies["bob"])
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("revisions: %d %s", resp.StatusCode, body)
+	}
+	var payload struct {
+		Revisions []struct {
+			Text       string `json:"text"`
+			Visibility string `json:"visibility"`
+		} `json:"revisions"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	if len(payload.Revisions) != 2 || payload.Revisions[0].Text != "hello again" || payload.Revisions[1].Text != "hello" ||
+		payload.Revisions[0].Visibility != "public" {
+		t.Fatalf("revisions: %s", body)
+	}
+	if resp, _ := doJSON(t, http.MethodGet, postURL+"/revisions", nil, cookies["carol"]); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("revisions of hidden post: %d", resp.StatusCode)
+	}
+
+	if resp, _ := doJSON(t, http.MethodDelete, postURL, nil, cookies["bob"]); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("delete by other user: %d", resp.StatusCode)
+	}
+	if resp, _ := doJSON(t, http.MethodDelete, postURL, nil, cookies["alice"]); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete: %d", resp.StatusCode)
+	}
+	if resp, _ := doJSON(t, http.MethodDelete, postURL, nil, cookies["alice"]); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("delete again: %d", resp.StatusCode)
+	}
+
+	resp, body = postJSON(t, srv.URL+"/api/groups", map[string]any{"title": "Raid night"}, cookies["alice"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create group: %d", resp.StatusCode)
+	}
+	var group struct{ ID int64 }
+	_ = json.Unmarshal(body, &group)
+	for _, name := range []string{"bob", "carol"} {
+		if _, err := db.Exec("INSERT INTO group_members (group_id, user_id, role) VALUES (?, ?, 'member')", group.ID, ids[name]); err != nil {
+			t.Fatalf("join group: %v", err)
+		}
+	}
+	resp, body = postJSON(t, srv.URL+"/api/groups/"+strconv.FormatInt(group.ID, 10)+"/posts", map[string]any{"text": "lfg"}, cookies["bob"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("group post: %d %s", resp.StatusCode, body)
+	}
+	_ = json.Unmarshal(body, &post)
+	postURL = srv.URL + "/api/posts/" + strconv.FormatInt(post.ID, 10)
+	if resp, _ := patchJSON(t, postURL, map[string]any{"visibility": "public"}, cookies["bob"]); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("group post visibility edit: %d", resp.StatusCode)
+	}
+	if resp, _ := doJSON(t, http.MethodDelete, postURL, nil, cookies["carol"]); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("delete by group member: %d", resp.StatusCode)
+	}
+	if resp, _ := doJSON(t, http.MethodDelete, postURL, nil, cookies["alice"]); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete by group creator: %d", resp.StatusCode)
+	}
+}
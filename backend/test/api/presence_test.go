@@ -0,0 +1,122 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type presenceStatus struct {
+	Type       string  `json:"type"`
+	UserID     int64   `json:"user_id"`
+	Status     string  `json:"status"`
+	LastSeenAt *string `json:"last_seen_at"`
+	NowPlaying *string `json:"now_playing"`
+}
+
+func dialWS(t *testing.T, baseURL string, cookies []*http.Cookie) *websocket.Conn {
+	t.Helper()
+	header := http.Header{}
+	for _, c := range cookies {
+		header.Add("Cookie", c.String())
+	}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(baseURL, "http")+"/api/ws", header)
+	if err != nil {
+		t.Fatalf("dial ws: %v", err)
+	}
+	return conn
+}
+
+// waitPresence reads from conn until a presence event for userID with the
+// given status arrives.
+func waitPresence(t *testing.T, conn *websocket.Conn, userID int64, status string) presenceStatus {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %d %s: %v", userID, status, err)
+		}
+		var event presenceStatus
+		if json.Unmarshal(data, &event) == nil && event.Type == "presence" && event.UserID == userID && event.Status == status {
+			return event
+		}
+	}
+}
+
+func getPresence(t *testing.T, baseURL string, id int64, cookies []*http.Cookie) []presenceStatus {
+	t.Helper()
+	resp, body := doJSON(t, http.MethodGet, baseURL+"/api/presence?user_ids="+strconv.FormatInt(id, 10), nil, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("presence: %d %s", resp.StatusCode, body)
+	}
+	var payload struct {
+		Presence []presenceStatus `json:"presence"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	return payload.Presence
+}
+
+func TestPresence(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	registerUser(t, srv.URL, "bob@example.com")
+	registerUser(t, srv.URL, "carol@example.com")
+	alice := loginUser(t, srv.URL, "alice@example.com")
+	bob := loginUser(t, srv.URL, "bob@example.com")
+	carol := loginUser(t, srv.URL, "carol@example.com")
+	aliceID := userID(t, db, "alice@example.com")
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": aliceID}, bob)
+
+	if presence := getPresence(t, srv.URL, aliceID, bob); len(presence) != 1 || presence[0].Status != "offline" || presence[0].LastSeenAt != nil {
+		t.Fatalf("never connected: %+v", presence)
+	}
+
+	bobConn := dialWS(t, srv.URL, bob)
+	defer bobConn.Close()
+	aliceConn := dialWS(t, srv.URL, alice)
+	waitPresence(t, bobConn, aliceID, "online")
+
+	resp, body := patchJSON(t, srv.URL+"/api/users/me/presence", map[string]any{"status": "away", "now_playing": "Valorant"}, alice)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update presence: %d %s", resp.StatusCode, body)
+	}
+	if event := waitPresence(t, bobConn, aliceID, "away"); event.NowPlaying == nil || *event.NowPlaying != "Valorant" {
+		t.Fatalf("away event: %+v", event)
+	}
+	if presence := getPresence(t, srv.URL, aliceID, carol); len(presence) != 1 || presence[0].Status != "away" {
+		t.Fatalf("public presence hidden from stranger: %+v", presence)
+	}
+
+	patchJSON(t, srv.URL+"/api/users/me/presence", map[string]any{"visibility": "followers"}, alice)
+	if presence := getPresence(t, srv.URL, aliceID, carol); len(presence) != 0 {
+		t.Fatalf("followers-only presence shown to stranger: %+v", presence)
+	}
+	patchJSON(t, srv.URL+"/api/users/me/presence", map[string]any{"visibility": "private"}, alice)
+	waitPresence(t, bobConn, aliceID, "offline")
+	if presence := getPresence(t, srv.URL, aliceID, bob); len(presence) != 0 {
+		t.Fatalf("private presence shown to follower: %+v", presence)
+	}
+
+	patchJSON(t, srv.URL+"/api/users/me/presence", map[string]any{"visibility": "public"}, alice)
+	waitPresence(t, bobConn, aliceID, "away")
+	aliceConn.Close()
+	if event := waitPresence(t, bobConn, aliceID, "offline"); event.LastSeenAt == nil || event.NowPlaying != nil {
+		t.Fatalf("offline event: %+v", event)
+	}
+
+	if resp, _ := patchJSON(t, srv.URL+"/api/users/me/presence", map[string]any{"status": "busy"}, alice); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad status: %d", resp.StatusCode)
+	}
+	if resp, _ := doJSON(t, http.MethodGet, srv.URL+"/api/presence?user_ids=x", nil, bob); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad user_ids: %d", resp.StatusCode)
+	}
+}
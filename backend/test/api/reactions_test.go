@@ -0,0 +1,226 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+
+	"github.com/gorilla/websocket"
+)
+
+type reactionSummary struct {
+	Reactions  map[string]int `json:"reactions"`
+	MyReaction *string        `json:"my_reaction"`
+}
+
+func react(t *testing.T, url, emoji string, cookies []*http.Cookie) reactionSummary {
+	t.Helper()
+	resp, body := postJSON(t, url, map[string]any{"emoji": emoji}, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("react %s: %d %s", emoji, resp.StatusCode, body)
+	}
+	var summary reactionSummary
+	_ = json.Unmarshal(body, &summary)
+	return summary
+}
+
+func TestReactions(t *testing.T) {
+	srv, _, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.ReactionNotifyWindow = time.Hour
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	cookies, ids := registerUsers(t, srv.URL, db, "alice", "bob", "carol")
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": ids["alice"]}, cookies["bob"])
+
+	resp, body := postJSON(t, srv.URL+"/api/posts", map[string]any{"text": "gg all", "visibility": "public"}, cookies["alice"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create post: %d %s", resp.StatusCode, body)
+	}
+	var post struct{ ID int64 }
+	_ = json.Unmarshal(body, &post)
+	reactionsURL := srv.URL + "/api/posts/" + strconv.FormatInt(post.ID, 10) + "/reactions"
+
+	if resp, _ := postJSON(t, reactionsURL, map[string]any{"emoji": "shrug"}, cookies["bob"]); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unknown emoji: %d", resp.StatusCode)
+	}
+	react(t, reactionsURL, "like", cookies["bob"])
+	react(t, reactionsURL, "gg", cookies["carol"])
+	summary := react(t, reactionsURL, "fire", cookies["bob"])
+	if len(summary.Reactions) != 2 || summary.Reactions["fire"] != 1 || summary.Reactions["gg"] != 1 ||
+		summary.MyReaction == nil || *summary.MyReaction != "fire" {
+		t.Fatalf("summary: %+v", summary)
+	}
+
+	resp, body = doJSON(t, http.MethodGet, srv.URL+"/api/feed", nil, cookies["bob"])
+	var feed struct {
+		Posts []reactionSummary `json:"posts"`
+	}
+	_ = json.Unmarshal(body, &feed)
+	if resp.StatusCode != http.StatusOK || len(feed.Posts) != 1 || feed.Posts[0].Reactions["gg"] != 1 ||
+		feed.Posts[0].MyReaction == nil || *feed.Posts[0].MyReaction != "fire" {
+		t.Fatalf("feed reactions: %s", body)
+	}
+
+	_, body = doJSON(t, http.MethodGet, reactionsURL+"?emoji=gg", nil, cookies["alice"])
+	var reactors struct {
+		Users []struct {
+			ID    int64  `json:"id"`
+			Emoji string `json:"emoji"`
+		} `json:"users"`
+	}
+	_ = json.Unmarshal(body, &reactors)
+	if len(reactors.Users) != 1 || reactors.Users[0].ID != ids["carol"] || reactors.Users[0].Emoji != "gg" {
+		t.Fatalf("who reacted: %s", body)
+	}
+
+	_, body = doJSON(t, http.MethodGet, srv.URL+"/api/notifications", nil, cookies["alice"])
+	var notifications struct {
+		Notifications []struct{ Type string }
+	}
+	_ = json.Unmarshal(body, &notifications)
+	count := 0
+	for _, notification := range notifications.Notifications {
+		if notification.Type == "post_reaction" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("reaction notifications not throttled: %s", body)
+	}
+
+	if resp, _ := doJSON(t, http.MethodDelete, reactionsURL, nil, cookies["bob"]); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("remove reaction: %d", resp.StatusCode)
+	}
+	_, body = doJSON(t, http.MethodGet, reactionsURL, nil, cookies["alice"])
+	_ = json.Unmarshal(body, &reactors)
+	if len(reactors.Users) != 1 {
+		t.Fatalf("reaction not removed: %s", body)
+	}
+
+	resp, body = postJSON(t, srv.URL+"/api/posts", map[string]any{"text": "bob only", "visibility": "private", "allowed_follower_ids": []int64{ids["bob"]}}, cookies["alice"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("private post: %d %s", resp.StatusCode, body)
+	}
+	_ = json.Unmarshal(body, &post)
+	privateURL := srv.URL + "/api/posts/" + strconv.FormatInt(post.ID, 10)
+	if resp, _ := postJSON(t, privateURL+"/reactions", map[string]any{"emoji": "like"}, cookies["carol"]); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("react to hidden post: %d", resp.StatusCode)
+	}
+	if resp, _ := doJSON(t, http.MethodGet, privateURL+"/reactions", nil, cookies["carol"]); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("list reactions of hidden post: %d", resp.StatusCode)
+	}
+
+	resp, body = postJSON(t, privateURL+"/comments", map[string]any{"text": "nice"}, cookies["bob"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("comment: %d %s", resp.StatusCode, body)
+	}
+	var comment struct{ ID int64 }
+	_ = json.Unmarshal(body, &comment)
+	commentURL := srv.URL + "/api/comments/" + strconv.FormatInt(comment.ID, 10) + "/reactions"
+	if resp, _ := postJSON(t, commentURL, map[string]any{"emoji": "love"}, cookies["carol"]); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("react to hidden comment: %d", resp.StatusCode)
+	}
+	react(t, commentURL, "love", cookies["alice"])
+	_, body = doJSON(t, http.MethodGet, privateURL+"/comments", nil, cookies["bob"])
+	var comments struct {
+		Comments []reactionSummary `json:"comments"`
+	}
+	_ = json.Unmarshal(body, &comments)
+	if len(comments.Comments) != 1 || comments.Comments[0].Reactions["love"] != 1 || comments.Comments[0].MyReaction != nil {
+		t.Fatalf("comment reactions: %s", body)
+	}
+}
+
+// waitEvent reads from conn until an event of the given type arrives.
+func waitEvent(t *testing.T, conn *websocket.Conn, eventType string) map[string]any {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", eventType, err)
+		}
+		var event map[string]any
+		if json.Unmarshal(data, &event) == nil && event["type"] == eventType {
+			return event
+		}
+	}
+}
+
+func TestMessageReactions(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	cookies, ids := registerUsers(t, srv.URL, db, "alice", "bob", "carol")
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": ids["alice"]}, cookies["bob"])
+
+	bobConn := dialWS(t, srv.URL, cookies["bob"])
+	defer bobConn.Close()
+	if err := bobConn.WriteJSON(map[string]any{"type": "dm_send", "to_user_id": ids["alice"], "text": "gg"}); err != nil {
+		t.Fatalf("send dm: %v", err)
+	}
+	messageID := int64(waitEvent(t, bobConn, "dm_new")["message_id"].(float64))
+	dmURL := srv.URL + "/api/messages/" + strconv.FormatInt(messageID, 10) + "/reactions"
+
+	if resp, _ := postJSON(t, dmURL, map[string]any{"emoji": "gg"}, cookies["carol"]); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("outsider reacts to dm: %d", resp.StatusCode)
+	}
+	summary := react(t, dmURL, "gg", cookies["alice"])
+	if summary.Reactions["gg"] != 1 || summary.MyReaction == nil || *summary.MyReaction != "gg" {
+		t.Fatalf("dm reaction: %+v", summary)
+	}
+	if event := waitEvent(t, bobConn, "dm_reaction"); event["emoji"] != "gg" || event["user_id"] != float64(ids["alice"]) {
+		t.Fatalf("dm reaction event: %v", event)
+	}
+	if resp, _ := doJSON(t, http.MethodGet, dmURL, nil, cookies["carol"]); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("outsider lists dm reactions: %d", resp.StatusCode)
+	}
+	if resp, _ := doJSON(t, http.MethodDelete, dmURL, nil, cookies["alice"]); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("remove dm reaction: %d", resp.StatusCode)
+	}
+	if event := waitEvent(t, bobConn, "dm_reaction"); event["emoji"] != nil {
+		t.Fatalf("dm reaction removal event: %v", event)
+	}
+	postJSON(t, srv.URL+"/api/blocks/"+strconv.FormatInt(ids["bob"], 10), nil, cookies["alice"])
+	if resp, _ := postJSON(t, dmURL, map[string]any{"emoji": "fire"}, cookies["bob"]); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("react to dm after block: %d", resp.StatusCode)
+	}
+
+	resp, body := postJSON(t, srv.URL+"/api/groups", map[string]any{"title": "Raid night"}, cookies["alice"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create group: %d", resp.StatusCode)
+	}
+	var group struct{ ID int64 }
+	_ = json.Unmarshal(body, &group)
+	result, err := db.Exec("INSERT INTO group_messages (group_id, from_user_id, text) VALUES (?, ?, 'lfg')", group.ID, ids["alice"])
+	if err != nil {
+		t.Fatalf("group message: %v", err)
+	}
+	groupMessageID, _ := result.LastInsertId()
+	groupURL := srv.URL + "/api/group-messages/" + strconv.FormatInt(groupMessageID, 10) + "/reactions"
+	if resp, _ := postJSON(t, groupURL, map[string]any{"emoji": "fire"}, cookies["carol"]); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("non-member reacts to group message: %d", resp.StatusCode)
+	}
+	if _, err := db.Exec("INSERT INTO group_members (group_id, user_id, role) VALUES (?, ?, 'member')", group.ID, ids["carol"]); err != nil {
+		t.Fatalf("join group: %v", err)
+	}
+	react(t, groupURL, "fire", cookies["carol"])
+	_, body = doJSON(t, http.MethodGet, groupURL, nil, cookies["alice"])
+	var reactors struct {
+		Users []struct {
+			ID    int64  `json:"id"`
+			Emoji string `json:"emoji"`
+		} `json:"users"`
+	}
+	_ = json.Unmarshal(body, &reactors)
+	if len(reactors.Users) != 1 || reactors.Users[0].ID != ids["carol"] || reactors.Users[0].Emoji != "fire" {
+		t.Fatalf("group message reactors: %s", body)
+	}
+}
@@ -0,0 +1,101 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+type searchCard struct {
+	ID        int64   `json:"id"`
+	Handle    string  `json:"handle"`
+	Nickname  *string `json:"nickname"`
+	FirstName string  `json:"first_name"`
+	Limited   bool    `json:"limited"`
+}
+
+func searchUsers(t *testing.T, baseURL, query string, cookies []*http.Cookie) []searchCard {
+	t.Helper()
+	resp, body := doJSON(t, http.MethodGet, baseURL+"/api/users/search?q="+url.QueryEscape(query), nil, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("search %q: %d %s", query, resp.StatusCode, body)
+	}
+	var payload struct {
+		Users []searchCard `json:"users"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	return payload.Users
+}
+
+func registerNamed(t *testing.T, baseURL, email, first, last, nickname string) {
+	t.Helper()
+	resp, _ := postJSON(t, baseURL+"/api/auth/register", map[string]any{
+		"email": email, "password": "password123", "first_name": first, "last_name": last,
+		"dob": "1990-01-01", "nickname": nickname,
+	}, nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register %s: %d", email, resp.StatusCode)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerNamed(t, srv.URL, "alice@example.com", "Alice", "Martin", "shadowhunter")
+	registerNamed(t, srv.URL, "bob@example.com", "Bob", "Shadow", "bobby")
+	registerNamed(t, srv.URL, "carol@example.com", "Carol", "Lee", "carol")
+	bob := loginUser(t, srv.URL, "bob@example.com")
+	if resp, _ := patchJSON(t, srv.URL+"/api/users/me", map[string]any{"is_public": false}, bob); resp.StatusCode != http.StatusOK {
+		t.Fatalf("make bob private: %d", resp.StatusCode)
+	}
+	carol := loginUser(t, srv.URL, "carol@example.com")
+
+	results := searchUsers(t, srv.URL, "shad", carol)
+	if len(results) != 1 || results[0].Nickname == nil || *results[0].Nickname != "shadowhunter" {
+		t.Fatalf("private profile matched on a hidden name: %+v", results)
+	}
+	results = searchUsers(t, srv.URL, "bobby", carol)
+	if len(results) != 1 || !results[0].Limited || results[0].FirstName != "" {
+		t.Fatalf("private profile not reduced to a card: %+v", results)
+	}
+
+	results = searchUsers(t, srv.URL, "shad", bob)
+	if len(results) != 2 || results[0].Nickname == nil || *results[0].Nickname != "shadowhunter" {
+		t.Fatalf("nickname match should rank first: %+v", results)
+	}
+	resp, body := doJSON(t, http.MethodGet, srv.URL+"/api/users/search?q=shad&limit=1&offset=1", nil, bob)
+	var page struct {
+		Users []searchCard `json:"users"`
+	}
+	_ = json.Unmarshal(body, &page)
+	if resp.StatusCode != http.StatusOK || len(page.Users) != 1 || page.Users[0].FirstName != "Bob" {
+		t.Fatalf("second page: %d %+v", resp.StatusCode, page.Users)
+	}
+
+	if results := searchUsers(t, srv.URL, "alice@example.com", carol); len(results) != 1 || results[0].FirstName != "Alice" {
+		t.Fatalf("exact email lookup: %+v", results)
+	}
+	if results := searchUsers(t, srv.URL, "alice@example", carol); len(results) != 0 {
+		t.Fatalf("partial email matched: %+v", results)
+	}
+	if results := searchUsers(t, srv.URL, `"OR*`, carol); len(results) != 0 {
+		t.Fatalf("operators not escaped: %+v", results)
+	}
+
+	alice := loginUser(t, srv.URL, "alice@example.com")
+	if resp, _ := patchJSON(t, srv.URL+"/api/users/me", map[string]any{"nickname": "nightowl"}, alice); resp.StatusCode != http.StatusOK {
+		t.Fatalf("rename: %d", resp.StatusCode)
+	}
+	if resp, _ := patchJSON(t, srv.URL+"/api/users/me/handle", map[string]any{"handle": "nightowl"}, alice); resp.StatusCode != http.StatusOK {
+		t.Fatalf("change handle: %d", resp.StatusCode)
+	}
+	if results := searchUsers(t, srv.URL, "night", carol); len(results) != 1 {
+		t.Fatalf("index not updated after rename: %+v", results)
+	}
+	if results := searchUsers(t, srv.URL, "shadowhunter", carol); len(results) != 0 {
+		t.Fatalf("old nickname still indexed: %+v", results)
+	}
+}
@@ -0,0 +1,104 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+type sessionList struct {
+	Sessions []struct {
+		ID      string `json:"id"`
+		Current bool   `json:"current"`
+	} `json:"sessions"`
+}
+
+func TestSessionManagement(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	laptop := loginUser(t, srv.URL, "alice@example.com")
+	phone := loginUser(t, srv.URL, "alice@example.com")
+
+	resp, body := doJSON(t, http.MethodGet, srv.URL+"/api/sessions", nil, laptop)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("list status: %d", resp.StatusCode)
+	}
+	var list sessionList
+	if err := json.Unmarshal(body, &list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(list.Sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(list.Sessions))
+	}
+	var phoneID string
+	for _, s := range list.Sessions {
+		if !s.Current {
+			phoneID = s.ID
+		}
+	}
+	if phoneID == "" {
+		t.Fatalf("expected exactly one current session")
+	}
+
+	resp, _ = doJSON(t, http.MethodDelete, srv.URL+"/api/sessions/"+phoneID, nil, laptop)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("revoke status: %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, phone)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("revoked session still valid: %d", resp.StatusCode)
+	}
+
+	tablet := loginUser(t, srv.URL, "alice@example.com")
+	resp, _ = postJSON(t, srv.URL+"/api/sessions/revoke-others", map[string]any{}, laptop)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke-others status: %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, tablet)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("other session still valid: %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, laptop)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("current session revoked: %d", resp.StatusCode)
+	}
+}
+
+func TestSessionRotation(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	cookies := loginUser(t, srv.URL, "alice@example.com")
+
+	if _, err := db.Exec("UPDATE sessions SET created_at = datetime('now', '-2 days'), last_seen_at = datetime('now', '-2 days')"); err != nil {
+		t.Fatalf("age session: %v", err)
+	}
+
+	resp, _ := doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("me status: %d", resp.StatusCode)
+	}
+	var rotated *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "sid" {
+			rotated = c
+		}
+	}
+	if rotated == nil || rotated.Value == cookies[0].Value {
+		t.Fatalf("expected rotated session cookie")
+	}
+
+	resp, _ = doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, []*http.Cookie{rotated})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("rotated token rejected: %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("previous token rejected during grace period: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,95 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"backend/internal/repo"
+)
+
+type suggestionCard struct {
+	ID            int64 `json:"id"`
+	MutualFollows int   `json:"mutual_follows"`
+	SharedGroups  int   `json:"shared_groups"`
+	SharedGames   int   `json:"shared_games"`
+}
+
+func suggestions(t *testing.T, baseURL string, cookies []*http.Cookie) []suggestionCard {
+	t.Helper()
+	resp, body := doJSON(t, http.MethodGet, baseURL+"/api/suggestions/users", nil, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("suggestions: %d %s", resp.StatusCode, body)
+	}
+	var payload struct {
+		Users []suggestionCard `json:"users"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	return payload.Users
+}
+
+func TestFollowSuggestions(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	cookies, ids := registerUsers(t, srv.URL, db, "alice", "bob", "carol", "dave", "erin", "frank", "gina")
+	follow := func(from, to string) {
+		postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": ids[to]}, cookies[from])
+	}
+	follow("alice", "bob")
+	follow("bob", "carol")
+	follow("bob", "frank")
+	follow("erin", "bob")
+
+	resp, body := postJSON(t, srv.URL+"/api/groups", map[string]any{"title": "Raid night"}, cookies["alice"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create group: %d", resp.StatusCode)
+	}
+	var group struct{ ID int64 }
+	_ = json.Unmarshal(body, &group)
+	for _, name := range []string{"carol", "dave", "erin"} {
+		if _, err := db.Exec("INSERT INTO group_members (group_id, user_id, role) VALUES (?, ?, 'member')", group.ID, ids[name]); err != nil {
+			t.Fatalf("join group: %v", err)
+		}
+	}
+	postJSON(t, srv.URL+"/api/blocks/"+strconv.FormatInt(ids["erin"], 10), nil, cookies["alice"])
+	patchJSON(t, srv.URL+"/api/users/me", map[string]any{"is_public": false}, cookies["frank"])
+	follow("alice", "frank")
+	// A shared game only counts where alice may see the profile listing it.
+	patchJSON(t, srv.URL+"/api/users/me", map[string]any{"is_public": false}, cookies["gina"])
+	for _, name := range []string{"alice", "dave", "gina"} {
+		if _, err := db.Exec("INSERT INTO user_games (user_id, game_slug, game_name) VALUES (?, 'chess', 'Chess')", ids[name]); err != nil {
+			t.Fatalf("add game: %v", err)
+		}
+	}
+
+	if _, err := repo.RefreshSuggestions(context.Background(), db, 50); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	got := suggestions(t, srv.URL, cookies["alice"])
+	if len(got) != 2 || got[0].ID != ids["carol"] || got[0].MutualFollows != 1 || got[0].SharedGroups != 1 ||
+		got[1].ID != ids["dave"] || got[1].SharedGroups != 1 || got[1].SharedGames != 1 {
+		t.Fatalf("suggestions: %+v", got)
+	}
+
+	follow("alice", "carol")
+	if got := suggestions(t, srv.URL, cookies["alice"]); len(got) != 1 || got[0].ID != ids["dave"] {
+		t.Fatalf("followed user still suggested: %+v", got)
+	}
+
+	if _, err := db.Exec("DELETE FROM user_suggestions"); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	got = suggestions(t, srv.URL, cookies["dave"])
+	if len(got) == 0 || got[0].ID != ids["bob"] {
+		t.Fatalf("popular fallback: %+v", got)
+	}
+	for _, card := range got {
+		if card.ID == ids["frank"] || card.ID == ids["dave"] {
+			t.Fatalf("fallback suggests private or own profile: %+v", got)
+		}
+	}
+}
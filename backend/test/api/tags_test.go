@@ -0,0 +1,149 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// mentionCount counts the mention notifications of a user.
+func mentionCount(t *testing.T, baseURL string, cookies []*http.Cookie) int {
+	t.Helper()
+	resp, body := doJSON(t, http.MethodGet, baseURL+"/api/notifications", nil, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("notifications: %d %s", resp.StatusCode, body)
+	}
+	var payload struct {
+		Notifications []struct{ Type string }
+	}
+	_ = json.Unmarshal(body, &payload)
+	count := 0
+	for _, notification := range payload.Notifications {
+		if notification.Type == "mention" {
+			count++
+		}
+	}
+	return count
+}
+
+func tagTimeline(t *testing.T, baseURL, tag string, cookies []*http.Cookie) []string {
+	t.Helper()
+	resp, body := doJSON(t, http.MethodGet, baseURL+"/api/tags/"+tag+"/posts", nil, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("tag timeline: %d %s", resp.StatusCode, body)
+	}
+	var payload struct {
+		Posts []struct {
+			Text string `json:"text"`
+		} `json:"posts"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	texts := []string{}
+	for _, post := range payload.Posts {
+		texts = append(texts, post.Text)
+	}
+	return texts
+}
+
+func TestTagsAndMentions(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	cookies, ids := registerUsers(t, srv.URL, db, "alice", "bob", "carol", "dave")
+	handles := map[string]string{}
+	for name, id := range ids {
+		var handle string
+		if err := db.QueryRow("SELECT handle FROM users WHERE id = ?", id).Scan(&handle); err != nil {
+			t.Fatalf("handle: %v", err)
+		}
+		handles[name] = handle
+	}
+	postJSON(t, srv.URL+"/api/follows/request", map[string]any{"to_user_id": ids["alice"]}, cookies["bob"])
+
+	publicText := "GG #LeagueOfLegends #1 with @" + handles["bob"] + " and @" + handles["carol"] + ", mail me@example.com @" + handles["alice"]
+	resp, body := postJSON(t, srv.URL+"/api/posts", map[string]any{"text": publicText, "visibility": "public"}, cookies["alice"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("public post: %d %s", resp.StatusCode, body)
+	}
+	var post struct{ ID int64 }
+	_ = json.Unmarshal(body, &post)
+	publicID := post.ID
+	resp, body = postJSON(t, srv.URL+"/api/posts", map[string]any{
+		"text":                 "#leagueoflegends scrim @" + handles["bob"] + " @" + handles["carol"],
+		"visibility":           "private",
+		"allowed_follower_ids": []int64{ids["bob"]},
+	}, cookies["alice"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("private post: %d %s", resp.StatusCode, body)
+	}
+	_ = json.Unmarshal(body, &post)
+	privateID := post.ID
+
+	if got := mentionCount(t, srv.URL, cookies["bob"]); got != 2 {
+		t.Fatalf("bob mentions: %d", got)
+	}
+	if got := mentionCount(t, srv.URL, cookies["carol"]); got != 1 {
+		t.Fatalf("carol mentioned in a post they cannot see: %d", got)
+	}
+	if got := mentionCount(t, srv.URL, cookies["alice"]); got != 0 {
+		t.Fatalf("self mention: %d", got)
+	}
+
+	if got := tagTimeline(t, srv.URL, "LeagueOfLegends", cookies["bob"]); len(got) != 2 {
+		t.Fatalf("bob timeline: %v", got)
+	}
+	if got := tagTimeline(t, srv.URL, "leagueoflegends", cookies["carol"]); len(got) != 1 || got[0] != publicText {
+		t.Fatalf("carol timeline: %v", got)
+	}
+	if resp, _ := doJSON(t, http.MethodGet, srv.URL+"/api/tags/1/posts", nil, cookies["carol"]); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("numeric tag: %d", resp.StatusCode)
+	}
+
+	postURL := srv.URL + "/api/posts/" + strconv.FormatInt(publicID, 10)
+	resp, body = postJSON(t, postURL+"/comments", map[string]any{"text": "count me in @" + handles["dave"] + " #Ranked"}, cookies["bob"])
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("comment: %d %s", resp.StatusCode, body)
+	}
+	if got := mentionCount(t, srv.URL, cookies["dave"]); got != 1 {
+		t.Fatalf("dave comment mention: %d", got)
+	}
+
+	resp, body = patchJSON(t, postURL, map[string]any{"text": publicText + " @" + handles["dave"]}, cookies["alice"])
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("edit: %d %s", resp.StatusCode, body)
+	}
+	if mentionCount(t, srv.URL, cookies["dave"]) != 2 || mentionCount(t, srv.URL, cookies["bob"]) != 2 {
+		t.Fatalf("edit should notify only new mentions")
+	}
+
+	_, body = doJSON(t, http.MethodGet, srv.URL+"/api/tags?q=%23LEAG", nil, cookies["dave"])
+	var autocomplete struct {
+		Tags []struct {
+			Tag  string `json:"tag"`
+			Uses int    `json:"uses"`
+		} `json:"tags"`
+	}
+	_ = json.Unmarshal(body, &autocomplete)
+	if len(autocomplete.Tags) != 1 || autocomplete.Tags[0].Tag != "leagueoflegends" || autocomplete.Tags[0].Uses != 1 {
+		t.Fatalf("autocomplete: %s", body)
+	}
+	_, body = doJSON(t, http.MethodGet, srv.URL+"/api/tags?q=ran", nil, cookies["dave"])
+	_ = json.Unmarshal(body, &autocomplete)
+	if len(autocomplete.Tags) != 1 || autocomplete.Tags[0].Tag != "ranked" {
+		t.Fatalf("comment tag autocomplete: %s", body)
+	}
+
+	// carol could not see the private post, so opening it up mentions them.
+	resp, body = patchJSON(t, srv.URL+"/api/posts/"+strconv.FormatInt(privateID, 10), map[string]any{"visibility": "public"}, cookies["alice"])
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("widen: %d %s", resp.StatusCode, body)
+	}
+	if got := mentionCount(t, srv.URL, cookies["carol"]); got != 2 {
+		t.Fatalf("carol mentions after the post was opened up: %d", got)
+	}
+	if got := mentionCount(t, srv.URL, cookies["bob"]); got != 2 {
+		t.Fatalf("bob notified twice for one post: %d", got)
+	}
+}
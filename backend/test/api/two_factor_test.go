@@ -0,0 +1,144 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/platform/totp"
+)
+
+func TestTwoFactorLogin(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	cookies := loginUser(t, srv.URL, "alice@example.com")
+
+	resp, body := postJSON(t, srv.URL+"/api/me/2fa/setup", map[string]any{}, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("setup status: %d", resp.StatusCode)
+	}
+	var setup struct {
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(body, &setup); err != nil || setup.Secret == "" {
+		t.Fatalf("setup payload: %s", body)
+	}
+
+	code, _ := totp.Code(setup.Secret, time.Now())
+	resp, body = postJSON(t, srv.URL+"/api/me/2fa/confirm", map[string]any{"code": code}, cookies)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("confirm status: %d", resp.StatusCode)
+	}
+	var confirm struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if err := json.Unmarshal(body, &confirm); err != nil || len(confirm.RecoveryCodes) == 0 {
+		t.Fatalf("confirm payload: %s", body)
+	}
+
+	resp, body = postJSON(t, srv.URL+"/api/auth/login", map[string]any{
+		"email":    "alice@example.com",
+		"password": "password123",
+	}, nil)
+	if resp.StatusCode != http.StatusOK || len(resp.Cookies()) != 0 {
+		t.Fatalf("password step should not create a session: %d", resp.StatusCode)
+	}
+	var challenge struct {
+		Required bool   `json:"two_factor_required"`
+		Token    string `json:"challenge_token"`
+	}
+	if err := json.Unmarshal(body, &challenge); err != nil || !challenge.Required || challenge.Token == "" {
+		t.Fatalf("challenge payload: %s", body)
+	}
+
+	resp, _ = postJSON(t, srv.URL+"/api/auth/login/2fa", map[string]any{
+		"challenge_token": challenge.Token,
+		"code":            code,
+	}, nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("replayed code accepted: %d", resp.StatusCode)
+	}
+
+	resp, _ = postJSON(t, srv.URL+"/api/auth/login/2fa", map[string]any{
+		"challenge_token": challenge.Token,
+		"code":            confirm.RecoveryCodes[0],
+	}, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("recovery code login status: %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, http.MethodGet, srv.URL+"/api/me", nil, resp.Cookies())
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("me after 2fa: %d", resp.StatusCode)
+	}
+}
+
+func TestTwoFactorLoginLockout(t *testing.T) {
+	srv, _, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.LoginMaxFailuresEmail = 3
+		cfg.LoginFailureWindow = 15 * time.Minute
+		cfg.LoginLockoutBase = time.Minute
+		cfg.LoginLockoutMax = time.Hour
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	cookies := loginUser(t, srv.URL, "alice@example.com")
+	_, body := postJSON(t, srv.URL+"/api/me/2fa/setup", map[string]any{}, cookies)
+	var setup struct {
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(body, &setup); err != nil || setup.Secret == "" {
+		t.Fatalf("setup payload: %s", body)
+	}
+	code, _ := totp.Code(setup.Secret, time.Now())
+	if resp, _ := postJSON(t, srv.URL+"/api/me/2fa/confirm", map[string]any{"code": code}, cookies); resp.StatusCode != http.StatusOK {
+		t.Fatalf("confirm status: %d", resp.StatusCode)
+	}
+
+	// Only the newest few challenges stay live.
+	var tokens []string
+	for i := 0; i < 4; i++ {
+		_, body := postJSON(t, srv.URL+"/api/auth/login", map[string]any{
+			"email":    "alice@example.com",
+			"password": "password123",
+		}, nil)
+		var challenge struct {
+			Token string `json:"challenge_token"`
+		}
+		if err := json.Unmarshal(body, &challenge); err != nil || challenge.Token == "" {
+			t.Fatalf("challenge payload: %s", body)
+		}
+		tokens = append(tokens, challenge.Token)
+	}
+	var live int
+	if err := db.QueryRow("SELECT COUNT(*) FROM login_challenges").Scan(&live); err != nil || live != 3 {
+		t.Fatalf("live challenges: %d %v", live, err)
+	}
+	resp, _ := postJSON(t, srv.URL+"/api/auth/login/2fa", map[string]any{"challenge_token": tokens[0], "code": "000000"}, nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("dropped challenge status: %d", resp.StatusCode)
+	}
+
+	// Wrong codes across challenges add up to the account lockout.
+	for i, token := range tokens[1:3] {
+		resp, _ := postJSON(t, srv.URL+"/api/auth/login/2fa", map[string]any{"challenge_token": token, "code": "000000"}, nil)
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("wrong code %d status: %d", i+1, resp.StatusCode)
+		}
+	}
+	resp, _ = postJSON(t, srv.URL+"/api/auth/login/2fa", map[string]any{"challenge_token": tokens[3], "code": "000000"}, nil)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("lockout status: %d", resp.StatusCode)
+	}
+	code, _ = totp.Code(setup.Secret, time.Now())
+	resp, _ = postJSON(t, srv.URL+"/api/auth/login/2fa", map[string]any{"challenge_token": tokens[3], "code": code}, nil)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("right code during lockout: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,97 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+)
+
+type fieldErrors struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields"`
+}
+
+func TestRegisterValidatesFields(t *testing.T) {
+	srv, _, db := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.MinimumAge = 13
+	})
+	defer srv.Close()
+	defer db.Close()
+
+	tooYoung := time.Now().AddDate(-10, 0, 0).Format("2006-01-02")
+	resp, body := postJSON(t, srv.URL+"/api/auth/register", map[string]any{
+		"email": "not-an-email", "password": "password123", "first_name": "Dan", "last_name": "",
+		"dob": tooYoung, "nickname": strings.Repeat("n", 40), "avatar": "media/someone-else.png",
+	}, nil)
+	var invalid fieldErrors
+	_ = json.Unmarshal(body, &invalid)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid register: %d", resp.StatusCode)
+	}
+	for _, field := range []string{"email", "last_name", "dob", "nickname", "avatar"} {
+		if invalid.Fields[field] == "" {
+			t.Fatalf("no error for %s: %+v", field, invalid.Fields)
+		}
+	}
+	if invalid.Fields["first_name"] != "" || invalid.Fields["password"] != "" {
+		t.Fatalf("valid fields reported: %+v", invalid.Fields)
+	}
+
+	resp, body = postJSON(t, srv.URL+"/api/auth/register", map[string]any{
+		"email": "dan@example.com", "password": "password123", "first_name": "Dan", "last_name": "Doe", "dob": "01/02/1990",
+	}, nil)
+	_ = json.Unmarshal(body, &invalid)
+	if resp.StatusCode != http.StatusBadRequest || invalid.Fields["dob"] == "" {
+		t.Fatalf("non-ISO dob: %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestEmailsAreCaseInsensitive(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "  Bob@Example.COM ")
+	if userID(t, db, "bob@example.com") == 0 {
+		t.Fatalf("email not normalized")
+	}
+	resp, _ := postJSON(t, srv.URL+"/api/auth/register", map[string]any{
+		"email": "bob@example.com", "password": "password123", "first_name": "Bob", "last_name": "Two", "dob": "1990-01-01",
+	}, nil)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("duplicate email with other case: %d", resp.StatusCode)
+	}
+	loginUser(t, srv.URL, "BOB@example.com")
+}
+
+func TestUpdateMeRequiresOwnedAvatar(t *testing.T) {
+	srv, _, db := newTestServer(t)
+	defer srv.Close()
+	defer db.Close()
+
+	registerUser(t, srv.URL, "alice@example.com")
+	registerUser(t, srv.URL, "bob@example.com")
+	alice := loginUser(t, srv.URL, "alice@example.com")
+	bob := loginUser(t, srv.URL, "bob@example.com")
+	alicePath := uploadMedia(t, srv.URL, alice)
+
+	resp, body := patchJSON(t, srv.URL+"/api/users/me", map[string]any{"avatar": alicePath, "about": strings.Repeat("a", 501)}, bob)
+	var invalid fieldErrors
+	_ = json.Unmarshal(body, &invalid)
+	if resp.StatusCode != http.StatusBadRequest || invalid.Fields["avatar"] == "" || invalid.Fields["about"] == "" {
+		t.Fatalf("foreign avatar: %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = patchJSON(t, srv.URL+"/api/users/me", map[string]any{"avatar": alicePath}, alice)
+	var updated struct {
+		Avatar *string `json:"avatar_path"`
+	}
+	_ = json.Unmarshal(body, &updated)
+	if resp.StatusCode != http.StatusOK || updated.Avatar == nil || *updated.Avatar != alicePath {
+		t.Fatalf("own avatar: %d %s", resp.StatusCode, body)
+	}
+}
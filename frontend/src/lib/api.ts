@@ -1,10 +1,27 @@
 export const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'
 
-export async function apiFetch(path: string, options: RequestInit = {}) {
+const SAFE_METHODS = ['GET', 'HEAD', 'OPTIONS']
+
+let csrfToken: string | null = null
+
+async function getCsrfToken(): Promise<string> {
+  if (csrfToken) return csrfToken
+  const res = await fetch(`${API_URL}/api/auth/csrf`, { credentials: 'include' })
+  const data = await res.json()
+  csrfToken = data.csrf_token as string
+  return csrfToken
+}
+
+export async function apiFetch(path: string, options: RequestInit = {}, retried = false): Promise<any> {
+  const method = (options.method || 'GET').toUpperCase()
+  const csrfHeaders: Record<string, string> = SAFE_METHODS.includes(method)
+    ? {}
+    : { 'X-CSRF-Token': await getCsrfToken() }
   const res = await fetch(`${API_URL}${path}`, {
     ...options,
     headers: {
       'Content-Type': 'application/json',
+      ...csrfHeaders,
       ...(options.headers || {})
     },
     credentials: 'include'
@@ -13,6 +30,11 @@ export async function apiFetch(path: string, options: RequestInit = {}) {
   const contentType = res.headers.get('content-type') || ''
   const data = contentType.includes('application/json') ? await res.json() : null
   if (!res.ok) {
+    if (res.status === 403 && data?.error === 'csrf token mismatch' && !retried) {
+      // The cookie expired or was cleared; fetch a fresh token and retry once.
+      csrfToken = null
+      return apiFetch(path, options, true)
+    }
     const message = data?.error || `HTTP ${res.status}`
     throw new Error(message)
   }